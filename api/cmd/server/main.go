@@ -10,6 +10,7 @@ import (
 
 	"github.com/stwalsh4118/mirageapi/internal/auth"
 	"github.com/stwalsh4118/mirageapi/internal/config"
+	"github.com/stwalsh4118/mirageapi/internal/events"
 	"github.com/stwalsh4118/mirageapi/internal/jobs"
 	"github.com/stwalsh4118/mirageapi/internal/logging"
 	"github.com/stwalsh4118/mirageapi/internal/railway"
@@ -68,6 +69,7 @@ func main() {
 			Namespace:  cfg.VaultNamespace,
 			SkipVerify: cfg.VaultSkipVerify,
 			MountPath:  cfg.VaultMountPath,
+			CacheTTL:   time.Duration(cfg.VaultCacheTTLSeconds) * time.Second,
 		}
 		vc, err := vault.NewClient(vaultCfg)
 		if err != nil {
@@ -78,6 +80,29 @@ func main() {
 		}
 	}
 
+	// Background jobs below run on whichever replica holds their DB lease
+	leaseTTL := time.Duration(cfg.JobLeaseTTLSeconds) * time.Second
+	leases := jobs.NewLeases(db, jobs.NewLeaseHolderID(), leaseTTL)
+	log.Info().Str("holder", leases.Holder()).Msg("job lease holder id")
+
+	// Provisioning jobs run in the replica that queued them; its liveness lease tells the
+	// others whether they may still finish
+	heartbeatStop := leases.Heartbeat(context.Background())
+	defer heartbeatStop()
+	sweeperStop := leases.Run(context.Background(), jobs.LeaseJobSweeper, func(ctx context.Context) func() {
+		return jobs.StartInterruptedJobSweeper(ctx, db, leaseTTL)
+	})
+	defer sweeperStop()
+
+	// Event hub fans out job progress and environment status changes to websocket clients; the
+	// relay carries them between replicas, since jobs and the poller each run on just one
+	hub := events.NewHub()
+	relayStop := events.NewRelay(db, hub, leases.Holder(), events.DefaultRelayInterval).Start(context.Background())
+	defer relayStop()
+	jobsCtx, jobsCancel := context.WithCancel(context.Background())
+	defer jobsCancel()
+	runner := jobs.NewProvisioningRunner(jobsCtx, db, hub, leases.Holder())
+
 	// Start status poller (Phase 1)
 	pollInterval := time.Duration(cfg.PollIntervalSeconds) * time.Second
 	if pollInterval <= 0 {
@@ -85,14 +110,18 @@ func main() {
 	} else {
 		parent := context.Background()
 		ctx, cancel := context.WithCancel(parent)
-		pollStop := jobs.StartStatusPoller(
-			ctx,
-			db,
-			rw,
-			pollInterval,
-			cfg.PollJitterFraction,
-			nil, // use default log publisher
-		)
+		pollStop := leases.Run(ctx, jobs.LeaseStatusPoller, func(ctx context.Context) func() {
+			return jobs.StartStatusPoller(
+				ctx,
+				db,
+				rw,
+				vaultClient, // poll each environment as its owner
+				pollInterval,
+				cfg.PollJitterFraction,
+				time.Duration(cfg.PollMaxBackoffSeconds)*time.Second,
+				hub, // fan out status changes to websocket clients
+			)
+		})
 		defer func() {
 			// Ensure poller goroutine and its ticker are stopped
 			if pollStop != nil {
@@ -102,7 +131,37 @@ func main() {
 		}()
 	}
 
-	engine := server.NewHTTPServer(cfg, db, rw, vaultClient)
+	// Start TTL reaper for expiring environments
+	reaperInterval := time.Duration(cfg.TTLReaperIntervalSeconds) * time.Second
+	if reaperInterval <= 0 {
+		log.Info().Int("ttl_reaper_interval_seconds", cfg.TTLReaperIntervalSeconds).Msg("ttl reaper disabled")
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		reaperStop := leases.Run(ctx, jobs.LeaseTTLReaper, func(ctx context.Context) func() {
+			return jobs.StartTTLReaper(ctx, db, rw, vaultClient, reaperInterval)
+		})
+		defer func() {
+			reaperStop()
+			cancel()
+		}()
+	}
+
+	// Start drift reconciler comparing environments with Railway
+	driftInterval := time.Duration(cfg.DriftReconcileIntervalSeconds) * time.Second
+	if driftInterval <= 0 {
+		log.Info().Int("drift_reconcile_interval_seconds", cfg.DriftReconcileIntervalSeconds).Msg("drift reconciler disabled")
+	} else {
+		ctx, cancel := context.WithCancel(context.Background())
+		driftStop := leases.Run(ctx, jobs.LeaseDriftReconciler, func(ctx context.Context) func() {
+			return jobs.StartDriftReconciler(ctx, db, rw, vaultClient, driftInterval, cfg.DriftAutoApply)
+		})
+		defer func() {
+			driftStop()
+			cancel()
+		}()
+	}
+
+	engine := server.NewHTTPServer(cfg, db, rw, vaultClient, hub, runner, leases)
 
 	port := cfg.HTTPPort
 	if port == "" {
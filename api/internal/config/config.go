@@ -13,6 +13,20 @@ const (
 	// Poller defaults
 	DefaultPollIntervalSeconds = 0
 	DefaultPollJitterFraction  = 0.2
+	// DefaultPollMaxBackoffSeconds caps how long a user whose Railway polls fail is skipped
+	DefaultPollMaxBackoffSeconds = 900
+	// TTL reaper defaults
+	DefaultTTLReaperIntervalSeconds = 60
+	// DefaultDriftReconcileIntervalSeconds is how often environments are compared with Railway for drift
+	DefaultDriftReconcileIntervalSeconds = 600
+	// DefaultJobLeaseTTLSeconds is how long a replica keeps a background job lease without renewing it
+	DefaultJobLeaseTTLSeconds = 30
+	// Idempotency defaults
+	DefaultIdempotencyRetentionSeconds = 86400
+	// Provisioning defaults
+	DefaultProvisionConcurrency = 4
+	// Vault defaults
+	DefaultVaultCacheTTLSeconds = 300
 	// CORS defaults
 	DefaultAllowedOrigins = "http://localhost:3000,http://127.0.0.1:3000,http://localhost:3002"
 )
@@ -30,6 +44,20 @@ type AppConfig struct {
 	// Status poller settings
 	PollIntervalSeconds int
 	PollJitterFraction  float64
+	// PollMaxBackoffSeconds caps the per-user backoff after failed status polls
+	PollMaxBackoffSeconds int
+	// TTL reaper settings
+	TTLReaperIntervalSeconds int
+	// DriftReconcileIntervalSeconds is how often the drift reconciler runs; 0 disables it
+	DriftReconcileIntervalSeconds int
+	// DriftAutoApply makes the drift reconciler correct records instead of only logging drift
+	DriftAutoApply bool
+	// JobLeaseTTLSeconds bounds how long background jobs stay down after their replica dies
+	JobLeaseTTLSeconds int
+	// IdempotencyRetentionSeconds is how long provisioning responses are kept for replay by RequestID
+	IdempotencyRetentionSeconds int
+	// ProvisionConcurrency bounds how many services are created in Railway at once per request
+	ProvisionConcurrency int
 	// Clerk authentication
 	ClerkSecretKey     string
 	ClerkWebhookSecret string
@@ -42,30 +70,41 @@ type AppConfig struct {
 	VaultNamespace  string
 	VaultSkipVerify bool
 	VaultMountPath  string
+	// VaultCacheTTLSeconds is how long tokens read from Vault are cached in memory (0 disables).
+	// Each replica has its own cache, so a rotated token can be served by the others for this long.
+	VaultCacheTTLSeconds int
 }
 
 // LoadFromEnv loads configuration from environment variables with defaults.
 func LoadFromEnv() (AppConfig, error) {
 	cfg := AppConfig{
-		Environment:         getEnv("APP_ENV", "development"),
-		HTTPPort:            getEnv("HTTP_PORT", DefaultHTTPPort),
-		DatabaseURL:         firstNonEmpty(os.Getenv("DATABASE_URL"), os.Getenv("DB_URL")),
-		RailwayAPIToken:     os.Getenv("RAILWAY_API_TOKEN"),
-		RailwayProjectID:    os.Getenv("RAILWAY_PROJECT_ID"),
-		RailwayEndpoint:     os.Getenv("RAILWAY_GRAPHQL_ENDPOINT"),
-		AllowedOrigins:      parseAllowedOrigins(getEnv("ALLOWED_ORIGINS", DefaultAllowedOrigins)),
-		PollIntervalSeconds: getEnvInt("POLL_INTERVAL_SECONDS", DefaultPollIntervalSeconds),
-		PollJitterFraction:  getEnvFloat("POLL_JITTER_FRACTION", DefaultPollJitterFraction),
-		ClerkSecretKey:      os.Getenv("CLERK_SECRET_KEY"),
-		ClerkWebhookSecret:  os.Getenv("CLERK_WEBHOOK_SECRET"),
-		VaultEnabled:        getEnvBool("VAULT_ENABLED", false),
-		VaultAddr:           os.Getenv("VAULT_ADDR"),
-		VaultToken:          os.Getenv("VAULT_TOKEN"),
-		VaultRoleID:         os.Getenv("VAULT_ROLE_ID"),
-		VaultSecretID:       os.Getenv("VAULT_SECRET_ID"),
-		VaultNamespace:      os.Getenv("VAULT_NAMESPACE"),
-		VaultSkipVerify:     getEnvBool("VAULT_SKIP_VERIFY", false),
-		VaultMountPath:      getEnv("VAULT_MOUNT_PATH", "mirage"),
+		Environment:                   getEnv("APP_ENV", "development"),
+		HTTPPort:                      getEnv("HTTP_PORT", DefaultHTTPPort),
+		DatabaseURL:                   firstNonEmpty(os.Getenv("DATABASE_URL"), os.Getenv("DB_URL")),
+		RailwayAPIToken:               os.Getenv("RAILWAY_API_TOKEN"),
+		RailwayProjectID:              os.Getenv("RAILWAY_PROJECT_ID"),
+		RailwayEndpoint:               os.Getenv("RAILWAY_GRAPHQL_ENDPOINT"),
+		AllowedOrigins:                parseAllowedOrigins(getEnv("ALLOWED_ORIGINS", DefaultAllowedOrigins)),
+		PollIntervalSeconds:           getEnvInt("POLL_INTERVAL_SECONDS", DefaultPollIntervalSeconds),
+		PollJitterFraction:            getEnvFloat("POLL_JITTER_FRACTION", DefaultPollJitterFraction),
+		PollMaxBackoffSeconds:         getEnvInt("POLL_MAX_BACKOFF_SECONDS", DefaultPollMaxBackoffSeconds),
+		TTLReaperIntervalSeconds:      getEnvInt("TTL_REAPER_INTERVAL_SECONDS", DefaultTTLReaperIntervalSeconds),
+		DriftReconcileIntervalSeconds: getEnvInt("DRIFT_RECONCILE_INTERVAL_SECONDS", DefaultDriftReconcileIntervalSeconds),
+		DriftAutoApply:                getEnvBool("DRIFT_AUTO_APPLY", false),
+		JobLeaseTTLSeconds:            getEnvInt("JOB_LEASE_TTL_SECONDS", DefaultJobLeaseTTLSeconds),
+		IdempotencyRetentionSeconds:   getEnvInt("IDEMPOTENCY_RETENTION_SECONDS", DefaultIdempotencyRetentionSeconds),
+		ProvisionConcurrency:          getEnvInt("PROVISION_CONCURRENCY", DefaultProvisionConcurrency),
+		ClerkSecretKey:                os.Getenv("CLERK_SECRET_KEY"),
+		ClerkWebhookSecret:            os.Getenv("CLERK_WEBHOOK_SECRET"),
+		VaultEnabled:                  getEnvBool("VAULT_ENABLED", false),
+		VaultAddr:                     os.Getenv("VAULT_ADDR"),
+		VaultToken:                    os.Getenv("VAULT_TOKEN"),
+		VaultRoleID:                   os.Getenv("VAULT_ROLE_ID"),
+		VaultSecretID:                 os.Getenv("VAULT_SECRET_ID"),
+		VaultNamespace:                os.Getenv("VAULT_NAMESPACE"),
+		VaultSkipVerify:               getEnvBool("VAULT_SKIP_VERIFY", false),
+		VaultMountPath:                getEnv("VAULT_MOUNT_PATH", "mirage"),
+		VaultCacheTTLSeconds:          getEnvInt("VAULT_CACHE_TTL_SECONDS", DefaultVaultCacheTTLSeconds),
 	}
 
 	// Clamp and validate poller configuration
@@ -83,6 +122,27 @@ func LoadFromEnv() (AppConfig, error) {
 		cfg.PollJitterFraction = 0.999
 		log.Warn().Float64("old", old).Float64("new", cfg.PollJitterFraction).Msg("PollJitterFraction too high; clamped below 1")
 	}
+	if cfg.PollMaxBackoffSeconds <= 0 {
+		old := cfg.PollMaxBackoffSeconds
+		cfg.PollMaxBackoffSeconds = DefaultPollMaxBackoffSeconds
+		log.Warn().Int("old", old).Int("new", cfg.PollMaxBackoffSeconds).Msg("invalid PollMaxBackoffSeconds; using default")
+	}
+
+	if cfg.JobLeaseTTLSeconds <= 0 {
+		old := cfg.JobLeaseTTLSeconds
+		cfg.JobLeaseTTLSeconds = DefaultJobLeaseTTLSeconds
+		log.Warn().Int("old", old).Int("new", cfg.JobLeaseTTLSeconds).Msg("invalid JobLeaseTTLSeconds; using default")
+	}
+	if cfg.ProvisionConcurrency <= 0 {
+		old := cfg.ProvisionConcurrency
+		cfg.ProvisionConcurrency = DefaultProvisionConcurrency
+		log.Warn().Int("old", old).Int("new", cfg.ProvisionConcurrency).Msg("invalid ProvisionConcurrency; using default")
+	}
+	if cfg.IdempotencyRetentionSeconds <= 0 {
+		old := cfg.IdempotencyRetentionSeconds
+		cfg.IdempotencyRetentionSeconds = DefaultIdempotencyRetentionSeconds
+		log.Warn().Int("old", old).Int("new", cfg.IdempotencyRetentionSeconds).Msg("invalid IdempotencyRetentionSeconds; using default")
+	}
 
 	return cfg, nil
 }
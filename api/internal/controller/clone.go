@@ -0,0 +1,515 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// railwaySystemVariablePrefix marks variables Railway injects itself (RAILWAY_PUBLIC_DOMAIN, etc.).
+// They describe the source environment and must not be replayed into a clone.
+const railwaySystemVariablePrefix = "RAILWAY_"
+
+// environmentCloner is the subset of the Railway client used to clone an environment.
+type environmentCloner interface {
+	CreateEnvironment(ctx context.Context, in railway.CreateEnvironmentInput) (railway.CreateEnvironmentResult, error)
+	CreateService(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error)
+	GetAllEnvironmentAndServiceVariables(ctx context.Context, in railway.GetAllEnvironmentAndServiceVariablesInput) (railway.GetAllEnvironmentAndServiceVariablesResult, error)
+	UpsertVariableCollection(ctx context.Context, in railway.UpsertVariableCollectionInput) error
+	volumeClient
+}
+
+// CloneEnvironmentRequest is the payload to clone an environment.
+type CloneEnvironmentRequest struct {
+	Name       string                 `json:"name" binding:"required"`
+	ProjectID  string                 `json:"projectId,omitempty"`  // Optional: target Railway project, defaults to the source project
+	EnvType    *store.EnvironmentType `json:"envType,omitempty"`    // Optional: defaults to the source environment type
+	TTLSeconds *int64                 `json:"ttlSeconds,omitempty"` // Optional: defaults to the source environment TTL
+}
+
+// ClonedServiceDTO maps a source service to the service created for the clone.
+type ClonedServiceDTO struct {
+	Name                   string `json:"name"`
+	ServiceID              string `json:"serviceId,omitempty"` // Mirage ID, empty if persisting failed
+	RailwayServiceID       string `json:"railwayServiceId"`
+	SourceServiceID        string `json:"sourceServiceId"`
+	SourceRailwayServiceID string `json:"sourceRailwayServiceId"`
+}
+
+// CloneEnvironmentResponse describes the environment created by a clone.
+type CloneEnvironmentResponse struct {
+	EnvironmentID        string             `json:"environmentId"`        // Mirage internal environment ID
+	RailwayEnvironmentID string             `json:"railwayEnvironmentId"` // Railway's environment ID
+	RailwayProjectID     string             `json:"railwayProjectId"`
+	ClonedFromEnvID      string             `json:"clonedFromEnvId"` // Mirage ID of the source environment
+	Services             []ClonedServiceDTO `json:"services"`
+	Warnings             []string           `json:"warnings,omitempty"`
+}
+
+// cloneOptions controls how cloneEnvironment builds the new environment.
+type cloneOptions struct {
+	Name       string
+	ProjectID  string
+	EnvType    *store.EnvironmentType
+	TTLSeconds *int64
+	Branch     string // Optional: replaces the branch of every repository-based service
+	TemplateID string // Optional: metadata ID of the template being instantiated
+}
+
+// CloneEnvironment creates a new Railway environment from an existing one, recreating every
+// service with the same deployment configuration and replaying its variables and secrets.
+// The :id parameter is the Railway environment ID of the source.
+//
+// POST /api/v1/environments/:id/clone
+// Request body: {"name": "feature-x", "projectId": "optional-target-project"}
+// Response: {"environmentId": "...", "railwayEnvironmentId": "...", "services": [...]}
+func (c *EnvironmentController) CloneEnvironment(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "railway environment id required"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req CloneEnvironmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TTLSeconds != nil && *req.TTLSeconds <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ttlSeconds must be positive"})
+		return
+	}
+
+	// Look up the source environment by Railway ID with ownership check
+	var source store.Environment
+	err = c.DB.Preload("Services", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Services.Volumes").Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&source).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to query environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment"})
+		return
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before cloning environments",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	result, err := c.cloneEnvironment(ctx, rwClient, user.ID, source, cloneOptions{
+		Name:       req.Name,
+		ProjectID:  req.ProjectID,
+		EnvType:    req.EnvType,
+		TTLSeconds: req.TTLSeconds,
+	})
+	if err != nil {
+		respondCloneError(ctx, err, result)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// respondCloneError reports a failed clone. Once the Railway environment exists the partial
+// result is returned so the caller can inspect or delete what was created.
+func respondCloneError(ctx *gin.Context, err error, partial CloneEnvironmentResponse) {
+	if partial.RailwayEnvironmentID == "" {
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "partial": partial})
+}
+
+// cloneEnvironment replays source into a new Railway environment and persists the result.
+// Source variables are read before anything is created so a failed read leaves nothing behind.
+// Later failures return the partial result alongside the error.
+func (c *EnvironmentController) cloneEnvironment(ctx context.Context, rw environmentCloner, userID string, source store.Environment, opts cloneOptions) (CloneEnvironmentResponse, error) {
+	vars, err := rw.GetAllEnvironmentAndServiceVariables(ctx, railway.GetAllEnvironmentAndServiceVariablesInput{
+		ProjectID:     source.RailwayProjectID,
+		EnvironmentID: source.RailwayEnvironmentID,
+	})
+	if err != nil {
+		return newCloneResponse(source, opts), fmt.Errorf("read source variables: %w", err)
+	}
+	serviceVars := make(map[string]map[string]string, len(vars.ServiceVariables))
+	for _, sv := range vars.ServiceVariables {
+		serviceVars[sv.ServiceID] = sv.Variables
+	}
+	// Railway returns references already resolved against the source environment, so linked
+	// variables are replayed from the recorded references instead
+	links, err := loadServiceLinks(ctx, c.DB, userID, source.ID)
+	if err != nil {
+		return newCloneResponse(source, opts), fmt.Errorf("read source service links: %w", err)
+	}
+
+	return c.replayEnvironment(ctx, rw, userID, source, vars.EnvironmentVariables, serviceVars, links, opts)
+}
+
+// newCloneResponse starts the response for an environment built from source.
+func newCloneResponse(source store.Environment, opts cloneOptions) CloneEnvironmentResponse {
+	projectID := opts.ProjectID
+	if projectID == "" {
+		projectID = source.RailwayProjectID
+	}
+	return CloneEnvironmentResponse{
+		RailwayProjectID: projectID,
+		ClonedFromEnvID:  source.ID,
+		Services:         make([]ClonedServiceDTO, 0, len(source.Services)),
+	}
+}
+
+// replayEnvironment creates a new Railway environment holding source's services and persists
+// it. sharedVars are set once at environment scope; serviceVars, keyed by the source's Railway
+// service ID, go to that service only, and links are replayed on top as references.
+func (c *EnvironmentController) replayEnvironment(ctx context.Context, rw environmentCloner, userID string, source store.Environment, sharedVars map[string]string, serviceVars map[string]map[string]string, links []ServiceLink, opts cloneOptions) (CloneEnvironmentResponse, error) {
+	result := newCloneResponse(source, opts)
+	projectID := result.RailwayProjectID
+
+	created, err := rw.CreateEnvironment(ctx, railway.CreateEnvironmentInput{ProjectID: projectID, Name: opts.Name})
+	if err != nil {
+		return result, fmt.Errorf("create environment: %w", err)
+	}
+	result.RailwayEnvironmentID = created.EnvironmentID
+
+	env, err := c.persistClonedEnvironment(userID, source, created.EnvironmentID, projectID, opts)
+	if err != nil {
+		log.Error().Err(err).
+			Str("source_env_id", source.ID).
+			Str("railway_env_id", created.EnvironmentID).
+			Msg("failed to persist cloned environment")
+		return result, fmt.Errorf("persist environment: %w", err)
+	}
+	result.EnvironmentID = env.ID
+
+	shared := cloneSharedVariables(sharedVars)
+	if len(shared) > 0 {
+		if err := rw.UpsertVariableCollection(ctx, railway.UpsertVariableCollectionInput{
+			ProjectID:     projectID,
+			EnvironmentID: created.EnvironmentID,
+			Variables:     shared,
+			SkipDeploys:   true,
+		}); err != nil {
+			return result, fmt.Errorf("set shared variables: %w", err)
+		}
+	}
+
+	for _, svc := range orderServicesByLinks(source.Services, links) {
+		if opts.Branch != "" && svc.DeploymentType != store.DeploymentTypeDockerImage && svc.DeploymentType != store.DeploymentTypeDatabase {
+			svc.SourceBranch = opts.Branch
+		}
+		input := cloneServiceInput(svc, projectID, created.EnvironmentID)
+		svcVars := mergeCloneVariables(shared, serviceVars[svc.RailwayServiceID], svc)
+		if svc.DeploymentType == store.DeploymentTypeDatabase {
+			// The clone gets its own empty database with fresh credentials
+			if svcVars, err = cloneDatabaseVariables(serviceVars[svc.RailwayServiceID], svc.DatabaseEngine); err != nil {
+				return result, fmt.Errorf("service %s: %w", svc.Name, err)
+			}
+		}
+		input.Variables = applyServiceLinks(svcVars, svc.Name, links)
+		if input.Image != nil && input.RegistryCredentials == nil {
+			input.RegistryCredentials = lookupStoredRegistryCredentials(ctx, c.Vault, userID, serviceModelToSpec(svc))
+			if input.RegistryCredentials == nil && svc.ImageAuthStored {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("service %s: no stored registry credentials found for private image", svc.Name))
+			}
+		}
+
+		out, err := rw.CreateService(ctx, input)
+		if err != nil {
+			return result, fmt.Errorf("create service %s: %w", svc.Name, err)
+		}
+		// The clone's volumes start empty, like its databases
+		volumes, err := createServiceVolumes(ctx, rw, ServiceSpec{Volumes: volumeSpecsFromModels(svc.Volumes)}, projectID, created.EnvironmentID, out.ServiceID)
+		if err != nil {
+			return result, fmt.Errorf("create volumes for service %s: %w", svc.Name, err)
+		}
+
+		cloned := ClonedServiceDTO{
+			Name:                   svc.Name,
+			RailwayServiceID:       out.ServiceID,
+			SourceServiceID:        svc.ID,
+			SourceRailwayServiceID: svc.RailwayServiceID,
+		}
+
+		model := cloneServiceModel(svc, env.ID, out.ServiceID)
+		model.ImageAuthStored = input.RegistryCredentials != nil
+		model.Volumes = volumeModels(userID, volumes)
+		if err := c.DB.Create(&model).Error; err != nil {
+			log.Error().Err(err).
+				Str("service_name", svc.Name).
+				Str("railway_service_id", out.ServiceID).
+				Msg("failed to persist cloned service")
+			result.Warnings = append(result.Warnings, fmt.Sprintf("service %s: created in Railway but not persisted", svc.Name))
+		} else {
+			cloned.ServiceID = model.ID
+		}
+		result.Services = append(result.Services, cloned)
+	}
+
+	// Copy Vault-backed environment secrets so future provisioning in the clone sees them too
+	if c.Vault != nil {
+		secrets, err := c.Vault.GetAllEnvironmentSecrets(ctx, userID, source.ID)
+		if err == nil && len(secrets) > 0 {
+			err = c.Vault.BulkStoreEnvironmentSecrets(ctx, userID, env.ID, secrets)
+		}
+		if err != nil {
+			log.Warn().Err(err).
+				Str("source_env_id", source.ID).
+				Str("env_id", env.ID).
+				Msg("failed to copy environment secrets to clone")
+			result.Warnings = append(result.Warnings, "environment secrets were not copied")
+		}
+	}
+
+	log.Info().
+		Str("source_env_id", source.ID).
+		Str("env_id", env.ID).
+		Str("railway_env_id", created.EnvironmentID).
+		Int("service_count", len(result.Services)).
+		Msg("cloned environment")
+
+	return result, nil
+}
+
+// persistClonedEnvironment stores the cloned environment together with metadata recording its lineage.
+// Wizard inputs and service links are carried over from the source so the clone can itself be
+// cloned or templated.
+func (c *EnvironmentController) persistClonedEnvironment(userID string, source store.Environment, railwayEnvID, projectID string, opts cloneOptions) (store.Environment, error) {
+	envType := source.Type
+	if opts.EnvType != nil {
+		envType = *opts.EnvType
+	}
+	ttl := source.TTLSeconds
+	if opts.TTLSeconds != nil {
+		ttl = opts.TTLSeconds
+	}
+	branch, commit := source.SourceBranch, source.SourceCommit
+	if opts.Branch != "" {
+		// The source commit belongs to the old branch
+		branch, commit = opts.Branch, ""
+	}
+
+	now := time.Now()
+	env := store.Environment{
+		ID:                   uuid.New().String(),
+		UserID:               userID,
+		Name:                 opts.Name,
+		Type:                 envType,
+		SourceRepo:           source.SourceRepo,
+		SourceBranch:         branch,
+		SourceCommit:         commit,
+		Status:               status.StatusCreating,
+		RailwayProjectID:     projectID,
+		RailwayEnvironmentID: railwayEnvID,
+		TTLSeconds:           ttl,
+		ExpiresAt:            store.ComputeExpiresAt(now, ttl),
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&env).Error; err != nil {
+			return err
+		}
+
+		var sourceMeta store.EnvironmentMetadata
+		if err := tx.Where("environment_id = ? AND user_id = ?", source.ID, userID).First(&sourceMeta).Error; err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		provisionOutputs := map[string]interface{}{
+			"projectId":     projectID,
+			"environmentId": railwayEnvID,
+		}
+		if opts.TemplateID != "" {
+			provisionOutputs["templateId"] = opts.TemplateID
+		}
+		provisionOutputsJSON, _ := json.Marshal(provisionOutputs)
+		sourceID := source.ID
+		metadata := store.EnvironmentMetadata{
+			ID:                   uuid.New().String(),
+			UserID:               userID,
+			EnvironmentID:        env.ID,
+			ClonedFromEnvID:      &sourceID,
+			WizardInputsJSON:     sourceMeta.WizardInputsJSON,
+			ProvisionOutputsJSON: provisionOutputsJSON,
+			ServiceLinksJSON:     sourceMeta.ServiceLinksJSON,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		}
+		return tx.Create(&metadata).Error
+	})
+	return env, err
+}
+
+// cloneServiceInput builds the Railway input that recreates svc in another environment.
+func cloneServiceInput(svc store.Service, projectID, railwayEnvID string) railway.CreateServiceInput {
+	input := railway.CreateServiceInput{
+		ProjectID:     projectID,
+		EnvironmentID: railwayEnvID,
+		Name:          svc.Name,
+	}
+	if svc.DeploymentType == store.DeploymentTypeDockerImage || svc.DeploymentType == store.DeploymentTypeDatabase {
+		image := svc.DockerImage
+		if image == "" && svc.ImageName != "" {
+			image = buildImageReference(serviceModelToSpec(svc))
+		}
+		input.Image = &image
+		return input
+	}
+	repo, branch := svc.SourceRepo, svc.SourceBranch
+	input.Repo = &repo
+	input.Branch = &branch
+	return input
+}
+
+// cloneSharedVariables returns the environment-level variables to replay into a clone, without
+// the Railway-injected ones since they describe the source environment.
+func cloneSharedVariables(envVars map[string]string) map[string]string {
+	shared := make(map[string]string, len(envVars))
+	for k, v := range envVars {
+		if strings.HasPrefix(k, railwaySystemVariablePrefix) {
+			continue
+		}
+		shared[k] = v
+	}
+	return shared
+}
+
+// mergeCloneVariables builds the variables to replay into a cloned service: the service's own,
+// then system variables derived from its configuration. Railway renders shared variables into
+// each service, so a value matching the shared one is replayed as a ${{shared.X}} reference
+// rather than copied. Railway-injected variables are dropped since they describe the source
+// environment.
+func mergeCloneVariables(shared, serviceVars map[string]string, svc store.Service) map[string]string {
+	merged := make(map[string]string, len(serviceVars))
+	for k, v := range serviceVars {
+		if strings.HasPrefix(k, railwaySystemVariablePrefix) && k != railwayDockerfilePathVar {
+			continue
+		}
+		if sv, ok := shared[k]; ok && sv == v {
+			v = "${{" + railwaySharedNamespace + "." + k + "}}"
+		}
+		merged[k] = v
+	}
+	if svc.DockerfilePath != nil && *svc.DockerfilePath != "" {
+		merged[railwayDockerfilePathVar] = *svc.DockerfilePath
+	}
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// cloneDatabaseVariables builds a cloned database's variables from fresh template variables,
+// since Railway returns the source's rendered against its own host and password. Variables the
+// source service added on top of the template are kept; like at provisioning, environment-level
+// variables are left out of database containers.
+func cloneDatabaseVariables(serviceVars map[string]string, engine string) (map[string]string, error) {
+	fresh, err := databaseVariables(engine)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range serviceVars {
+		if strings.HasPrefix(k, railwaySystemVariablePrefix) || isDatabaseTemplateVariable(engine, k) {
+			continue
+		}
+		fresh[k] = v
+	}
+	return fresh, nil
+}
+
+// applyServiceLinks overwrites the linked variables of the named service with their recorded
+// references.
+func applyServiceLinks(vars map[string]string, service string, links []ServiceLink) map[string]string {
+	for _, l := range links {
+		if l.Service != service {
+			continue
+		}
+		if vars == nil {
+			vars = make(map[string]string)
+		}
+		vars[l.Variable] = l.Value
+	}
+	return vars
+}
+
+// cloneServiceModel copies a service's deployment configuration into a new record for the clone.
+func cloneServiceModel(svc store.Service, environmentID, railwayServiceID string) store.Service {
+	now := time.Now()
+	clone := svc
+	clone.ID = uuid.New().String()
+	clone.EnvironmentID = environmentID
+	clone.RailwayServiceID = railwayServiceID
+	clone.Status = "provisioning"
+	clone.CreatedAt = now
+	clone.UpdatedAt = now
+	clone.Volumes = nil
+	clone.User = nil
+	clone.Environment = nil
+	return clone
+}
+
+// serviceModelToSpec converts a stored service back into the ServiceSpec that would provision it.
+func serviceModelToSpec(svc store.Service) ServiceSpec {
+	spec := ServiceSpec{Name: svc.Name}
+	optional := func(v string) *string {
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+	if svc.DeploymentType == store.DeploymentTypeDatabase {
+		spec.Database = &DatabaseSpec{Engine: svc.DatabaseEngine}
+		spec.ImageName = optional(svc.ImageName)
+		spec.ImageTag = optional(svc.ImageTag)
+		return spec
+	}
+	if svc.DeploymentType == store.DeploymentTypeDockerImage {
+		spec.ImageRegistry = optional(svc.ImageRegistry)
+		spec.ImageName = optional(svc.ImageName)
+		spec.ImageTag = optional(svc.ImageTag)
+		return spec
+	}
+	spec.Repo = optional(svc.SourceRepo)
+	spec.Branch = optional(svc.SourceBranch)
+	spec.DockerfilePath = svc.DockerfilePath
+	return spec
+}
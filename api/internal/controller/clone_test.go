@@ -0,0 +1,199 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+// fakeEnvironmentCloner records clone calls and hands out sequential Railway IDs.
+type fakeEnvironmentCloner struct {
+	vars              railway.GetAllEnvironmentAndServiceVariablesResult
+	varsErr           error
+	createServiceErr  error
+	envInputs         []railway.CreateEnvironmentInput
+	serviceInputs     []railway.CreateServiceInput
+	volumeInputs      []railway.CreateVolumeInput
+	sharedInputs      []railway.UpsertVariableCollectionInput
+	destroyedServices []string
+}
+
+func (f *fakeEnvironmentCloner) CreateEnvironment(ctx context.Context, in railway.CreateEnvironmentInput) (railway.CreateEnvironmentResult, error) {
+	f.envInputs = append(f.envInputs, in)
+	return railway.CreateEnvironmentResult{EnvironmentID: "rw-env-clone"}, nil
+}
+
+func (f *fakeEnvironmentCloner) CreateService(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+	if f.createServiceErr != nil {
+		return railway.CreateServiceResult{}, f.createServiceErr
+	}
+	f.serviceInputs = append(f.serviceInputs, in)
+	return railway.CreateServiceResult{ServiceID: "rw-svc-clone-" + in.Name}, nil
+}
+
+func (f *fakeEnvironmentCloner) DestroyService(ctx context.Context, in railway.DestroyServiceInput) error {
+	f.destroyedServices = append(f.destroyedServices, in.ServiceID)
+	return nil
+}
+
+func (f *fakeEnvironmentCloner) CreateServiceDomain(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error) {
+	return railway.Domain{Domain: in.ServiceID + ".up.railway.app"}, nil
+}
+
+func (f *fakeEnvironmentCloner) GetAllEnvironmentAndServiceVariables(ctx context.Context, in railway.GetAllEnvironmentAndServiceVariablesInput) (railway.GetAllEnvironmentAndServiceVariablesResult, error) {
+	return f.vars, f.varsErr
+}
+
+func (f *fakeEnvironmentCloner) UpsertVariableCollection(ctx context.Context, in railway.UpsertVariableCollectionInput) error {
+	f.sharedInputs = append(f.sharedInputs, in)
+	return nil
+}
+
+func (f *fakeEnvironmentCloner) CreateVolume(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error) {
+	f.volumeInputs = append(f.volumeInputs, in)
+	return railway.Volume{ID: fmt.Sprintf("rw-vol-clone-%d", len(f.volumeInputs)), MountPath: in.MountPath}, nil
+}
+
+func (f *fakeEnvironmentCloner) DeleteVolume(ctx context.Context, in railway.DeleteVolumeInput) error {
+	return nil
+}
+
+func seedCloneSource(t *testing.T) (*EnvironmentController, store.Environment) {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+
+	dockerfile := "services/api/Dockerfile"
+	source := store.Environment{
+		ID:                   "env-src",
+		UserID:               "user-1",
+		Name:                 "staging",
+		Type:                 store.EnvironmentTypeStaging,
+		RailwayProjectID:     "rw-proj",
+		RailwayEnvironmentID: "rw-env-src",
+		Services: []store.Service{
+			{
+				ID:               "svc-api",
+				UserID:           "user-1",
+				Name:             "api",
+				RailwayServiceID: "rw-svc-api",
+				DeploymentType:   store.DeploymentTypeSourceRepo,
+				SourceRepo:       "acme/api",
+				SourceBranch:     "main",
+				DockerfilePath:   &dockerfile,
+			},
+			{
+				ID:               "svc-cache",
+				UserID:           "user-1",
+				Name:             "cache",
+				RailwayServiceID: "rw-svc-cache",
+				DeploymentType:   store.DeploymentTypeDockerImage,
+				DockerImage:      "redis:7",
+				ImageName:        "redis",
+				ImageTag:         "7",
+			},
+		},
+	}
+	require.NoError(t, db.Create(&source).Error)
+	return &EnvironmentController{DB: db}, source
+}
+
+func TestCloneEnvironment_ReplaysServicesAndVariables(t *testing.T) {
+	c, source := seedCloneSource(t)
+	rw := &fakeEnvironmentCloner{vars: railway.GetAllEnvironmentAndServiceVariablesResult{
+		EnvironmentVariables: map[string]string{"SHARED": "1", "REGION": "eu", "RAILWAY_ENVIRONMENT_NAME": "staging"},
+		ServiceVariables: []railway.ServiceVariables{
+			{ServiceID: "rw-svc-api", ServiceName: "api", Variables: map[string]string{"PORT": "8080", "SHARED": "2", "REGION": "eu", "RAILWAY_PUBLIC_DOMAIN": "api.up.railway.app"}},
+		},
+	}}
+
+	result, err := c.cloneEnvironment(context.Background(), rw, "user-1", source, cloneOptions{Name: "feature-x", ProjectID: "rw-proj-2"})
+	require.NoError(t, err)
+
+	require.Len(t, rw.envInputs, 1)
+	assert.Equal(t, railway.CreateEnvironmentInput{ProjectID: "rw-proj-2", Name: "feature-x"}, rw.envInputs[0])
+
+	require.Len(t, rw.sharedInputs, 1)
+	assert.Equal(t, railway.UpsertVariableCollectionInput{
+		ProjectID:     "rw-proj-2",
+		EnvironmentID: "rw-env-clone",
+		Variables:     map[string]string{"SHARED": "1", "REGION": "eu"},
+		SkipDeploys:   true,
+	}, rw.sharedInputs[0])
+
+	require.Len(t, rw.serviceInputs, 2)
+	api := rw.serviceInputs[0]
+	assert.Equal(t, "rw-env-clone", api.EnvironmentID)
+	assert.Equal(t, "acme/api", *api.Repo)
+	assert.Equal(t, map[string]string{
+		"SHARED":                 "2",
+		"REGION":                 "${{shared.REGION}}",
+		"PORT":                   "8080",
+		railwayDockerfilePathVar: "services/api/Dockerfile",
+	}, api.Variables)
+	cache := rw.serviceInputs[1]
+	require.NotNil(t, cache.Image)
+	assert.Equal(t, "redis:7", *cache.Image)
+	assert.Nil(t, cache.Variables)
+
+	var env store.Environment
+	require.NoError(t, c.DB.Preload("Services").First(&env, "id = ?", result.EnvironmentID).Error)
+	assert.Equal(t, "rw-env-clone", env.RailwayEnvironmentID)
+	assert.Equal(t, "rw-proj-2", env.RailwayProjectID)
+	assert.Equal(t, store.EnvironmentTypeStaging, env.Type)
+	assert.Len(t, env.Services, 2)
+
+	var meta store.EnvironmentMetadata
+	require.NoError(t, c.DB.First(&meta, "environment_id = ?", env.ID).Error)
+	require.NotNil(t, meta.ClonedFromEnvID)
+	assert.Equal(t, source.ID, *meta.ClonedFromEnvID)
+}
+
+func TestCloneEnvironment_VariableReadFailureCreatesNothing(t *testing.T) {
+	c, source := seedCloneSource(t)
+	rw := &fakeEnvironmentCloner{varsErr: errors.New("railway down")}
+
+	result, err := c.cloneEnvironment(context.Background(), rw, "user-1", source, cloneOptions{Name: "feature-x"})
+	require.Error(t, err)
+	assert.Empty(t, result.RailwayEnvironmentID)
+	assert.Empty(t, rw.envInputs)
+}
+
+func TestCloneEnvironment_ServiceFailureReturnsPartialResult(t *testing.T) {
+	c, source := seedCloneSource(t)
+	rw := &fakeEnvironmentCloner{createServiceErr: errors.New("quota exceeded")}
+
+	result, err := c.cloneEnvironment(context.Background(), rw, "user-1", source, cloneOptions{Name: "feature-x"})
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "create service api"))
+	assert.Equal(t, "rw-env-clone", result.RailwayEnvironmentID)
+	assert.NotEmpty(t, result.EnvironmentID)
+	assert.Empty(t, result.Services)
+}
+
+func TestCloneEnvironment_SourceNotFound(t *testing.T) {
+	c, _ := seedCloneSource(t)
+	c.Railway = &mockRailwayClientForEnvironment{}
+
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	auth.SetCurrentUser(ctx, &store.User{ID: "user-2"})
+	ctx.Params = gin.Params{{Key: "id", Value: "rw-env-src"}}
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/api/v1/environments/rw-env-src/clone", strings.NewReader(`{"name":"copy"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	c.CloneEnvironment(ctx)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Database engines supported by the database service kind
+const (
+	DatabaseEnginePostgres = "postgres"
+	DatabaseEngineRedis    = "redis"
+	DatabaseEngineMySQL    = "mysql"
+	DatabaseEngineMongo    = "mongo"
+)
+
+// databasePasswordBytes is the entropy of generated database passwords (hex encoded, so twice as
+// many characters)
+const databasePasswordBytes = 24
+
+// DatabaseSpec provisions a service from one of Railway's database templates.
+type DatabaseSpec struct {
+	Engine   string   `json:"engine"`             // postgres, redis, mysql or mongo
+	ExposeTo []string `json:"exposeTo,omitempty"` // Sibling services that get the connection URL as a reference
+}
+
+// databaseTemplate mirrors one of Railway's database templates: the image it runs and the
+// variables it defines. Variables use Railway's ${{VAR}} self-references so connection details
+// follow the service's private domain, and the password is generated per service.
+type databaseTemplate struct {
+	imageName        string
+	imageTag         string
+	urlVariable      string // Connection URL exposed to sibling services
+	passwordVariable string
+	mountPath        string // Data directory mounted on a volume unless the spec sets its own volumes
+	variables        map[string]string
+}
+
+// databaseTemplates are the supported engines, keyed by DatabaseSpec.Engine
+var databaseTemplates = map[string]databaseTemplate{
+	DatabaseEnginePostgres: {
+		imageName:        "ghcr.io/railwayapp-templates/postgres-ssl",
+		imageTag:         "16",
+		urlVariable:      "DATABASE_URL",
+		passwordVariable: "POSTGRES_PASSWORD",
+		mountPath:        "/var/lib/postgresql/data",
+		variables: map[string]string{
+			"POSTGRES_DB":   "railway",
+			"POSTGRES_USER": "postgres",
+			"PGDATA":        "/var/lib/postgresql/data/pgdata",
+			"PGHOST":        "${{RAILWAY_PRIVATE_DOMAIN}}",
+			"PGPORT":        "5432",
+			"PGUSER":        "${{POSTGRES_USER}}",
+			"PGPASSWORD":    "${{POSTGRES_PASSWORD}}",
+			"PGDATABASE":    "${{POSTGRES_DB}}",
+			"DATABASE_URL":  "postgresql://${{PGUSER}}:${{POSTGRES_PASSWORD}}@${{RAILWAY_PRIVATE_DOMAIN}}:5432/${{PGDATABASE}}",
+		},
+	},
+	DatabaseEngineRedis: {
+		imageName:        "bitnami/redis",
+		imageTag:         "7.2",
+		urlVariable:      "REDIS_URL",
+		passwordVariable: "REDIS_PASSWORD",
+		mountPath:        "/bitnami",
+		variables: map[string]string{
+			"REDISHOST": "${{RAILWAY_PRIVATE_DOMAIN}}",
+			"REDISPORT": "6379",
+			"REDISUSER": "default",
+			"REDIS_URL": "redis://${{REDISUSER}}:${{REDIS_PASSWORD}}@${{RAILWAY_PRIVATE_DOMAIN}}:6379",
+		},
+	},
+	DatabaseEngineMySQL: {
+		imageName:        "mysql",
+		imageTag:         "9",
+		urlVariable:      "MYSQL_URL",
+		passwordVariable: "MYSQL_ROOT_PASSWORD",
+		mountPath:        "/var/lib/mysql",
+		variables: map[string]string{
+			"MYSQL_DATABASE": "railway",
+			"MYSQLHOST":      "${{RAILWAY_PRIVATE_DOMAIN}}",
+			"MYSQLPORT":      "3306",
+			"MYSQLUSER":      "root",
+			"MYSQLPASSWORD":  "${{MYSQL_ROOT_PASSWORD}}",
+			"MYSQLDATABASE":  "${{MYSQL_DATABASE}}",
+			"MYSQL_URL":      "mysql://${{MYSQLUSER}}:${{MYSQL_ROOT_PASSWORD}}@${{RAILWAY_PRIVATE_DOMAIN}}:3306/${{MYSQL_DATABASE}}",
+		},
+	},
+	DatabaseEngineMongo: {
+		imageName:        "mongo",
+		imageTag:         "7",
+		urlVariable:      "MONGO_URL",
+		passwordVariable: "MONGO_INITDB_ROOT_PASSWORD",
+		mountPath:        "/data/db",
+		variables: map[string]string{
+			"MONGO_INITDB_ROOT_USERNAME": "mongo",
+			"MONGOHOST":                  "${{RAILWAY_PRIVATE_DOMAIN}}",
+			"MONGOPORT":                  "27017",
+			"MONGOUSER":                  "${{MONGO_INITDB_ROOT_USERNAME}}",
+			"MONGOPASSWORD":              "${{MONGO_INITDB_ROOT_PASSWORD}}",
+			"MONGO_URL":                  "mongodb://${{MONGO_INITDB_ROOT_USERNAME}}:${{MONGO_INITDB_ROOT_PASSWORD}}@${{RAILWAY_PRIVATE_DOMAIN}}:27017",
+		},
+	},
+}
+
+// databaseEngines returns the supported engine names in sorted order.
+func databaseEngines() []string {
+	engines := make([]string, 0, len(databaseTemplates))
+	for e := range databaseTemplates {
+		engines = append(engines, e)
+	}
+	sort.Strings(engines)
+	return engines
+}
+
+// lookupDatabaseTemplate returns the template for engine or an error naming the supported ones.
+func lookupDatabaseTemplate(engine string) (databaseTemplate, error) {
+	tmpl, ok := databaseTemplates[engine]
+	if !ok {
+		return databaseTemplate{}, fmt.Errorf("unsupported database engine %q (supported: %s)", engine, strings.Join(databaseEngines(), ", "))
+	}
+	return tmpl, nil
+}
+
+// databaseVariables returns the template's variables with a freshly generated password.
+func databaseVariables(engine string) (map[string]string, error) {
+	tmpl, err := lookupDatabaseTemplate(engine)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, databasePasswordBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate database password: %w", err)
+	}
+	vars := make(map[string]string, len(tmpl.variables)+1)
+	for k, v := range tmpl.variables {
+		vars[k] = v
+	}
+	vars[tmpl.passwordVariable] = hex.EncodeToString(buf)
+	return vars, nil
+}
+
+// isDatabaseTemplateVariable reports whether name is defined by the engine's template, including
+// its password. Such variables are regenerated rather than copied.
+func isDatabaseTemplateVariable(engine, name string) bool {
+	tmpl, ok := databaseTemplates[engine]
+	if !ok {
+		return false
+	}
+	_, defined := tmpl.variables[name]
+	return defined || name == tmpl.passwordVariable
+}
+
+// expandDatabaseSpecs turns database specs into image specs running the template image with the
+// template variables; the spec's own variables override the template's. Databases without
+// volumes get one on the template's data directory. Each service named in
+// ExposeTo gets the connection URL as a ${{service.VAR}} reference unless it sets that variable
+// itself, so the reference is validated, ordered and recorded like any other link. specs is not
+// modified.
+func expandDatabaseSpecs(specs []ServiceSpec) ([]ServiceSpec, error) {
+	out := make([]ServiceSpec, len(specs))
+	copy(out, specs)
+	byName := make(map[string]int, len(out))
+	for i, s := range out {
+		byName[s.Name] = i
+	}
+
+	for i := range out {
+		// Read from out so references injected by earlier databases are kept
+		s := out[i]
+		if s.Database == nil {
+			continue
+		}
+		tmpl, err := lookupDatabaseTemplate(s.Database.Engine)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s': %w", s.Name, err)
+		}
+		vars, err := databaseVariables(s.Database.Engine)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range s.EnvVars {
+			vars[k] = v
+		}
+		imageName, imageTag := tmpl.imageName, tmpl.imageTag
+		out[i].ImageName = &imageName
+		out[i].ImageTag = &imageTag
+		out[i].EnvVars = vars
+		if len(s.Volumes) == 0 {
+			out[i].Volumes = []VolumeSpec{{MountPath: tmpl.mountPath}}
+		}
+
+		for _, target := range s.Database.ExposeTo {
+			j, ok := byName[target]
+			if !ok || j == i {
+				return nil, fmt.Errorf("%w: database %s is exposed to %q, which is not a sibling service in the request", errInvalidServiceReference, s.Name, target)
+			}
+			if _, set := out[j].EnvVars[tmpl.urlVariable]; set {
+				continue
+			}
+			siblingVars := make(map[string]string, len(out[j].EnvVars)+1)
+			for k, v := range out[j].EnvVars {
+				siblingVars[k] = v
+			}
+			siblingVars[tmpl.urlVariable] = fmt.Sprintf("${{%s.%s}}", s.Name, tmpl.urlVariable)
+			out[j].EnvVars = siblingVars
+		}
+	}
+	return out, nil
+}
+
+// planProvisioning expands database specs and plans the service links of a provisioning request.
+func planProvisioning(specs []ServiceSpec, existing map[string]bool) (provisionPlan, error) {
+	expanded, err := expandDatabaseSpecs(specs)
+	if err != nil {
+		return provisionPlan{}, err
+	}
+	return planServiceLinks(expanded, existing)
+}
+
+// deleteDataQueryParam confirms deleting a database service and the data it holds
+const deleteDataQueryParam = "deleteData"
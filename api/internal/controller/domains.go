@@ -0,0 +1,279 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+// URL kinds reported by GET /environments/:id/urls
+const (
+	urlKindRailway    = "railway"    // Railway-generated *.up.railway.app domain
+	urlKindCustom     = "custom"     // User-owned domain
+	urlKindDeployment = "deployment" // Static URL of the latest deployment without a listed domain
+)
+
+// httpsScheme is prefixed to bare hostnames returned by Railway
+const httpsScheme = "https://"
+
+var (
+	// errInvalidDomain is returned when a custom domain isn't a bare hostname
+	errInvalidDomain = errors.New("domain must be a hostname such as api.example.com")
+	// errRailwayEnvironmentNotFound is returned when the environment no longer exists in Railway
+	errRailwayEnvironmentNotFound = errors.New("environment not found in railway project")
+)
+
+// serviceDomainClient is the subset of the Railway client used to add domains to a service.
+type serviceDomainClient interface {
+	CreateServiceDomain(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error)
+	CreateCustomDomain(ctx context.Context, in railway.CreateCustomDomainInput) (railway.CustomDomain, error)
+}
+
+// environmentURLSource is the subset of the Railway client used to list an environment's URLs.
+type environmentURLSource interface {
+	GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error)
+	ListServiceDomains(ctx context.Context, in railway.ListServiceDomainsInput) (railway.ServiceDomains, error)
+}
+
+// AddServiceDomainRequest adds a domain to a service. Without Domain a Railway domain is generated.
+type AddServiceDomainRequest struct {
+	Domain     string `json:"domain"`     // Optional custom hostname
+	TargetPort *int   `json:"targetPort"` // Optional port to route to
+}
+
+// ServiceDomainResponse is a domain added to a service
+type ServiceDomainResponse struct {
+	ServiceID  string              `json:"serviceId"`
+	ID         string              `json:"id"`
+	Domain     string              `json:"domain"`
+	URL        string              `json:"url"`
+	Custom     bool                `json:"custom"`
+	TargetPort *int                `json:"targetPort,omitempty"`
+	DNSRecords []railway.DNSRecord `json:"dnsRecords,omitempty"` // Records to create for custom domains
+}
+
+// ServiceURLDTO is one URL a service is reachable at
+type ServiceURLDTO struct {
+	URL        string `json:"url"`
+	Kind       string `json:"kind"` // railway, custom or deployment
+	TargetPort *int   `json:"targetPort,omitempty"`
+}
+
+// ServiceURLsDTO lists the URLs of one service in an environment
+type ServiceURLsDTO struct {
+	ServiceID        string          `json:"serviceId,omitempty"` // Empty for services Mirage doesn't track
+	RailwayServiceID string          `json:"railwayServiceId"`
+	Name             string          `json:"name"`
+	URLs             []ServiceURLDTO `json:"urls"`
+}
+
+// EnvironmentURLsResponse lists the URLs of every service in an environment
+type EnvironmentURLsResponse struct {
+	EnvironmentID        string           `json:"environmentId"`
+	RailwayEnvironmentID string           `json:"railwayEnvironmentId"`
+	Services             []ServiceURLsDTO `json:"services"`
+}
+
+// AddServiceDomain generates a Railway domain for the service or attaches a custom one.
+// POST /api/v1/services/:id/domains
+// Request body (optional): {"domain": "api.example.com", "targetPort": 8080}
+func (c *ServicesController) AddServiceDomain(ctx *gin.Context) {
+	var req AddServiceDomainRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TargetPort != nil && (*req.TargetPort < minPort || *req.TargetPort > maxPort) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("targetPort must be between %d and %d", minPort, maxPort)})
+		return
+	}
+
+	svc, env, rwClient, ok := c.serviceActionTarget(ctx)
+	if !ok {
+		return
+	}
+
+	resp, err := addServiceDomain(ctx, rwClient, svc, env, req)
+	switch {
+	case errors.Is(err, errInvalidDomain):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Error().Err(err).Str("service_id", svc.ID).Str("domain", req.Domain).Msg("failed to add service domain")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to add domain", "message": err.Error()})
+	default:
+		ctx.JSON(http.StatusCreated, resp)
+	}
+}
+
+// addServiceDomain creates the requested domain for the service's instance in env.
+func addServiceDomain(ctx context.Context, rw serviceDomainClient, svc store.Service, env store.Environment, req AddServiceDomainRequest) (ServiceDomainResponse, error) {
+	custom := strings.ToLower(strings.TrimSpace(req.Domain))
+	if custom == "" {
+		d, err := rw.CreateServiceDomain(ctx, railway.CreateServiceDomainInput{
+			EnvironmentID: env.RailwayEnvironmentID,
+			ServiceID:     svc.RailwayServiceID,
+			TargetPort:    req.TargetPort,
+		})
+		if err != nil {
+			return ServiceDomainResponse{}, err
+		}
+		return ServiceDomainResponse{ServiceID: svc.ID, ID: d.ID, Domain: d.Domain, URL: hostURL(d.Domain), TargetPort: d.TargetPort}, nil
+	}
+
+	if !isHostname(custom) {
+		return ServiceDomainResponse{}, errInvalidDomain
+	}
+	d, err := rw.CreateCustomDomain(ctx, railway.CreateCustomDomainInput{
+		ProjectID:     env.RailwayProjectID,
+		EnvironmentID: env.RailwayEnvironmentID,
+		ServiceID:     svc.RailwayServiceID,
+		Domain:        custom,
+		TargetPort:    req.TargetPort,
+	})
+	if err != nil {
+		return ServiceDomainResponse{}, err
+	}
+	return ServiceDomainResponse{
+		ServiceID:  svc.ID,
+		ID:         d.ID,
+		Domain:     d.Domain.Domain,
+		URL:        hostURL(d.Domain.Domain),
+		Custom:     true,
+		TargetPort: d.TargetPort,
+		DNSRecords: d.DNSRecords,
+	}, nil
+}
+
+// GetEnvironmentURLs lists the URLs of every service in the environment: generated and custom
+// domains plus the latest deployment's static URL.
+// GET /api/v1/environments/:id/urls
+func (c *EnvironmentController) GetEnvironmentURLs(ctx *gin.Context) {
+	env, rwClient, ok := c.railwayEnvironmentTarget(ctx)
+	if !ok {
+		return
+	}
+
+	resp, err := c.listEnvironmentURLs(ctx, rwClient, env)
+	switch {
+	case errors.Is(err, errRailwayEnvironmentNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Error().Err(err).Str("env_id", env.ID).Msg("failed to list environment urls")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch environment urls from railway", "message": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
+
+// listEnvironmentURLs collects the URLs of each live service instance in env. Services are listed
+// from Railway so untracked ones show up too; tracked ones carry their Mirage ID.
+func (c *EnvironmentController) listEnvironmentURLs(ctx context.Context, rw environmentURLSource, env store.Environment) (EnvironmentURLsResponse, error) {
+	var services []store.Service
+	if err := c.DB.WithContext(ctx).Select("id", "railway_service_id").Where("environment_id = ?", env.ID).Find(&services).Error; err != nil {
+		return EnvironmentURLsResponse{}, err
+	}
+	mirageIDs := make(map[string]string, len(services))
+	for _, s := range services {
+		mirageIDs[s.RailwayServiceID] = s.ID
+	}
+
+	details, err := rw.GetProjectWithDetailsByID(ctx, env.RailwayProjectID)
+	if err != nil {
+		return EnvironmentURLsResponse{}, err
+	}
+	var instances []railway.ServiceInstance
+	found := false
+	for _, e := range details.Environments {
+		if e.ID == env.RailwayEnvironmentID {
+			instances, found = e.Services, true
+			break
+		}
+	}
+	if !found {
+		return EnvironmentURLsResponse{}, errRailwayEnvironmentNotFound
+	}
+
+	resp := EnvironmentURLsResponse{
+		EnvironmentID:        env.ID,
+		RailwayEnvironmentID: env.RailwayEnvironmentID,
+		Services:             make([]ServiceURLsDTO, 0, len(instances)),
+	}
+	for _, inst := range instances {
+		if inst.DeletedAt != nil {
+			continue
+		}
+		domains, err := rw.ListServiceDomains(ctx, railway.ListServiceDomainsInput{
+			ProjectID:     env.RailwayProjectID,
+			EnvironmentID: env.RailwayEnvironmentID,
+			ServiceID:     inst.ServiceID,
+		})
+		if err != nil {
+			return EnvironmentURLsResponse{}, fmt.Errorf("service %s: %w", inst.ServiceName, err)
+		}
+
+		urls := make([]ServiceURLDTO, 0, len(domains.ServiceDomains)+len(domains.CustomDomains))
+		seen := make(map[string]bool)
+		add := func(host, kind string, port *int) {
+			if host == "" {
+				return
+			}
+			u := hostURL(host)
+			if seen[u] {
+				return
+			}
+			seen[u] = true
+			urls = append(urls, ServiceURLDTO{URL: u, Kind: kind, TargetPort: port})
+		}
+		for _, d := range domains.ServiceDomains {
+			add(d.Domain, urlKindRailway, d.TargetPort)
+		}
+		for _, d := range domains.CustomDomains {
+			add(d.Domain, urlKindCustom, d.TargetPort)
+		}
+		if ld := inst.LatestDeployment; ld != nil && ld.StaticURL != nil {
+			add(*ld.StaticURL, urlKindDeployment, nil)
+		}
+
+		resp.Services = append(resp.Services, ServiceURLsDTO{
+			ServiceID:        mirageIDs[inst.ServiceID],
+			RailwayServiceID: inst.ServiceID,
+			Name:             inst.ServiceName,
+			URLs:             urls,
+		})
+	}
+	return resp, nil
+}
+
+// hostURL turns a Railway hostname into an https URL, leaving full URLs untouched.
+func hostURL(host string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return httpsScheme + host
+}
+
+// isHostname reports whether s looks like a bare, dotted hostname without scheme, path or port.
+func isHostname(s string) bool {
+	if !strings.Contains(s, ".") || strings.ContainsAny(s, "/:@ ") {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
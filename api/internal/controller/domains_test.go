@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeDomainClient struct {
+	generated []railway.CreateServiceDomainInput
+	custom    []railway.CreateCustomDomainInput
+	domains   map[string]railway.ServiceDomains // keyed by Railway service ID
+	details   railway.ProjectDetails
+}
+
+func (f *fakeDomainClient) CreateServiceDomain(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error) {
+	f.generated = append(f.generated, in)
+	return railway.Domain{ID: "dom-1", Domain: "api-production.up.railway.app", TargetPort: in.TargetPort}, nil
+}
+
+func (f *fakeDomainClient) CreateCustomDomain(ctx context.Context, in railway.CreateCustomDomainInput) (railway.CustomDomain, error) {
+	f.custom = append(f.custom, in)
+	return railway.CustomDomain{
+		Domain:     railway.Domain{ID: "cd-1", Domain: in.Domain},
+		DNSRecords: []railway.DNSRecord{{Hostlabel: "api", RecordType: "DNS_RECORD_TYPE_CNAME", RequiredValue: "abc.up.railway.app"}},
+	}, nil
+}
+
+func (f *fakeDomainClient) GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error) {
+	return f.details, nil
+}
+
+func (f *fakeDomainClient) ListServiceDomains(ctx context.Context, in railway.ListServiceDomainsInput) (railway.ServiceDomains, error) {
+	return f.domains[in.ServiceID], nil
+}
+
+func TestAddServiceDomain_GeneratedAndCustom(t *testing.T) {
+	rw := &fakeDomainClient{}
+	svc := store.Service{ID: "svc-1", RailwayServiceID: "rw-svc"}
+	env := store.Environment{ID: "env-1", RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-env"}
+	port := 8080
+
+	resp, err := addServiceDomain(context.Background(), rw, svc, env, AddServiceDomainRequest{TargetPort: &port})
+	require.NoError(t, err)
+	assert.Equal(t, []railway.CreateServiceDomainInput{{EnvironmentID: "rw-env", ServiceID: "rw-svc", TargetPort: &port}}, rw.generated)
+	assert.Equal(t, "https://api-production.up.railway.app", resp.URL)
+	assert.False(t, resp.Custom)
+
+	resp, err = addServiceDomain(context.Background(), rw, svc, env, AddServiceDomainRequest{Domain: " API.example.com "})
+	require.NoError(t, err)
+	require.Len(t, rw.custom, 1)
+	assert.Equal(t, railway.CreateCustomDomainInput{ProjectID: "rw-proj", EnvironmentID: "rw-env", ServiceID: "rw-svc", Domain: "api.example.com"}, rw.custom[0])
+	assert.True(t, resp.Custom)
+	assert.Len(t, resp.DNSRecords, 1)
+
+	_, err = addServiceDomain(context.Background(), rw, svc, env, AddServiceDomainRequest{Domain: "https://api.example.com/"})
+	assert.ErrorIs(t, err, errInvalidDomain)
+	assert.Len(t, rw.custom, 1)
+}
+
+func TestListEnvironmentURLs_MergesDomainsAndDeploymentURL(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	env := store.Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: store.EnvironmentTypeDev, RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-env"}
+	require.NoError(t, db.Create(&env).Error)
+	require.NoError(t, db.Create(&store.Service{ID: "svc-api", UserID: "user-1", EnvironmentID: "env-1", Name: "api", RailwayServiceID: "rw-api"}).Error)
+
+	static := "api-production.up.railway.app"
+	deleted := "2026-01-01T00:00:00Z"
+	rw := &fakeDomainClient{
+		details: railway.ProjectDetails{Environments: []railway.ProjectEnvironment{{
+			ID: "rw-env",
+			Services: []railway.ServiceInstance{
+				{ServiceID: "rw-api", ServiceName: "api", LatestDeployment: &railway.LatestDeployment{StaticURL: &static}},
+				{ServiceID: "rw-worker", ServiceName: "worker"},
+				{ServiceID: "rw-gone", ServiceName: "gone", DeletedAt: &deleted},
+			},
+		}}},
+		domains: map[string]railway.ServiceDomains{
+			"rw-api": {
+				ServiceDomains: []railway.Domain{{Domain: static}},
+				CustomDomains:  []railway.Domain{{Domain: "api.example.com"}},
+			},
+		},
+	}
+	c := &EnvironmentController{DB: db}
+
+	resp, err := c.listEnvironmentURLs(context.Background(), rw, env)
+	require.NoError(t, err)
+	require.Len(t, resp.Services, 2)
+	assert.Equal(t, "svc-api", resp.Services[0].ServiceID)
+	assert.Equal(t, []ServiceURLDTO{
+		{URL: "https://api-production.up.railway.app", Kind: urlKindRailway},
+		{URL: "https://api.example.com", Kind: urlKindCustom},
+	}, resp.Services[0].URLs)
+	assert.Empty(t, resp.Services[1].ServiceID)
+	assert.Empty(t, resp.Services[1].URLs)
+
+	env.RailwayEnvironmentID = "rw-other"
+	_, err = c.listEnvironmentURLs(context.Background(), rw, env)
+	assert.ErrorIs(t, err, errRailwayEnvironmentNotFound)
+}
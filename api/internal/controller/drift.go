@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/reconcile"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// ApplyDriftResponse is the drift found and the database corrections made for it.
+type ApplyDriftResponse struct {
+	Drift   reconcile.Report      `json:"drift"`
+	Applied reconcile.ApplyResult `json:"applied"`
+}
+
+// GetEnvironmentDrift compares the environment's Mirage records with Railway and reports
+// services that are missing, extra or changed (source, branch, image).
+// GET /api/v1/environments/:id/drift
+func (c *EnvironmentController) GetEnvironmentDrift(ctx *gin.Context) {
+	env, rwClient, ok := c.railwayEnvironmentTarget(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.checkEnvironmentDrift(ctx, rwClient, env)
+	if err != nil {
+		log.Error().Err(err).Str("env_id", env.ID).Msg("failed to check environment drift")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch environment from railway", "message": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// ApplyEnvironmentDrift detects drift like GetEnvironmentDrift and then corrects the Mirage
+// records to match Railway. Railway itself is never modified.
+// POST /api/v1/environments/:id/drift/apply
+func (c *EnvironmentController) ApplyEnvironmentDrift(ctx *gin.Context) {
+	env, rwClient, ok := c.railwayEnvironmentTarget(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.checkEnvironmentDrift(ctx, rwClient, env)
+	if err != nil {
+		log.Error().Err(err).Str("env_id", env.ID).Msg("failed to check environment drift")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch environment from railway", "message": err.Error()})
+		return
+	}
+
+	applied, err := c.applyEnvironmentDrift(ctx, &env, report)
+	if err != nil {
+		log.Error().Err(err).Str("env_id", env.ID).Msg("failed to apply environment drift")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to correct environment records"})
+		return
+	}
+	ctx.JSON(http.StatusOK, ApplyDriftResponse{Drift: report, Applied: applied})
+}
+
+// railwayEnvironmentTarget loads the environment named by the :id route param (a Railway environment
+// ID) for the current user along with their Railway client, writing the error response and
+// returning false on failure.
+func (c *EnvironmentController) railwayEnvironmentTarget(ctx *gin.Context) (store.Environment, *railway.Client, bool) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return store.Environment{}, nil, false
+	}
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "railway environment id required"})
+		return store.Environment{}, nil, false
+	}
+
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return store.Environment{}, nil, false
+	}
+
+	var env store.Environment
+	err = c.DB.Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return store.Environment{}, nil, false
+	} else if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to query environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment"})
+		return store.Environment{}, nil, false
+	}
+
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return store.Environment{}, nil, false
+	}
+	return env, rwClient, true
+}
+
+// checkEnvironmentDrift loads the environment's services and compares them with Railway.
+func (c *EnvironmentController) checkEnvironmentDrift(ctx context.Context, rw reconcile.ProjectSource, env store.Environment) (reconcile.Report, error) {
+	var services []store.Service
+	if err := c.DB.WithContext(ctx).Where("environment_id = ?", env.ID).Order("created_at ASC").Find(&services).Error; err != nil {
+		return reconcile.Report{}, err
+	}
+	return reconcile.Check(ctx, rw, env, services, time.Now())
+}
+
+// applyEnvironmentDrift corrects the database and, when the environment is gone from Railway,
+// removes its Vault secrets as environment deletion does.
+func (c *EnvironmentController) applyEnvironmentDrift(ctx context.Context, env *store.Environment, report reconcile.Report) (reconcile.ApplyResult, error) {
+	applied, err := reconcile.Apply(ctx, c.DB, env, report, time.Now())
+	if err != nil {
+		return applied, err
+	}
+	log.Info().
+		Str("env_id", env.ID).
+		Bool("environment_deleted", applied.EnvironmentDeleted).
+		Int64("services_deleted", applied.ServicesDeleted).
+		Int("services_created", applied.ServicesCreated).
+		Int("services_updated", applied.ServicesUpdated).
+		Msg("applied environment drift")
+
+	if applied.EnvironmentDeleted && c.Vault != nil {
+		if err := c.Vault.DeleteAllEnvironmentSecrets(ctx, env.UserID, env.ID); err != nil {
+			log.Warn().Err(err).Str("env_id", env.ID).Msg("failed to delete secrets of environment missing from railway")
+		}
+	}
+	return applied, nil
+}
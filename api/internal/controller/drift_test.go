@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/reconcile"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeProjectSource struct {
+	details railway.ProjectDetails
+	asked   []string
+}
+
+func (f *fakeProjectSource) GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error) {
+	f.asked = append(f.asked, id)
+	return f.details, nil
+}
+
+func TestEnvironmentDrift_DetectsAndApplies(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	env := store.Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: store.EnvironmentTypeDev, RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-env"}
+	require.NoError(t, db.Create(&env).Error)
+	require.NoError(t, db.Create(&store.Service{ID: "svc-api", UserID: "user-1", EnvironmentID: "env-1", Name: "api", RailwayServiceID: "rw-api"}).Error)
+	require.NoError(t, db.Create(&store.Service{ID: "svc-old", UserID: "user-1", EnvironmentID: "env-1", Name: "old", RailwayServiceID: "rw-old"}).Error)
+
+	rw := &fakeProjectSource{details: railway.ProjectDetails{Environments: []railway.ProjectEnvironment{{
+		ID: "rw-env",
+		Services: []railway.ServiceInstance{
+			{ServiceID: "rw-api", ServiceName: "api-renamed"},
+		},
+	}}}}
+	c := &EnvironmentController{DB: db}
+
+	report, err := c.checkEnvironmentDrift(context.Background(), rw, env)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rw-proj"}, rw.asked)
+	require.Len(t, report.Services, 2)
+	assert.Equal(t, reconcile.DriftChanged, report.Services[0].Kind)
+	assert.Equal(t, reconcile.DriftMissing, report.Services[1].Kind)
+
+	applied, err := c.applyEnvironmentDrift(context.Background(), &env, report)
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.ApplyResult{ServicesDeleted: 1, ServicesUpdated: 1}, applied)
+
+	var services []store.Service
+	require.NoError(t, db.Where("environment_id = ?", env.ID).Find(&services).Error)
+	require.Len(t, services, 1)
+	assert.Equal(t, "api-renamed", services[0].Name)
+}
+
+func TestGetEnvironmentDrift_NotFoundForOtherUser(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&store.Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: store.EnvironmentTypeDev, RailwayEnvironmentID: "rw-env"}).Error)
+	c := &EnvironmentController{DB: db, Railway: &mockRailwayClientForEnvironment{}}
+
+	ctx, w := newTemplateRequest(t, "user-2", "rw-env", "/api/v1/environments/rw-env/drift", "")
+	c.GetEnvironmentDrift(ctx)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -46,6 +46,10 @@ type EnvironmentController struct {
 	DB      *gorm.DB
 	Railway RailwayEnvironmentClient
 	Vault   *vault.Client
+	// IdempotencyRetention is how long provisioning responses are kept for replay (default 24h)
+	IdempotencyRetention time.Duration
+	// Concurrency bounds how many services are created in Railway at once (default DefaultProvisionConcurrency)
+	Concurrency int
 }
 
 func (c *EnvironmentController) RegisterRoutes(r *gin.RouterGroup) {
@@ -54,6 +58,9 @@ func (c *EnvironmentController) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/railway/project/:id", c.GetRailwayProject)
 	r.DELETE("/railway/project/:id", c.DeleteRailwayProject)
 	r.DELETE("/railway/environment/:id", c.DeleteRailwayEnvironment)
+	// import environments not created by Mirage
+	r.POST("/railway/environment/:id/import", c.ImportRailwayEnvironment)
+	r.POST("/railway/project/:id/import", c.ImportRailwayProject)
 	// provisioning endpoints
 	r.POST("/provision/project", c.ProvisionProject)
 	r.POST("/provision/environment", c.ProvisionEnvironment)
@@ -61,7 +68,31 @@ func (c *EnvironmentController) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/environments/:id/metadata", c.GetEnvironmentMetadata)
 	r.GET("/environments/:id/services", c.ListEnvironmentServices)
 	r.GET("/environments/:id/snapshot", c.GetEnvironmentSnapshot)
+	r.POST("/environments/:id/clone", c.CloneEnvironment)
+	r.POST("/environments/:id/template", c.SaveEnvironmentTemplate)
+	// declarative manifest endpoints
+	r.GET("/environments/:id/manifest", c.ExportEnvironmentManifest)
+	r.POST("/environments/apply", c.ApplyManifest)
+	// drift detection between Mirage records and Railway
+	r.GET("/environments/:id/drift", c.GetEnvironmentDrift)
+	r.POST("/environments/:id/drift/apply", c.ApplyEnvironmentDrift)
+	// service urls (generated and custom domains)
+	r.GET("/environments/:id/urls", c.GetEnvironmentURLs)
+	// railway variable editing (shared environment variables)
+	r.PUT("/environments/:id/variables", c.PutEnvironmentVariables)
+	r.PATCH("/environments/:id/variables", c.PatchEnvironmentVariables)
+	r.DELETE("/environments/:id/variables", c.DeleteEnvironmentVariables)
+	// ttl management endpoints
+	r.GET("/environments/expiring", c.ListExpiringEnvironments)
+	r.PATCH("/environments/:id/ttl", c.UpdateEnvironmentTTL)
+	// environment secret endpoints (values stored in Vault)
+	r.GET("/environments/:id/secrets", c.ListEnvironmentSecrets)
+	r.POST("/environments/:id/secrets", c.BulkStoreEnvironmentSecrets)
+	r.GET("/environments/:id/secrets/:key", c.GetEnvironmentSecret)
+	r.PUT("/environments/:id/secrets/:key", c.PutEnvironmentSecret)
+	r.DELETE("/environments/:id/secrets/:key", c.DeleteEnvironmentSecret)
 	r.GET("/templates", c.ListTemplates)
+	r.POST("/templates/:id/instantiate", c.InstantiateTemplate)
 }
 
 // ProvisionEnvironmentRequest is the payload to create a Railway environment in an existing project.
@@ -71,6 +102,7 @@ type ProvisionEnvironmentRequest struct {
 	RequestID    string                 `json:"requestId"`
 	EnvType      *store.EnvironmentType `json:"envType,omitempty"`      // Optional: dev, staging, prod, ephemeral
 	WizardInputs map[string]interface{} `json:"wizardInputs,omitempty"` // Optional: full wizard state
+	TTLSeconds   *int64                 `json:"ttlSeconds,omitempty"`   // Optional: lifetime in seconds before the environment is reaped
 }
 
 type ProvisionEnvironmentResponse struct {
@@ -115,11 +147,37 @@ func (c *EnvironmentController) ProvisionEnvironment(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	res, err := rwClient.CreateEnvironment(ctx, railway.CreateEnvironmentInput{ProjectID: req.ProjectID, Name: req.Name})
+	if req.TTLSeconds != nil && *req.TTLSeconds <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ttlSeconds must be positive"})
+		return
+	}
+
+	finish, ok := beginIdempotentRequest(ctx, c.DB, c.IdempotencyRetention, user.ID, idempotencyScopeEnvironment, req.RequestID, req)
+	if !ok {
+		return
+	}
+	defer finish()
+
+	resp, err := c.provisionEnvironment(ctx, rwClient, user.ID, req)
 	if err != nil {
 		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// environmentCreator is the subset of the Railway client used to create an environment.
+type environmentCreator interface {
+	CreateEnvironment(ctx context.Context, in railway.CreateEnvironmentInput) (railway.CreateEnvironmentResult, error)
+}
+
+// provisionEnvironment creates a Railway environment and persists it with its wizard metadata.
+// Persistence failures are logged but not returned since the Railway environment exists.
+func (c *EnvironmentController) provisionEnvironment(ctx context.Context, rw environmentCreator, userID string, req ProvisionEnvironmentRequest) (ProvisionEnvironmentResponse, error) {
+	res, err := rw.CreateEnvironment(ctx, railway.CreateEnvironmentInput{ProjectID: req.ProjectID, Name: req.Name})
+	if err != nil {
+		return ProvisionEnvironmentResponse{}, err
+	}
 
 	// Persist environment to database
 	var env store.Environment
@@ -130,16 +188,19 @@ func (c *EnvironmentController) ProvisionEnvironment(ctx *gin.Context) {
 			envType = *req.EnvType
 		}
 
+		now := time.Now()
 		env = store.Environment{
 			ID:                   uuid.New().String(),
-			UserID:               user.ID, // Set from authenticated user
+			UserID:               userID, // Set from authenticated user
 			Name:                 req.Name,
 			Type:                 envType,
 			Status:               status.StatusCreating,
 			RailwayProjectID:     req.ProjectID,
 			RailwayEnvironmentID: res.EnvironmentID,
-			CreatedAt:            time.Now(),
-			UpdatedAt:            time.Now(),
+			TTLSeconds:           req.TTLSeconds,
+			ExpiresAt:            store.ComputeExpiresAt(now, req.TTLSeconds),
+			CreatedAt:            now,
+			UpdatedAt:            now,
 		}
 
 		// Use transaction to ensure Environment and EnvironmentMetadata are created atomically
@@ -165,7 +226,7 @@ func (c *EnvironmentController) ProvisionEnvironment(ctx *gin.Context) {
 
 				metadata := store.EnvironmentMetadata{
 					ID:                   uuid.New().String(),
-					UserID:               user.ID, // Set from authenticated user
+					UserID:               userID, // Set from authenticated user
 					EnvironmentID:        env.ID,
 					WizardInputsJSON:     wizardInputsJSON,
 					ProvisionOutputsJSON: provisionOutputsJSON,
@@ -180,7 +241,7 @@ func (c *EnvironmentController) ProvisionEnvironment(ctx *gin.Context) {
 				log.Info().
 					Str("env_id", env.ID).
 					Str("metadata_id", metadata.ID).
-					Str("user_id", user.ID).
+					Str("user_id", userID).
 					Msg("persisted environment metadata to database")
 			}
 
@@ -209,10 +270,10 @@ func (c *EnvironmentController) ProvisionEnvironment(ctx *gin.Context) {
 		mirageEnvID = env.ID
 	}
 
-	ctx.JSON(http.StatusOK, ProvisionEnvironmentResponse{
+	return ProvisionEnvironmentResponse{
 		EnvironmentID:        mirageEnvID,       // Mirage ID for foreign keys (or Railway ID if DB unavailable/failed)
 		RailwayEnvironmentID: res.EnvironmentID, // Railway ID for Railway API calls
-	})
+	}, nil
 }
 
 // EnvironmentMetadataDTO represents the complete metadata for an environment
@@ -225,6 +286,7 @@ type EnvironmentMetadataDTO struct {
 	ClonedFromEnvID     *string                `json:"clonedFromEnvId,omitempty"`
 	WizardInputs        map[string]interface{} `json:"wizardInputs,omitempty"`
 	ProvisionOutputs    map[string]interface{} `json:"provisionOutputs,omitempty"`
+	ServiceLinks        []ServiceLink          `json:"serviceLinks,omitempty"`
 	CreatedAt           string                 `json:"createdAt"`
 	UpdatedAt           string                 `json:"updatedAt"`
 }
@@ -249,6 +311,7 @@ type ServiceDetailDTO struct {
 	ImageName        string  `json:"imageName,omitempty"`
 	ImageTag         string  `json:"imageTag,omitempty"`
 	ImageDigest      string  `json:"imageDigest,omitempty"`
+	DatabaseEngine   string  `json:"databaseEngine,omitempty"` // Set for database deployments
 	ExposedPorts     []int   `json:"exposedPorts,omitempty"`
 	HealthCheckPath  *string `json:"healthCheckPath,omitempty"`
 	StartCommand     *string `json:"startCommand,omitempty"`
@@ -312,6 +375,7 @@ func (c *EnvironmentController) GetEnvironmentMetadata(ctx *gin.Context) {
 	// Unmarshal JSON fields
 	var wizardInputs map[string]interface{}
 	var provisionOutputs map[string]interface{}
+	var serviceLinks []ServiceLink
 
 	if len(metadata.WizardInputsJSON) > 0 {
 		if err := json.Unmarshal(metadata.WizardInputsJSON, &wizardInputs); err != nil {
@@ -325,6 +389,12 @@ func (c *EnvironmentController) GetEnvironmentMetadata(ctx *gin.Context) {
 		}
 	}
 
+	if len(metadata.ServiceLinksJSON) > 0 {
+		if err := json.Unmarshal(metadata.ServiceLinksJSON, &serviceLinks); err != nil {
+			log.Warn().Err(err).Msg("failed to unmarshal service links")
+		}
+	}
+
 	dto := EnvironmentMetadataDTO{
 		ID:                  metadata.ID,
 		EnvironmentID:       metadata.EnvironmentID,
@@ -334,6 +404,7 @@ func (c *EnvironmentController) GetEnvironmentMetadata(ctx *gin.Context) {
 		ClonedFromEnvID:     metadata.ClonedFromEnvID,
 		WizardInputs:        wizardInputs,
 		ProvisionOutputs:    provisionOutputs,
+		ServiceLinks:        serviceLinks,
 		CreatedAt:           metadata.CreatedAt.UTC().Format(time.RFC3339),
 		UpdatedAt:           metadata.UpdatedAt.UTC().Format(time.RFC3339),
 	}
@@ -469,7 +540,7 @@ func (c *EnvironmentController) GetEnvironmentSnapshot(ctx *gin.Context) {
 
 	// Look up the Mirage environment by Railway ID with ownership check
 	var env store.Environment
-	err = c.DB.Preload("Services").Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
+	err = c.DB.Preload("Services").Preload("Services.Volumes").Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
 	if err == gorm.ErrRecordNotFound {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
 		return
@@ -564,6 +635,7 @@ func serviceToDTO(svc store.Service) ServiceDetailDTO {
 		ImageName:        svc.ImageName,
 		ImageTag:         svc.ImageTag,
 		ImageDigest:      svc.ImageDigest,
+		DatabaseEngine:   svc.DatabaseEngine,
 		HealthCheckPath:  svc.HealthCheckPath,
 		StartCommand:     svc.StartCommand,
 		CreatedAt:        svc.CreatedAt.UTC().Format(time.RFC3339),
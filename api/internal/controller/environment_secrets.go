@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+	"gorm.io/gorm"
+)
+
+// EnvironmentSecretKeysResponse lists the secret keys stored for an environment (values are never listed)
+type EnvironmentSecretKeysResponse struct {
+	Keys []string `json:"keys"`
+}
+
+// EnvironmentSecretResponse returns a single environment secret
+type EnvironmentSecretResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// PutEnvironmentSecretRequest is the payload for storing a single environment secret
+type PutEnvironmentSecretRequest struct {
+	Value string `json:"value" binding:"required"`
+}
+
+// BulkEnvironmentSecretsRequest is the payload for storing several environment secrets at once
+type BulkEnvironmentSecretsRequest struct {
+	Secrets map[string]string `json:"secrets" binding:"required"`
+}
+
+// ListEnvironmentSecrets lists the keys of all secrets stored for an environment.
+// The :id parameter is the Railway environment ID.
+//
+// GET /api/v1/environments/:id/secrets
+// Response: {"keys": ["API_KEY", "DATABASE_URL"]}
+func (c *EnvironmentController) ListEnvironmentSecrets(ctx *gin.Context) {
+	user, env, ok := c.resolveEnvironmentForSecrets(ctx)
+	if !ok {
+		return
+	}
+
+	secrets, err := c.Vault.GetAllEnvironmentSecrets(ctx, user.ID, env.ID)
+	if err != nil {
+		log.Error().Err(err).Str("env_id", env.ID).Msg("failed to list environment secrets")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list environment secrets"})
+		return
+	}
+
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ctx.JSON(http.StatusOK, EnvironmentSecretKeysResponse{Keys: keys})
+}
+
+// GetEnvironmentSecret returns the value of a single environment secret.
+//
+// GET /api/v1/environments/:id/secrets/:key
+// Response: {"key": "API_KEY", "value": "..."}
+func (c *EnvironmentController) GetEnvironmentSecret(ctx *gin.Context) {
+	user, env, ok := c.resolveEnvironmentForSecrets(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	value, err := c.Vault.GetEnvironmentSecret(ctx, user.ID, env.ID, key)
+	if err != nil {
+		if err == vault.ErrSecretNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "secret not found"})
+			return
+		}
+		log.Error().Err(err).Str("env_id", env.ID).Str("key", key).Msg("failed to get environment secret")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get environment secret"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, EnvironmentSecretResponse{Key: key, Value: value})
+}
+
+// PutEnvironmentSecret creates or updates a single environment secret.
+//
+// PUT /api/v1/environments/:id/secrets/:key
+// Request body: {"value": "..."}
+func (c *EnvironmentController) PutEnvironmentSecret(ctx *gin.Context) {
+	user, env, ok := c.resolveEnvironmentForSecrets(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+	if err := vault.ValidateEnvironmentSecretKey(key); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req PutEnvironmentSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Vault.StoreEnvironmentSecret(ctx, user.ID, env.ID, key, req.Value); err != nil {
+		respondEnvironmentSecretWriteError(ctx, err, env.ID)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// BulkStoreEnvironmentSecrets stores several environment secrets atomically.
+//
+// POST /api/v1/environments/:id/secrets
+// Request body: {"secrets": {"API_KEY": "...", "DATABASE_URL": "..."}}
+func (c *EnvironmentController) BulkStoreEnvironmentSecrets(ctx *gin.Context) {
+	user, env, ok := c.resolveEnvironmentForSecrets(ctx)
+	if !ok {
+		return
+	}
+
+	var req BulkEnvironmentSecretsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := c.Vault.BulkStoreEnvironmentSecrets(ctx, user.ID, env.ID, req.Secrets); err != nil {
+		respondEnvironmentSecretWriteError(ctx, err, env.ID)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// DeleteEnvironmentSecret removes a single environment secret.
+//
+// DELETE /api/v1/environments/:id/secrets/:key
+func (c *EnvironmentController) DeleteEnvironmentSecret(ctx *gin.Context) {
+	user, env, ok := c.resolveEnvironmentForSecrets(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	if err := c.Vault.DeleteEnvironmentSecret(ctx, user.ID, env.ID, key); err != nil {
+		if err == vault.ErrSecretNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "secret not found"})
+			return
+		}
+		log.Error().Err(err).Str("env_id", env.ID).Str("key", key).Msg("failed to delete environment secret")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete environment secret"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// resolveEnvironmentForSecrets performs the checks shared by the environment secret handlers:
+// Vault availability, authentication and ownership of the environment named by :id.
+// It writes the error response and returns ok=false when any check fails.
+func (c *EnvironmentController) resolveEnvironmentForSecrets(ctx *gin.Context) (*store.User, store.Environment, bool) {
+	var env store.Environment
+
+	if c.Vault == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "secret management not available"})
+		return nil, env, false
+	}
+
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "railway environment id required"})
+		return nil, env, false
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return nil, env, false
+	}
+
+	err = c.DB.Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return nil, env, false
+	} else if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to query environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment"})
+		return nil, env, false
+	}
+
+	return user, env, true
+}
+
+// respondEnvironmentSecretWriteError maps Vault write errors to HTTP responses.
+func respondEnvironmentSecretWriteError(ctx *gin.Context, err error, envID string) {
+	switch {
+	case errors.Is(err, vault.ErrSecretLocked):
+		ctx.JSON(http.StatusConflict, gin.H{"error": "secrets were modified concurrently, please retry"})
+	case errors.Is(err, vault.ErrInvalidSecret):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error().Err(err).Str("env_id", envID).Msg("failed to store environment secrets")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to store environment secrets"})
+	}
+}
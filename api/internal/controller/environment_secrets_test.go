@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func TestListEnvironmentSecrets_VaultNotConfigured(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	auth.SetCurrentUser(c, &store.User{ID: "user-1"})
+	c.Params = gin.Params{{Key: "id", Value: "railway-env-1"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/environments/railway-env-1/secrets", nil)
+
+	controller := &EnvironmentController{}
+	controller.ListEnvironmentSecrets(c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestVariableNames_SortedWithoutValues(t *testing.T) {
+	names := variableNames(map[string]string{"PORT": "8080", "API_KEY": "secret"})
+	assert.Equal(t, []string{"API_KEY", "PORT"}, names)
+}
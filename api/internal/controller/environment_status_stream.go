@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/coder/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/events"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// Status stream WebSocket message types
+const (
+	messageTypeEnvironmentStatus = "environment_status" // Data is an EnvironmentStatusDTO
+	messageTypeServiceStatus     = "service_status"     // Data is a ServiceStatusDTO
+)
+
+// EnvironmentStatusController streams environment and service status changes found by the status poller.
+type EnvironmentStatusController struct {
+	DB             *gorm.DB
+	Hub            *events.Hub
+	AllowedOrigins []string
+}
+
+// EnvironmentStatusDTO is a status change for one of the user's environments
+type EnvironmentStatusDTO struct {
+	EnvironmentID        string `json:"environmentId"`        // Mirage internal environment ID
+	RailwayEnvironmentID string `json:"railwayEnvironmentId"` // Railway's environment ID
+	Name                 string `json:"name"`
+	Status               string `json:"status"`
+}
+
+// ServiceStatusDTO is a deployment status change for a service in one of the user's environments
+type ServiceStatusDTO struct {
+	EnvironmentID        string `json:"environmentId"`        // Mirage internal environment ID
+	RailwayEnvironmentID string `json:"railwayEnvironmentId"` // Railway's environment ID
+	ServiceID            string `json:"serviceId"`            // Mirage internal service ID
+	RailwayServiceID     string `json:"railwayServiceId"`     // Railway's service ID
+	Name                 string `json:"name"`
+	Status               string `json:"status"`
+}
+
+// StreamEnvironmentStatus pushes environment and service status changes for the user's
+// environments via WebSocket.
+// GET /api/v1/environments/status/stream
+// Auth is handled via first message after connection, as for log streams
+func (c *EnvironmentStatusController) StreamEnvironmentStatus(ginCtx *gin.Context) {
+	if c.Hub == nil {
+		ginCtx.JSON(http.StatusServiceUnavailable, gin.H{"error": "environment events not configured"})
+		return
+	}
+
+	conn, user, ok := acceptAuthenticatedWebSocket(ginCtx, c.DB, c.AllowedOrigins)
+	if !ok {
+		return
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "connection closed")
+
+	sub := c.Hub.Subscribe(events.EnvironmentStatusTopic)
+	defer sub.Close()
+
+	log.Info().Str("user_id", user.ID).Msg("client connected to environment status stream")
+
+	// CloseRead discards client messages and cancels ctx when the client disconnects
+	ctx := conn.CloseRead(ginCtx.Request.Context())
+
+	if err := writeWebSocketMessage(ctx, conn, messageTypeStatus, "connected"); err != nil {
+		log.Error().Err(err).Msg("failed to send status message")
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Str("user_id", user.ID).Msg("environment status stream cancelled")
+			return
+		case e, open := <-sub.C:
+			if !open {
+				return
+			}
+			msgType, update, ok := c.statusMessageForUser(user.ID, e)
+			if !ok {
+				continue
+			}
+			if err := writeWebSocketMessage(ctx, conn, msgType, update); err != nil {
+				log.Info().Err(err).Str("user_id", user.ID).Msg("environment status stream ended")
+				return
+			}
+		}
+	}
+}
+
+// statusMessageForUser converts a status event into a WebSocket message type and DTO, or
+// returns false when the environment does not belong to userID.
+func (c *EnvironmentStatusController) statusMessageForUser(userID string, e events.Event) (string, interface{}, bool) {
+	switch update := e.Data.(type) {
+	case events.EnvironmentStatus:
+		env, ok := c.userEnvironment(userID, update.EnvironmentID)
+		if !ok {
+			return "", nil, false
+		}
+		return messageTypeEnvironmentStatus, EnvironmentStatusDTO{
+			EnvironmentID:        env.ID,
+			RailwayEnvironmentID: env.RailwayEnvironmentID,
+			Name:                 env.Name,
+			Status:               update.Status,
+		}, true
+	case events.ServiceStatus:
+		env, ok := c.userEnvironment(userID, update.EnvironmentID)
+		if !ok {
+			return "", nil, false
+		}
+		var svc store.Service
+		if err := c.DB.Select("id", "railway_service_id", "name").Where("id = ? AND environment_id = ?", update.ServiceID, env.ID).First(&svc).Error; err != nil {
+			if err != gorm.ErrRecordNotFound {
+				log.Error().Err(err).Str("service_id", update.ServiceID).Msg("failed to load service for status update")
+			}
+			return "", nil, false
+		}
+		return messageTypeServiceStatus, ServiceStatusDTO{
+			EnvironmentID:        env.ID,
+			RailwayEnvironmentID: env.RailwayEnvironmentID,
+			ServiceID:            svc.ID,
+			RailwayServiceID:     svc.RailwayServiceID,
+			Name:                 svc.Name,
+			Status:               update.Status,
+		}, true
+	default:
+		return "", nil, false
+	}
+}
+
+// userEnvironment loads the environment if it belongs to userID.
+func (c *EnvironmentStatusController) userEnvironment(userID, environmentID string) (store.Environment, bool) {
+	var env store.Environment
+	err := c.DB.Select("id", "railway_environment_id", "name").
+		Where("id = ? AND user_id = ?", environmentID, userID).
+		First(&env).Error
+	if err != nil {
+		if err != gorm.ErrRecordNotFound {
+			log.Error().Err(err).Str("env_id", environmentID).Msg("failed to check environment ownership")
+		}
+		return store.Environment{}, false
+	}
+	return env, true
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/events"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func TestStatusMessageForUser_FiltersByOwner(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&store.Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: store.EnvironmentTypeDev, RailwayEnvironmentID: "rw-env-1"}).Error)
+	c := &EnvironmentStatusController{DB: db}
+
+	e := events.Event{Type: events.EventTypeEnvironmentStatus, Data: events.EnvironmentStatus{EnvironmentID: "env-1", Status: "active"}}
+
+	msgType, dto, ok := c.statusMessageForUser("user-1", e)
+	require.True(t, ok)
+	assert.Equal(t, messageTypeEnvironmentStatus, msgType)
+	assert.Equal(t, EnvironmentStatusDTO{EnvironmentID: "env-1", RailwayEnvironmentID: "rw-env-1", Name: "dev", Status: "active"}, dto)
+
+	_, _, ok = c.statusMessageForUser("user-2", e)
+	assert.False(t, ok, "other users must not see the update")
+
+	_, _, ok = c.statusMessageForUser("user-1", events.Event{Type: events.EventTypeJob, Data: store.ProvisioningJob{}})
+	assert.False(t, ok, "unrelated payloads are ignored")
+}
+
+func TestStatusMessageForUser_ServiceStatus(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&store.Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: store.EnvironmentTypeDev, RailwayEnvironmentID: "rw-env-1"}).Error)
+	require.NoError(t, db.Create(&store.Service{ID: "svc-1", UserID: "user-1", EnvironmentID: "env-1", Name: "api", RailwayServiceID: "rw-svc-1"}).Error)
+	c := &EnvironmentStatusController{DB: db}
+
+	e := events.Event{Type: events.EventTypeServiceStatus, Data: events.ServiceStatus{EnvironmentID: "env-1", ServiceID: "svc-1", Status: "error"}}
+
+	msgType, dto, ok := c.statusMessageForUser("user-1", e)
+	require.True(t, ok)
+	assert.Equal(t, messageTypeServiceStatus, msgType)
+	assert.Equal(t, ServiceStatusDTO{
+		EnvironmentID:        "env-1",
+		RailwayEnvironmentID: "rw-env-1",
+		ServiceID:            "svc-1",
+		RailwayServiceID:     "rw-svc-1",
+		Name:                 "api",
+		Status:               "error",
+	}, dto)
+
+	_, _, ok = c.statusMessageForUser("user-2", e)
+	assert.False(t, ok, "other users must not see the update")
+}
@@ -0,0 +1,205 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultExpiringWithinMinutes is the warning window used when withinMinutes is not provided.
+	DefaultExpiringWithinMinutes = 60
+)
+
+// UpdateEnvironmentTTLRequest changes when an environment expires. Exactly one field must be set:
+// ttlSeconds resets the expiry to now + ttlSeconds, extendSeconds pushes the current expiry out
+// (keep alive), and clear removes the TTL so the environment never expires.
+type UpdateEnvironmentTTLRequest struct {
+	TTLSeconds    *int64 `json:"ttlSeconds,omitempty"`
+	ExtendSeconds *int64 `json:"extendSeconds,omitempty"`
+	Clear         bool   `json:"clear,omitempty"`
+}
+
+// EnvironmentTTLDTO describes an environment's expiry.
+type EnvironmentTTLDTO struct {
+	EnvironmentID        string  `json:"environmentId"`
+	RailwayEnvironmentID string  `json:"railwayEnvironmentId"`
+	Name                 string  `json:"name"`
+	Type                 string  `json:"type"`
+	Status               string  `json:"status"`
+	TTLSeconds           *int64  `json:"ttlSeconds,omitempty"`
+	ExpiresAt            *string `json:"expiresAt,omitempty"`
+	SecondsRemaining     *int64  `json:"secondsRemaining,omitempty"`
+}
+
+// UpdateEnvironmentTTL extends, resets or clears an environment's TTL.
+// The :id parameter is the Railway environment ID.
+func (c *EnvironmentController) UpdateEnvironmentTTL(ctx *gin.Context) {
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "railway environment id required"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req UpdateEnvironmentTTLRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateTTLRequest(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var env store.Environment
+	err = c.DB.Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to query environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment"})
+		return
+	}
+
+	if req.ExtendSeconds != nil && env.ExpiresAt == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "environment has no TTL to extend; set ttlSeconds instead"})
+		return
+	}
+
+	now := time.Now()
+	env.SetExpiry(nextExpiry(env.ExpiresAt, req, now))
+
+	if err := c.DB.Model(&env).Select("ttl_seconds", "expires_at").Updates(&env).Error; err != nil {
+		log.Error().Err(err).Str("env_id", env.ID).Msg("failed to update environment ttl")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update ttl"})
+		return
+	}
+
+	log.Info().
+		Str("env_id", env.ID).
+		Str("user_id", user.ID).
+		Bool("cleared", env.ExpiresAt == nil).
+		Msg("updated environment ttl")
+
+	ctx.JSON(http.StatusOK, environmentToTTLDTO(env, now))
+}
+
+// ListExpiringEnvironments lists the user's environments that expire within the next N minutes.
+// Query parameter withinMinutes defaults to DefaultExpiringWithinMinutes.
+func (c *EnvironmentController) ListExpiringEnvironments(ctx *gin.Context) {
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	within := DefaultExpiringWithinMinutes
+	if raw := ctx.Query("withinMinutes"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "withinMinutes must be a positive integer"})
+			return
+		}
+		within = n
+	}
+
+	now := time.Now()
+	deadline := now.Add(time.Duration(within) * time.Minute)
+
+	var envs []store.Environment
+	err = c.DB.Where("user_id = ? AND expires_at IS NOT NULL AND expires_at > ? AND expires_at <= ?", user.ID, now, deadline).
+		Order("expires_at ASC").
+		Find(&envs).Error
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Msg("failed to query expiring environments")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environments"})
+		return
+	}
+
+	dtos := make([]EnvironmentTTLDTO, 0, len(envs))
+	for _, env := range envs {
+		dtos = append(dtos, environmentToTTLDTO(env, now))
+	}
+
+	ctx.JSON(http.StatusOK, dtos)
+}
+
+// validateTTLRequest ensures exactly one TTL operation is requested with a positive value.
+func validateTTLRequest(req UpdateEnvironmentTTLRequest) error {
+	ops := 0
+	if req.TTLSeconds != nil {
+		ops++
+		if *req.TTLSeconds <= 0 {
+			return errors.New("ttlSeconds must be positive")
+		}
+	}
+	if req.ExtendSeconds != nil {
+		ops++
+		if *req.ExtendSeconds <= 0 {
+			return errors.New("extendSeconds must be positive")
+		}
+	}
+	if req.Clear {
+		ops++
+	}
+	if ops != 1 {
+		return errors.New("exactly one of ttlSeconds, extendSeconds or clear must be provided")
+	}
+	return nil
+}
+
+// nextExpiry computes the new expiry for a validated request. Extensions start from the later of
+// now and the current expiry so keep-alive calls on an overdue environment still buy real time.
+func nextExpiry(current *time.Time, req UpdateEnvironmentTTLRequest, now time.Time) *time.Time {
+	switch {
+	case req.Clear:
+		return nil
+	case req.TTLSeconds != nil:
+		at := now.Add(time.Duration(*req.TTLSeconds) * time.Second)
+		return &at
+	default:
+		base := now
+		if current != nil && current.After(now) {
+			base = *current
+		}
+		at := base.Add(time.Duration(*req.ExtendSeconds) * time.Second)
+		return &at
+	}
+}
+
+func environmentToTTLDTO(env store.Environment, now time.Time) EnvironmentTTLDTO {
+	dto := EnvironmentTTLDTO{
+		EnvironmentID:        env.ID,
+		RailwayEnvironmentID: env.RailwayEnvironmentID,
+		Name:                 env.Name,
+		Type:                 string(env.Type),
+		Status:               env.Status,
+		TTLSeconds:           env.TTLSeconds,
+	}
+	if env.ExpiresAt != nil {
+		expiresAt := env.ExpiresAt.UTC().Format(time.RFC3339)
+		remaining := int64(env.ExpiresAt.Sub(now).Seconds())
+		if remaining < 0 {
+			remaining = 0
+		}
+		dto.ExpiresAt = &expiresAt
+		dto.SecondsRemaining = &remaining
+	}
+	return dto
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+func setupTTLTestEnvironment(t *testing.T, ttlSeconds *int64) (*gorm.DB, *store.User, store.Environment) {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+
+	user := &store.User{ID: "user-1", ClerkUserID: "clerk_user_1", Email: "user1@example.com", IsActive: true}
+	require.NoError(t, db.Create(user).Error)
+
+	created := time.Now().Add(-time.Minute)
+	env := store.Environment{
+		ID:                   "env-1",
+		UserID:               user.ID,
+		Name:                 "preview",
+		Type:                 store.EnvironmentTypeEphemeral,
+		RailwayProjectID:     "railway-proj-1",
+		RailwayEnvironmentID: "railway-env-1",
+		TTLSeconds:           ttlSeconds,
+		ExpiresAt:            store.ComputeExpiresAt(created, ttlSeconds),
+		CreatedAt:            created,
+		UpdatedAt:            created,
+	}
+	require.NoError(t, db.Create(&env).Error)
+	return db, user, env
+}
+
+func performTTLUpdate(t *testing.T, db *gorm.DB, user *store.User, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	auth.SetCurrentUser(c, user)
+	c.Params = gin.Params{{Key: "id", Value: "railway-env-1"}}
+	c.Request = httptest.NewRequest(http.MethodPatch, "/api/v1/environments/railway-env-1/ttl", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	controller := &EnvironmentController{DB: db}
+	controller.UpdateEnvironmentTTL(c)
+	return w
+}
+
+func TestUpdateEnvironmentTTL_Extend(t *testing.T) {
+	ttl := int64(3600)
+	db, user, env := setupTTLTestEnvironment(t, &ttl)
+
+	w := performTTLUpdate(t, db, user, `{"extendSeconds": 1800}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var updated store.Environment
+	require.NoError(t, db.First(&updated, "id = ?", env.ID).Error)
+	require.NotNil(t, updated.ExpiresAt)
+	require.NotNil(t, updated.TTLSeconds)
+	assert.WithinDuration(t, env.ExpiresAt.Add(30*time.Minute), *updated.ExpiresAt, time.Second)
+	assert.InDelta(t, 5400, *updated.TTLSeconds, 1)
+}
+
+func TestUpdateEnvironmentTTL_Clear(t *testing.T) {
+	ttl := int64(3600)
+	db, user, env := setupTTLTestEnvironment(t, &ttl)
+
+	w := performTTLUpdate(t, db, user, `{"clear": true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var updated store.Environment
+	require.NoError(t, db.First(&updated, "id = ?", env.ID).Error)
+	assert.Nil(t, updated.ExpiresAt)
+	assert.Nil(t, updated.TTLSeconds)
+}
+
+func TestUpdateEnvironmentTTL_ExtendWithoutTTL(t *testing.T) {
+	db, user, _ := setupTTLTestEnvironment(t, nil)
+
+	w := performTTLUpdate(t, db, user, `{"extendSeconds": 60}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateEnvironmentTTL_RejectsMultipleOperations(t *testing.T) {
+	ttl := int64(3600)
+	db, user, _ := setupTTLTestEnvironment(t, &ttl)
+
+	w := performTTLUpdate(t, db, user, `{"ttlSeconds": 60, "clear": true}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListExpiringEnvironments(t *testing.T) {
+	ttl := int64(600)
+	db, user, env := setupTTLTestEnvironment(t, &ttl)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	auth.SetCurrentUser(c, user)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/environments/expiring?withinMinutes=30", nil)
+
+	controller := &EnvironmentController{DB: db}
+	controller.ListExpiringEnvironments(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var dtos []EnvironmentTTLDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dtos))
+	require.Len(t, dtos, 1)
+	assert.Equal(t, env.ID, dtos[0].EnvironmentID)
+	require.NotNil(t, dtos[0].SecondsRemaining)
+	assert.Greater(t, *dtos[0].SecondsRemaining, int64(0))
+}
+
+func TestEnvironmentController_RegisterRoutes(t *testing.T) {
+	r := gin.New()
+	controller := &EnvironmentController{}
+	assert.NotPanics(t, func() { controller.RegisterRoutes(r.Group("/api/v1")) })
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultIdempotencyRetention is used when a controller has no retention configured.
+	DefaultIdempotencyRetention = 24 * time.Hour
+
+	// idempotencyClaimTimeout is how long a request may hold its key without finishing before
+	// a retry may take it over. Provisioning requests finish well within it unless the server
+	// that took them died.
+	idempotencyClaimTimeout = 15 * time.Minute
+
+	// idempotentReplayHeader marks responses replayed from a stored result.
+	idempotentReplayHeader = "Idempotent-Replayed"
+
+	// idempotencyKeepResponseKey is the gin context key set by keepIdempotentResponse.
+	idempotencyKeepResponseKey = "idempotency_keep_response"
+
+	// Idempotency scopes, one per provisioning endpoint
+	idempotencyScopeProject     = "provision_project"
+	idempotencyScopeEnvironment = "provision_environment"
+	idempotencyScopeServices    = "provision_services"
+)
+
+// recordingWriter tees the response body so it can be stored for replay.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// beginIdempotentRequest claims requestID for the handler's request. It returns ok=false when a
+// response has already been written: the stored result for a completed duplicate, 422 when the
+// requestID was used with a different request, or 409 while the original request is still
+// running, unless it has held the key past idempotencyClaimTimeout. Otherwise the caller must
+// defer finish, which stores a successful response for replay or releases the claim so a failed
+// request can be retried. Failures marked with keepIdempotentResponse are stored like successes.
+// Requests without a RequestID, or without a database, are not deduplicated.
+func beginIdempotentRequest(ctx *gin.Context, db *gorm.DB, retention time.Duration, userID, scope, requestID string, request interface{}) (finish func(), ok bool) {
+	noop := func() {}
+	if db == nil || requestID == "" {
+		return noop, true
+	}
+	if retention <= 0 {
+		retention = DefaultIdempotencyRetention
+	}
+	requestHash, err := idempotencyRequestHash(request)
+	if err != nil {
+		log.Error().Err(err).Str("request_id", requestID).Str("scope", scope).Msg("failed to hash request for idempotency")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check request idempotency"})
+		return noop, false
+	}
+
+	record, claimed, err := store.ClaimIdempotencyKey(db, userID, scope, requestID, requestHash, retention, idempotencyClaimTimeout)
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "a request with this requestId was just retried, try again"})
+		return noop, false
+	} else if err != nil {
+		log.Error().Err(err).Str("request_id", requestID).Str("scope", scope).Msg("failed to claim idempotency key")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check request idempotency"})
+		return noop, false
+	}
+
+	if !claimed {
+		// Records from before payloads were hashed have no hash to compare
+		if record.RequestHash != "" && record.RequestHash != requestHash {
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": "requestId was already used for a different request"})
+			return noop, false
+		}
+		if record.Status != store.IdempotencyStatusCompleted {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "a request with this requestId is already in progress"})
+			return noop, false
+		}
+		log.Info().Str("request_id", requestID).Str("scope", scope).Msg("replaying stored response for duplicate request")
+		ctx.Header(idempotentReplayHeader, "true")
+		ctx.Data(record.StatusCode, record.ContentType, record.ResponseBody)
+		return noop, false
+	}
+
+	rec := &recordingWriter{ResponseWriter: ctx.Writer}
+	ctx.Writer = rec
+	return func() {
+		code := rec.Status()
+		succeeded := code >= http.StatusOK && code < http.StatusMultipleChoices
+		if rec.Written() && (succeeded || ctx.GetBool(idempotencyKeepResponseKey)) {
+			err = store.CompleteIdempotencyKey(db, record.ID, code, rec.Header().Get("Content-Type"), rec.body.Bytes())
+		} else {
+			// Nothing was created, so let the client retry rather than replay an error
+			err = store.ReleaseIdempotencyKey(db, record.ID)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("request_id", requestID).Str("scope", scope).Msg("failed to finalize idempotency key")
+		}
+	}, true
+}
+
+// keepIdempotentResponse stores the error response being written for replay instead of releasing
+// the key. Handlers call it when the request failed after creating Railway resources, since a
+// retry would create them again.
+func keepIdempotentResponse(ctx *gin.Context) {
+	ctx.Set(idempotencyKeepResponseKey, true)
+}
+
+// idempotencyRequestHash returns a digest of the request payload.
+func idempotencyRequestHash(request interface{}) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
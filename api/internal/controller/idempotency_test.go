@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// newIdempotentRouter serves a handler that counts calls and responds with the given status.
+func newIdempotentRouter(db *gorm.DB, statuses ...int) (*gin.Engine, *int) {
+	calls := 0
+	var mu sync.Mutex
+	r := gin.New()
+	r.POST("/provision", func(ctx *gin.Context) {
+		finish, ok := beginIdempotentRequest(ctx, db, 0, "user-1", idempotencyScopeEnvironment, ctx.Query("requestId"), ctx.Query("name"))
+		if !ok {
+			return
+		}
+		defer finish()
+
+		mu.Lock()
+		code := statuses[calls%len(statuses)]
+		calls++
+		mu.Unlock()
+		if ctx.Query("keep") != "" {
+			keepIdempotentResponse(ctx)
+		}
+		ctx.JSON(code, gin.H{"call": calls})
+	})
+	return r, &calls
+}
+
+func doProvision(r *gin.Engine, requestID string) *httptest.ResponseRecorder {
+	return doProvisionQuery(r, "requestId="+requestID)
+}
+
+func doProvisionQuery(r *gin.Engine, query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/provision?"+query, nil))
+	return w
+}
+
+func TestBeginIdempotentRequest_ReplaysCompletedResponse(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	r, calls := newIdempotentRouter(db, http.StatusOK)
+
+	first := doProvision(r, "req-1")
+	second := doProvision(r, "req-1")
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.JSONEq(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayHeader))
+	assert.Contains(t, second.Header().Get("Content-Type"), "application/json")
+
+	// Requests without an ID are never deduplicated
+	doProvision(r, "")
+	doProvision(r, "")
+	assert.Equal(t, 3, *calls)
+}
+
+func TestBeginIdempotentRequest_FailureReleasesKey(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	r, calls := newIdempotentRouter(db, http.StatusBadGateway, http.StatusOK)
+
+	assert.Equal(t, http.StatusBadGateway, doProvision(r, "req-1").Code)
+	assert.Equal(t, http.StatusOK, doProvision(r, "req-1").Code)
+	assert.Equal(t, 2, *calls)
+}
+
+func TestBeginIdempotentRequest_RejectsInFlightDuplicate(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	_, claimed, err := store.ClaimIdempotencyKey(db, "user-1", idempotencyScopeEnvironment, "req-1", "", DefaultIdempotencyRetention, idempotencyClaimTimeout)
+	require.NoError(t, err)
+	require.True(t, claimed)
+	r, calls := newIdempotentRouter(db, http.StatusOK)
+
+	w := doProvision(r, "req-1")
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestBeginIdempotentRequest_ReplaysKeptFailure(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	r, calls := newIdempotentRouter(db, http.StatusBadGateway, http.StatusOK)
+
+	first := doProvisionQuery(r, "requestId=req-1&keep=1")
+	second := doProvisionQuery(r, "requestId=req-1&keep=1")
+
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusBadGateway, second.Code)
+	assert.JSONEq(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayHeader))
+}
+
+func TestBeginIdempotentRequest_RejectsReusedIDWithDifferentPayload(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	r, calls := newIdempotentRouter(db, http.StatusOK)
+
+	require.Equal(t, http.StatusOK, doProvisionQuery(r, "requestId=req-1&name=a").Code)
+	w := doProvisionQuery(r, "requestId=req-1&name=b")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, http.StatusOK, doProvisionQuery(r, "requestId=req-1&name=a").Code)
+	assert.Equal(t, 1, *calls)
+}
@@ -0,0 +1,386 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/reconcile"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+const (
+	// importProvisionSource is recorded in provision outputs and wizard inputs for imported environments.
+	importProvisionSource = "import"
+
+	// Import conflict reasons
+	importConflictTracked = "already_managed" // The Railway environment already has a Mirage record
+	importConflictName    = "name_in_use"     // Another Mirage environment in the project has the same name
+)
+
+// environmentImporter is the subset of the Railway client used to import environments.
+type environmentImporter interface {
+	GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error)
+	GetAllEnvironmentAndServiceVariables(ctx context.Context, in railway.GetAllEnvironmentAndServiceVariablesInput) (railway.GetAllEnvironmentAndServiceVariablesResult, error)
+}
+
+// ImportEnvironmentRequest is the body for importing a single Railway environment.
+type ImportEnvironmentRequest struct {
+	ProjectID string                 `json:"projectId" binding:"required"` // Railway project containing the environment
+	EnvType   *store.EnvironmentType `json:"envType,omitempty"`            // Defaults to dev
+}
+
+// ImportProjectRequest is the body for importing every unmanaged environment of a Railway project.
+type ImportProjectRequest struct {
+	EnvType *store.EnvironmentType `json:"envType,omitempty"` // Applied to every imported environment, defaults to dev
+}
+
+// ImportedEnvironment describes an environment created by an import.
+type ImportedEnvironment struct {
+	EnvironmentID        string `json:"environmentId"`        // Mirage internal environment ID
+	RailwayEnvironmentID string `json:"railwayEnvironmentId"` // Railway's environment ID
+	Name                 string `json:"name"`
+	ServicesImported     int    `json:"servicesImported"`
+	VariablesCaptured    int    `json:"variablesCaptured"` // Environment plus service variables stored in metadata
+}
+
+// ImportConflict explains why a Railway environment was not imported.
+type ImportConflict struct {
+	RailwayEnvironmentID string `json:"railwayEnvironmentId"`
+	Name                 string `json:"name"`
+	Reason               string `json:"reason"`
+	EnvironmentID        string `json:"environmentId,omitempty"` // Conflicting Mirage environment, when it belongs to the caller
+}
+
+// ImportFailure is a Railway environment whose import failed for reasons other than a conflict.
+type ImportFailure struct {
+	RailwayEnvironmentID string `json:"railwayEnvironmentId"`
+	Name                 string `json:"name"`
+	Error                string `json:"error"`
+}
+
+// ImportProjectResponse summarizes a bulk project import.
+type ImportProjectResponse struct {
+	ProjectID string                `json:"projectId"`
+	Imported  []ImportedEnvironment `json:"imported"`
+	Conflicts []ImportConflict      `json:"conflicts"`
+	Failed    []ImportFailure       `json:"failed"`
+}
+
+// importedVariables is the variable snapshot captured into an imported environment's wizard inputs.
+type importedVariables struct {
+	SourceType           string                       `json:"sourceType"`
+	EnvironmentVariables map[string]string            `json:"environmentVariables,omitempty"`
+	ServiceVariables     map[string]map[string]string `json:"serviceVariables,omitempty"` // Keyed by service name
+}
+
+// errImportConflict is returned by importEnvironment when the environment conflicts with existing records.
+var errImportConflict = errors.New("import conflicts with existing environment")
+
+// ImportRailwayEnvironment brings a Railway environment that Mirage did not create under
+// management: the environment and its services are recorded and its variables captured
+// into the environment metadata. The :id parameter is the Railway environment ID.
+//
+// POST /api/v1/railway/environment/:id/import
+// Request body: {"projectId": "...", "envType": "dev"}
+// Response: ImportedEnvironment, or 409 with an ImportConflict
+func (c *EnvironmentController) ImportRailwayEnvironment(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "environment id required"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req ImportEnvironmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rwClient, ok := c.importRailwayClient(ctx, user.ID)
+	if !ok {
+		return
+	}
+
+	// The user's own token scopes what they can see, so a project they can fetch is one they may import
+	details, err := rwClient.GetProjectWithDetailsByID(ctx, req.ProjectID)
+	if err != nil {
+		log.Error().Err(err).Str("project_id", req.ProjectID).Msg("failed to fetch railway project for import")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch project from railway", "message": err.Error()})
+		return
+	}
+	var remote *railway.ProjectEnvironment
+	for i := range details.Environments {
+		if details.Environments[i].ID == railwayEnvID {
+			remote = &details.Environments[i]
+			break
+		}
+	}
+	if remote == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found in railway project"})
+		return
+	}
+
+	imported, conflict, err := c.importEnvironment(ctx, rwClient, user.ID, details, *remote, importEnvType(req.EnvType))
+	switch {
+	case errors.Is(err, errImportConflict):
+		ctx.JSON(http.StatusConflict, conflict)
+	case err != nil:
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to import railway environment")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to import environment", "message": err.Error()})
+	default:
+		ctx.JSON(http.StatusCreated, imported)
+	}
+}
+
+// ImportRailwayProject imports every environment of a Railway project that Mirage does not
+// manage yet. Environments that conflict with existing records are reported and skipped.
+//
+// POST /api/v1/railway/project/:id/import
+// Request body (optional): {"envType": "dev"}
+// Response: ImportProjectResponse
+func (c *EnvironmentController) ImportRailwayProject(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	projectID := ctx.Param("id")
+	if projectID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "project id required"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	// The body is optional
+	var req ImportProjectRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rwClient, ok := c.importRailwayClient(ctx, user.ID)
+	if !ok {
+		return
+	}
+
+	details, err := rwClient.GetProjectWithDetailsByID(ctx, projectID)
+	if err != nil {
+		log.Error().Err(err).Str("project_id", projectID).Msg("failed to fetch railway project for import")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to fetch project from railway", "message": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.importProject(ctx, rwClient, user.ID, details, importEnvType(req.EnvType)))
+}
+
+// importRailwayClient returns the user's Railway client, writing the error response on failure.
+func (c *EnvironmentController) importRailwayClient(ctx *gin.Context, userID string) (*railway.Client, bool) {
+	rwClient, err := railway.GetRailwayClientForUser(ctx, userID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before importing environments",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return nil, false
+	}
+	return rwClient, true
+}
+
+// importEnvType returns the requested environment type, defaulting to dev.
+func importEnvType(t *store.EnvironmentType) store.EnvironmentType {
+	if t != nil && *t != "" {
+		return *t
+	}
+	return store.EnvironmentTypeDev
+}
+
+// importProject imports each environment of details in turn, sorting the outcomes into
+// imported, conflicting and failed environments.
+func (c *EnvironmentController) importProject(ctx context.Context, rw environmentImporter, userID string, details railway.ProjectDetails, envType store.EnvironmentType) ImportProjectResponse {
+	resp := ImportProjectResponse{
+		ProjectID: details.ID,
+		Imported:  []ImportedEnvironment{},
+		Conflicts: []ImportConflict{},
+		Failed:    []ImportFailure{},
+	}
+	for _, remote := range details.Environments {
+		imported, conflict, err := c.importEnvironment(ctx, rw, userID, details, remote, envType)
+		switch {
+		case errors.Is(err, errImportConflict):
+			resp.Conflicts = append(resp.Conflicts, *conflict)
+		case err != nil:
+			log.Warn().Err(err).Str("railway_env_id", remote.ID).Msg("failed to import railway environment")
+			resp.Failed = append(resp.Failed, ImportFailure{RailwayEnvironmentID: remote.ID, Name: remote.Name, Error: err.Error()})
+		default:
+			resp.Imported = append(resp.Imported, imported)
+		}
+	}
+	log.Info().
+		Str("project_id", details.ID).
+		Str("user_id", userID).
+		Int("imported", len(resp.Imported)).
+		Int("conflicts", len(resp.Conflicts)).
+		Int("failed", len(resp.Failed)).
+		Msg("imported railway project")
+	return resp
+}
+
+// importEnvironment records remote and its services for userID and captures its variables into
+// the environment metadata, all in one transaction. It returns errImportConflict with the
+// conflict when the environment is already managed or its name is taken in the project.
+func (c *EnvironmentController) importEnvironment(ctx context.Context, rw environmentImporter, userID string, details railway.ProjectDetails, remote railway.ProjectEnvironment, envType store.EnvironmentType) (ImportedEnvironment, *ImportConflict, error) {
+	conflict, err := c.findImportConflict(ctx, userID, details.ID, remote)
+	if err != nil {
+		return ImportedEnvironment{}, nil, err
+	}
+	if conflict != nil {
+		return ImportedEnvironment{}, conflict, errImportConflict
+	}
+
+	vars, err := rw.GetAllEnvironmentAndServiceVariables(ctx, railway.GetAllEnvironmentAndServiceVariablesInput{
+		ProjectID:     details.ID,
+		EnvironmentID: remote.ID,
+	})
+	if err != nil {
+		return ImportedEnvironment{}, nil, fmt.Errorf("fetch variables: %w", err)
+	}
+	captured := importedVariables{
+		SourceType:           importProvisionSource,
+		EnvironmentVariables: vars.EnvironmentVariables,
+		ServiceVariables:     make(map[string]map[string]string, len(vars.ServiceVariables)),
+	}
+	variableCount := len(vars.EnvironmentVariables)
+	for _, sv := range vars.ServiceVariables {
+		captured.ServiceVariables[sv.ServiceName] = sv.Variables
+		variableCount += len(sv.Variables)
+	}
+	wizardInputsJSON, err := json.Marshal(captured)
+	if err != nil {
+		return ImportedEnvironment{}, nil, err
+	}
+	provisionOutputsJSON, _ := json.Marshal(map[string]interface{}{
+		"projectId":     details.ID,
+		"environmentId": remote.ID,
+		"source":        importProvisionSource,
+	})
+
+	now := time.Now()
+	env := store.Environment{
+		ID:                   uuid.New().String(),
+		UserID:               userID,
+		Name:                 remote.Name,
+		Type:                 envType,
+		Status:               status.StatusUnknown, // The status poller fills this in from Railway
+		RailwayProjectID:     details.ID,
+		RailwayEnvironmentID: remote.ID,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+
+	// With no local services every Railway service is reported as extra, so applying the
+	// drift report adopts them exactly as the drift reconciler would
+	report := reconcile.Detect(env, nil, details, now)
+	var applied reconcile.ApplyResult
+	err = c.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&env).Error; err != nil {
+			return err
+		}
+		metadata := store.EnvironmentMetadata{
+			ID:                   uuid.New().String(),
+			UserID:               userID,
+			EnvironmentID:        env.ID,
+			WizardInputsJSON:     wizardInputsJSON,
+			ProvisionOutputsJSON: provisionOutputsJSON,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		}
+		if err := tx.Create(&metadata).Error; err != nil {
+			return err
+		}
+		var err error
+		applied, err = reconcile.Apply(ctx, tx, &env, report, now)
+		return err
+	})
+	if err != nil {
+		return ImportedEnvironment{}, nil, err
+	}
+
+	log.Info().
+		Str("env_id", env.ID).
+		Str("railway_env_id", remote.ID).
+		Str("user_id", userID).
+		Int("services_imported", applied.ServicesCreated).
+		Int("variables_captured", variableCount).
+		Msg("imported railway environment")
+
+	return ImportedEnvironment{
+		EnvironmentID:        env.ID,
+		RailwayEnvironmentID: remote.ID,
+		Name:                 env.Name,
+		ServicesImported:     applied.ServicesCreated,
+		VariablesCaptured:    variableCount,
+	}, nil, nil
+}
+
+// findImportConflict reports why remote cannot be imported, or nil if it can. A Railway
+// environment has at most one Mirage record regardless of owner; only the caller's own
+// conflicting environment IDs are disclosed.
+func (c *EnvironmentController) findImportConflict(ctx context.Context, userID, projectID string, remote railway.ProjectEnvironment) (*ImportConflict, error) {
+	var existing store.Environment
+	err := c.DB.WithContext(ctx).Where("railway_environment_id = ?", remote.ID).First(&existing).Error
+	if err == nil {
+		conflict := &ImportConflict{RailwayEnvironmentID: remote.ID, Name: remote.Name, Reason: importConflictTracked}
+		if existing.UserID == userID {
+			conflict.EnvironmentID = existing.ID
+		}
+		return conflict, nil
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	// Railway names are unique per project, so a same-named record points at a stale environment
+	err = c.DB.WithContext(ctx).
+		Where("railway_project_id = ? AND name = ? AND user_id = ?", projectID, remote.Name, userID).
+		First(&existing).Error
+	if err == nil {
+		return &ImportConflict{RailwayEnvironmentID: remote.ID, Name: remote.Name, Reason: importConflictName, EnvironmentID: existing.ID}, nil
+	} else if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	return nil, nil
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeEnvironmentImporter struct {
+	details railway.ProjectDetails
+	vars    map[string]railway.GetAllEnvironmentAndServiceVariablesResult // Keyed by environment ID
+}
+
+func (f *fakeEnvironmentImporter) GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error) {
+	return f.details, nil
+}
+
+func (f *fakeEnvironmentImporter) GetAllEnvironmentAndServiceVariables(ctx context.Context, in railway.GetAllEnvironmentAndServiceVariablesInput) (railway.GetAllEnvironmentAndServiceVariablesResult, error) {
+	return f.vars[in.EnvironmentID], nil
+}
+
+func TestImportProject_ImportsUnmanagedAndReportsConflicts(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	// rw-tracked is already managed by another user; user-1 has a stale record named "staging"
+	require.NoError(t, db.Create(&store.Environment{ID: "env-other", UserID: "user-2", Name: "prod", Type: store.EnvironmentTypeProd, RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-tracked"}).Error)
+	require.NoError(t, db.Create(&store.Environment{ID: "env-stale", UserID: "user-1", Name: "staging", Type: store.EnvironmentTypeStaging, RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-gone"}).Error)
+
+	rw := &fakeEnvironmentImporter{
+		details: railway.ProjectDetails{ID: "rw-proj", Environments: []railway.ProjectEnvironment{
+			{ID: "rw-dev", Name: "dev", Services: []railway.ServiceInstance{
+				{ServiceID: "rw-api", ServiceName: "api", Source: &railway.ServiceSource{Repo: ptrString("org/api")}},
+				{ServiceID: "rw-redis", ServiceName: "redis", Source: &railway.ServiceSource{Image: ptrString("redis:7")}},
+			}},
+			{ID: "rw-tracked", Name: "prod"},
+			{ID: "rw-staging", Name: "staging"},
+		}},
+		vars: map[string]railway.GetAllEnvironmentAndServiceVariablesResult{
+			"rw-dev": {
+				EnvironmentVariables: map[string]string{"LOG_LEVEL": "debug"},
+				ServiceVariables:     []railway.ServiceVariables{{ServiceID: "rw-api", ServiceName: "api", Variables: map[string]string{"PORT": "8080"}}},
+			},
+		},
+	}
+	c := &EnvironmentController{DB: db}
+
+	resp := c.importProject(context.Background(), rw, "user-1", rw.details, store.EnvironmentTypeDev)
+
+	require.Len(t, resp.Imported, 1)
+	assert.Equal(t, "dev", resp.Imported[0].Name)
+	assert.Equal(t, 2, resp.Imported[0].ServicesImported)
+	assert.Equal(t, 2, resp.Imported[0].VariablesCaptured)
+	assert.Equal(t, []ImportConflict{
+		{RailwayEnvironmentID: "rw-tracked", Name: "prod", Reason: importConflictTracked},
+		{RailwayEnvironmentID: "rw-staging", Name: "staging", Reason: importConflictName, EnvironmentID: "env-stale"},
+	}, resp.Conflicts)
+	assert.Empty(t, resp.Failed)
+
+	var env store.Environment
+	require.NoError(t, db.Preload("Services").Where("railway_environment_id = ?", "rw-dev").First(&env).Error)
+	assert.Equal(t, "user-1", env.UserID)
+	assert.Equal(t, "rw-proj", env.RailwayProjectID)
+	require.Len(t, env.Services, 2)
+	for _, svc := range env.Services {
+		assert.Equal(t, "user-1", svc.UserID)
+		if svc.Name == "redis" {
+			assert.Equal(t, store.DeploymentTypeDockerImage, svc.DeploymentType)
+			assert.Equal(t, "redis:7", svc.DockerImage)
+		} else {
+			assert.Equal(t, "org/api", svc.SourceRepo)
+		}
+	}
+
+	var metadata store.EnvironmentMetadata
+	require.NoError(t, db.Where("environment_id = ?", env.ID).First(&metadata).Error)
+	var captured importedVariables
+	require.NoError(t, json.Unmarshal(metadata.WizardInputsJSON, &captured))
+	assert.Equal(t, importProvisionSource, captured.SourceType)
+	assert.Equal(t, "debug", captured.EnvironmentVariables["LOG_LEVEL"])
+	assert.Equal(t, "8080", captured.ServiceVariables["api"]["PORT"])
+}
+
+func TestImportEnvironment_ReimportIsConflict(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	remote := railway.ProjectEnvironment{ID: "rw-dev", Name: "dev"}
+	rw := &fakeEnvironmentImporter{details: railway.ProjectDetails{ID: "rw-proj", Environments: []railway.ProjectEnvironment{remote}}}
+	c := &EnvironmentController{DB: db}
+
+	first, _, err := c.importEnvironment(context.Background(), rw, "user-1", rw.details, remote, store.EnvironmentTypeDev)
+	require.NoError(t, err)
+
+	_, conflict, err := c.importEnvironment(context.Background(), rw, "user-1", rw.details, remote, store.EnvironmentTypeDev)
+	require.ErrorIs(t, err, errImportConflict)
+	assert.Equal(t, importConflictTracked, conflict.Reason)
+	assert.Equal(t, first.EnvironmentID, conflict.EnvironmentID)
+}
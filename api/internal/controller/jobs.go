@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/events"
+	"github.com/stwalsh4118/mirageapi/internal/jobs"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+	"gorm.io/gorm"
+)
+
+const (
+	// messageTypeJob carries a ProvisioningJobDTO snapshot over WebSocket
+	messageTypeJob = "job"
+
+	// Step names of a provisioning job, in execution order
+	jobStepProject     = "project"
+	jobStepEnvironment = "environment"
+	jobStepServices    = "services"
+
+	idempotencyScopeJob = "provision_job"
+)
+
+// JobsController runs full-stack provisioning (project → environment → services) as background
+// jobs so long requests don't hit proxy timeouts, and exposes their progress.
+type JobsController struct {
+	DB             *gorm.DB
+	Railway        RailwayEnvironmentClient
+	Vault          *vault.Client
+	Hub            *events.Hub
+	Runner         *jobs.ProvisioningRunner
+	AllowedOrigins []string
+	// Concurrency caps parallel Railway service creations in the services step (default 4)
+	Concurrency int
+	// IdempotencyRetention is how long job creation responses are kept for replay (default 24h)
+	IdempotencyRetention time.Duration
+}
+
+func (c *JobsController) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/jobs/provision", c.CreateProvisioningJob)
+	r.GET("/jobs/:id", c.GetJob)
+}
+
+// CreateProvisioningJobRequest describes a full-stack provisioning run. Either ProjectID
+// (use an existing project) or Project (create one) must be set.
+type CreateProvisioningJobRequest struct {
+	ProjectID   string                      `json:"projectId,omitempty"`
+	Project     *ProvisionProjectRequest    `json:"project,omitempty"`
+	Environment ProvisionEnvironmentRequest `json:"environment"` // projectId is filled in by the job
+	Services    []ServiceSpec               `json:"services,omitempty"`
+	Atomic      bool                        `json:"atomic,omitempty"` // Optional: destroy already-created services when one fails
+	RequestID   string                      `json:"requestId"`
+}
+
+type CreateProvisioningJobResponse struct {
+	JobID  string          `json:"jobId"`
+	Status store.JobStatus `json:"status"`
+}
+
+// ProvisioningJobDTO is the API view of a provisioning job
+type ProvisioningJobDTO struct {
+	ID          string          `json:"id"`
+	Status      store.JobStatus `json:"status"`
+	CurrentStep string          `json:"currentStep,omitempty"`
+	Error       *string         `json:"error,omitempty"`
+	Steps       []store.JobStep `json:"steps"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+	StartedAt   *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time      `json:"finishedAt,omitempty"`
+}
+
+func toProvisioningJobDTO(job store.ProvisioningJob) ProvisioningJobDTO {
+	steps, err := job.Steps()
+	if err != nil {
+		log.Error().Err(err).Str("job_id", job.ID).Msg("failed to decode job steps")
+	}
+	if steps == nil {
+		steps = []store.JobStep{}
+	}
+	return ProvisioningJobDTO{
+		ID:          job.ID,
+		Status:      job.Status,
+		CurrentStep: job.CurrentStep,
+		Error:       job.Error,
+		Steps:       steps,
+		CreatedAt:   job.CreatedAt,
+		UpdatedAt:   job.UpdatedAt,
+		StartedAt:   job.StartedAt,
+		FinishedAt:  job.FinishedAt,
+	}
+}
+
+// jobProvisioner is the subset of the Railway client used by provisioning job steps.
+type jobProvisioner interface {
+	projectProvisioner
+	environmentCreator
+	RailwayServiceClient
+}
+
+// CreateProvisioningJob validates the request, queues a provisioning job and returns its ID.
+// POST /api/v1/jobs/provision
+func (c *JobsController) CreateProvisioningJob(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	if c.Runner == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "job runner not configured"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req CreateProvisioningJobRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateProvisioningJobRequest(req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before provisioning",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	finish, ok := beginIdempotentRequest(ctx, c.DB, c.IdempotencyRetention, user.ID, idempotencyScopeJob, req.RequestID, req)
+	if !ok {
+		return
+	}
+	defer finish()
+
+	job, err := c.startProvisioningJob(rwClient, user.ID, req)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Msg("failed to create provisioning job")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create provisioning job"})
+		return
+	}
+
+	ctx.Header("Location", "/api/v1/jobs/"+job.ID)
+	ctx.JSON(http.StatusAccepted, CreateProvisioningJobResponse{JobID: job.ID, Status: job.Status})
+}
+
+// validateProvisioningJobRequest rejects requests that would fail partway through the job.
+func validateProvisioningJobRequest(req CreateProvisioningJobRequest) error {
+	if (req.ProjectID == "") == (req.Project == nil) {
+		return errors.New("exactly one of projectId or project is required")
+	}
+	if req.Environment.Name == "" {
+		return errors.New("environment name is required")
+	}
+	if req.Environment.TTLSeconds != nil && *req.Environment.TTLSeconds <= 0 {
+		return errors.New("ttlSeconds must be positive")
+	}
+	for _, s := range req.Services {
+		if err := validateServiceSpec(s); err != nil {
+			return err
+		}
+	}
+	// The environment is new, so services can only reference each other
+	if _, err := planProvisioning(req.Services, nil); err != nil {
+		return err
+	}
+	return nil
+}
+
+// startProvisioningJob persists a pending job for req and hands it to the runner.
+func (c *JobsController) startProvisioningJob(rw jobProvisioner, userID string, req CreateProvisioningJobRequest) (store.ProvisioningJob, error) {
+	steps := c.provisioningSteps(rw, userID, req)
+	names := make([]string, 0, len(steps))
+	for _, s := range steps {
+		names = append(names, s.Name)
+	}
+
+	job, err := jobs.NewProvisioningJob(userID, req, names)
+	if err != nil {
+		return store.ProvisioningJob{}, err
+	}
+	job.Holder = c.Runner.Holder()
+	if err := c.DB.Create(&job).Error; err != nil {
+		return store.ProvisioningJob{}, err
+	}
+
+	log.Info().Str("job_id", job.ID).Str("user_id", userID).Strs("steps", names).Msg("queued provisioning job")
+	c.Runner.Start(job, steps)
+	return job, nil
+}
+
+// provisioningSteps builds the job's steps. Each step reuses the synchronous provisioning
+// logic and passes the IDs it created to the next through the closure state.
+func (c *JobsController) provisioningSteps(rw jobProvisioner, userID string, req CreateProvisioningJobRequest) []jobs.ProvisioningStep {
+	ec := &EnvironmentController{DB: c.DB, Vault: c.Vault}
+	sc := &ServicesController{DB: c.DB, Vault: c.Vault, Concurrency: c.Concurrency}
+
+	projectID := req.ProjectID
+	var env ProvisionEnvironmentResponse
+	var steps []jobs.ProvisioningStep
+
+	if req.Project != nil {
+		steps = append(steps, jobs.ProvisioningStep{Name: jobStepProject, Run: func(ctx context.Context) (interface{}, error) {
+			res, err := ec.provisionProject(ctx, rw, userID, *req.Project)
+			if err != nil {
+				return nil, err
+			}
+			projectID = res.ProjectID
+			return res, nil
+		}})
+	}
+
+	steps = append(steps, jobs.ProvisioningStep{Name: jobStepEnvironment, Run: func(ctx context.Context) (interface{}, error) {
+		envReq := req.Environment
+		envReq.ProjectID = projectID
+		res, err := ec.provisionEnvironment(ctx, rw, userID, envReq)
+		if err != nil {
+			return nil, err
+		}
+		env = res
+		return res, nil
+	}})
+
+	if len(req.Services) > 0 {
+		steps = append(steps, jobs.ProvisioningStep{Name: jobStepServices, Run: func(ctx context.Context) (interface{}, error) {
+			// The environment was just created, so it has no Vault secrets to inject yet
+			results, err := sc.provisionServices(ctx, rw, userID, ProvisionServicesRequest{
+				ProjectID:            projectID,
+				EnvironmentID:        env.EnvironmentID,
+				RailwayEnvironmentID: env.RailwayEnvironmentID,
+				Services:             req.Services,
+				Atomic:               req.Atomic,
+			}, env.RailwayEnvironmentID, nil)
+			return ProvisionServicesResponse{ServiceIDs: createdServiceIDs(results), Services: results}, err
+		}})
+	}
+
+	return steps
+}
+
+// GetJob returns the current state of a provisioning job owned by the user.
+// GET /api/v1/jobs/:id
+func (c *JobsController) GetJob(ctx *gin.Context) {
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var job store.ProvisioningJob
+	err = c.DB.Where("id = ? AND user_id = ?", ctx.Param("id"), user.ID).First(&job).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("job_id", ctx.Param("id")).Msg("failed to query provisioning job")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve job"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, toProvisioningJobDTO(job))
+}
+
+// StreamJob streams progress updates for a provisioning job via WebSocket until it finishes.
+// GET /api/v1/jobs/:id/stream
+// Auth is handled via first message after connection, as for log streams
+func (c *JobsController) StreamJob(ginCtx *gin.Context) {
+	if c.Hub == nil {
+		ginCtx.JSON(http.StatusServiceUnavailable, gin.H{"error": "job events not configured"})
+		return
+	}
+
+	jobID := ginCtx.Param("id")
+	conn, user, ok := acceptAuthenticatedWebSocket(ginCtx, c.DB, c.AllowedOrigins)
+	if !ok {
+		return
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "connection closed")
+
+	// Subscribe before reading the snapshot so no update between the two is lost
+	sub := c.Hub.Subscribe(events.JobTopic(jobID))
+	defer sub.Close()
+
+	var job store.ProvisioningJob
+	if err := c.DB.Where("id = ? AND user_id = ?", jobID, user.ID).First(&job).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			writeWebSocketMessage(ginCtx.Request.Context(), conn, messageTypeError, "job not found")
+			conn.Close(websocket.StatusPolicyViolation, "job not found")
+			return
+		}
+		log.Error().Err(err).Str("job_id", jobID).Msg("failed to query provisioning job")
+		writeWebSocketMessage(ginCtx.Request.Context(), conn, messageTypeError, "failed to retrieve job")
+		conn.Close(websocket.StatusInternalError, "database error")
+		return
+	}
+
+	log.Info().Str("job_id", jobID).Str("user_id", user.ID).Msg("client connected to job stream")
+
+	// CloseRead discards client messages and cancels ctx when the client disconnects
+	ctx := conn.CloseRead(ginCtx.Request.Context())
+
+	if err := writeWebSocketMessage(ctx, conn, messageTypeStatus, "connected"); err != nil {
+		log.Error().Err(err).Msg("failed to send status message")
+		return
+	}
+	if err := writeWebSocketMessage(ctx, conn, messageTypeJob, toProvisioningJobDTO(job)); err != nil {
+		return
+	}
+	if job.Status.IsTerminal() {
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Str("job_id", jobID).Msg("job stream cancelled")
+			return
+		case e, open := <-sub.C:
+			if !open {
+				return
+			}
+			update, ok := e.Data.(store.ProvisioningJob)
+			if !ok {
+				continue
+			}
+			if err := writeWebSocketMessage(ctx, conn, messageTypeJob, toProvisioningJobDTO(update)); err != nil {
+				log.Info().Err(err).Str("job_id", jobID).Msg("job stream ended")
+				return
+			}
+			if update.Status.IsTerminal() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/jobs"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+// fakeJobProvisioner records provisioning calls and hands out fixed Railway IDs.
+type fakeJobProvisioner struct {
+	createServiceErr error
+	envInputs        []railway.CreateEnvironmentInput
+	serviceInputs    []railway.CreateServiceInput
+}
+
+func (f *fakeJobProvisioner) CreateProject(ctx context.Context, in railway.CreateProjectInput) (railway.CreateProjectResult, error) {
+	return railway.CreateProjectResult{ProjectID: "rw-proj-new", BaseEnvironmentID: "rw-env-base", Name: *in.Name}, nil
+}
+
+func (f *fakeJobProvisioner) GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error) {
+	return railway.ProjectDetails{}, errors.New("unexpected project lookup")
+}
+
+func (f *fakeJobProvisioner) CreateEnvironment(ctx context.Context, in railway.CreateEnvironmentInput) (railway.CreateEnvironmentResult, error) {
+	f.envInputs = append(f.envInputs, in)
+	return railway.CreateEnvironmentResult{EnvironmentID: "rw-env-new"}, nil
+}
+
+func (f *fakeJobProvisioner) CreateService(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+	if f.createServiceErr != nil {
+		return railway.CreateServiceResult{}, f.createServiceErr
+	}
+	f.serviceInputs = append(f.serviceInputs, in)
+	return railway.CreateServiceResult{ServiceID: "rw-svc-" + in.Name}, nil
+}
+
+func (f *fakeJobProvisioner) DestroyService(ctx context.Context, in railway.DestroyServiceInput) error {
+	return nil
+}
+
+func (f *fakeJobProvisioner) CreateServiceDomain(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error) {
+	return railway.Domain{}, errors.New("unexpected domain creation")
+}
+
+func (f *fakeJobProvisioner) CreateVolume(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error) {
+	return railway.Volume{}, errors.New("unexpected volume creation")
+}
+
+func (f *fakeJobProvisioner) DeleteVolume(ctx context.Context, in railway.DeleteVolumeInput) error {
+	return nil
+}
+
+func newJobsController(t *testing.T) *JobsController {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&store.User{ID: "user-1", ClerkUserID: "clerk_1", Email: "u1@example.com", IsActive: true}).Error)
+	return &JobsController{DB: db, Runner: jobs.NewProvisioningRunner(context.Background(), db, nil, "replica-a"), Concurrency: 1}
+}
+
+func loadJobSteps(t *testing.T, c *JobsController, id string) (store.ProvisioningJob, []store.JobStep) {
+	t.Helper()
+	var job store.ProvisioningJob
+	require.NoError(t, c.DB.First(&job, "id = ?", id).Error)
+	steps, err := job.Steps()
+	require.NoError(t, err)
+	return job, steps
+}
+
+func TestProvisioningJob_RunsProjectEnvironmentAndServices(t *testing.T) {
+	c := newJobsController(t)
+	rw := &fakeJobProvisioner{}
+	projectName := "shop"
+
+	queued, err := c.startProvisioningJob(rw, "user-1", CreateProvisioningJobRequest{
+		Project:     &ProvisionProjectRequest{Name: &projectName},
+		Environment: ProvisionEnvironmentRequest{Name: "dev"},
+		Services:    threeImageServices(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, store.JobStatusPending, queued.Status)
+	c.Runner.Wait()
+
+	job, steps := loadJobSteps(t, c, queued.ID)
+	assert.Equal(t, store.JobStatusSucceeded, job.Status)
+	require.Len(t, steps, 3)
+	for i, name := range []string{jobStepProject, jobStepEnvironment, jobStepServices} {
+		assert.Equal(t, name, steps[i].Name)
+		assert.Equal(t, store.JobStatusSucceeded, steps[i].Status)
+	}
+
+	// Each step consumes the IDs created by the previous one
+	require.Len(t, rw.envInputs, 1)
+	assert.Equal(t, "rw-proj-new", rw.envInputs[0].ProjectID)
+	require.Len(t, rw.serviceInputs, 3)
+	assert.Equal(t, "rw-env-new", rw.serviceInputs[0].EnvironmentID)
+
+	var services ProvisionServicesResponse
+	require.NoError(t, json.Unmarshal(steps[2].Output, &services))
+	assert.Len(t, services.ServiceIDs, 3)
+
+	var env store.Environment
+	require.NoError(t, c.DB.Where("railway_environment_id = ? AND user_id = ?", "rw-env-new", "user-1").First(&env).Error)
+}
+
+func TestProvisioningJob_ServiceFailureFailsJob(t *testing.T) {
+	c := newJobsController(t)
+	rw := &fakeJobProvisioner{createServiceErr: errors.New("quota exceeded")}
+
+	queued, err := c.startProvisioningJob(rw, "user-1", CreateProvisioningJobRequest{
+		ProjectID:   "rw-proj",
+		Environment: ProvisionEnvironmentRequest{Name: "dev"},
+		Services:    threeImageServices(),
+	})
+	require.NoError(t, err)
+	c.Runner.Wait()
+
+	job, steps := loadJobSteps(t, c, queued.ID)
+	assert.Equal(t, store.JobStatusFailed, job.Status)
+	require.NotNil(t, job.Error)
+	assert.Contains(t, *job.Error, "quota exceeded")
+
+	require.Len(t, steps, 2, "no project step when projectId is given")
+	assert.Equal(t, store.JobStatusSucceeded, steps[0].Status)
+	assert.Equal(t, store.JobStatusFailed, steps[1].Status)
+
+	// Per-service outcomes are kept on the failed step
+	var services ProvisionServicesResponse
+	require.NoError(t, json.Unmarshal(steps[1].Output, &services))
+	assert.Equal(t, []string{serviceProvisionFailed, serviceProvisionFailed, serviceProvisionFailed}, resultStatuses(services.Services))
+}
+
+func TestValidateProvisioningJobRequest(t *testing.T) {
+	name := "shop"
+	image := "redis:7"
+	badTTL := int64(0)
+	cases := map[string]CreateProvisioningJobRequest{
+		"missing project":  {Environment: ProvisionEnvironmentRequest{Name: "dev"}},
+		"both projects":    {ProjectID: "p", Project: &ProvisionProjectRequest{Name: &name}, Environment: ProvisionEnvironmentRequest{Name: "dev"}},
+		"missing env name": {ProjectID: "p"},
+		"bad ttl":          {ProjectID: "p", Environment: ProvisionEnvironmentRequest{Name: "dev", TTLSeconds: &badTTL}},
+		"bad service":      {ProjectID: "p", Environment: ProvisionEnvironmentRequest{Name: "dev"}, Services: []ServiceSpec{{Name: "x", Repo: ptrString("a/b"), ImageName: &image}}},
+	}
+	for name, req := range cases {
+		assert.Error(t, validateProvisioningJobRequest(req), name)
+	}
+
+	assert.NoError(t, validateProvisioningJobRequest(CreateProvisioningJobRequest{
+		ProjectID:   "p",
+		Environment: ProvisionEnvironmentRequest{Name: "dev"},
+		Services:    []ServiceSpec{{Name: "cache", ImageName: &image}},
+	}))
+}
+
+func TestGetJob_ScopedToOwner(t *testing.T) {
+	c := newJobsController(t)
+	job, err := jobs.NewProvisioningJob("user-1", map[string]string{}, []string{jobStepEnvironment})
+	require.NoError(t, err)
+	require.NoError(t, c.DB.Create(&job).Error)
+
+	ctx, w := newTemplateRequest(t, "user-1", job.ID, "/api/v1/jobs/"+job.ID, "")
+	c.GetJob(ctx)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var dto ProvisioningJobDTO
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dto))
+	assert.Equal(t, job.ID, dto.ID)
+	assert.Equal(t, store.JobStatusPending, dto.Status)
+	require.Len(t, dto.Steps, 1)
+	assert.Equal(t, jobStepEnvironment, dto.Steps[0].Name)
+
+	ctx, w = newTemplateRequest(t, "user-2", job.ID, "/api/v1/jobs/"+job.ID, "")
+	c.GetJob(ctx)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -824,19 +824,5 @@ func (c *LogsController) StreamServiceLogs(ginCtx *gin.Context) {
 
 // sendWebSocketMessage sends a typed message to the WebSocket client
 func (c *LogsController) sendWebSocketMessage(ctx context.Context, conn *websocket.Conn, msgType string, data interface{}) error {
-	msg := WebSocketMessage{
-		Type: msgType,
-		Data: data,
-	}
-
-	msgBytes, err := json.Marshal(msg)
-	if err != nil {
-		return fmt.Errorf("marshal message: %w", err)
-	}
-
-	if err := conn.Write(ctx, websocket.MessageText, msgBytes); err != nil {
-		return fmt.Errorf("write message: %w", err)
-	}
-
-	return nil
+	return writeWebSocketMessage(ctx, conn, msgType, data)
 }
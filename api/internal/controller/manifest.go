@@ -0,0 +1,541 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/manifest"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+	"gorm.io/gorm"
+)
+
+const (
+	contentTypeYAML = "application/x-yaml"
+	contentTypeJSON = "application/json"
+
+	// manifestProvisionSource is recorded in provision outputs for environments created by apply.
+	manifestProvisionSource = "manifest"
+)
+
+// manifestApplier is the subset of the Railway client used to apply a manifest.
+type manifestApplier interface {
+	CreateEnvironment(ctx context.Context, in railway.CreateEnvironmentInput) (railway.CreateEnvironmentResult, error)
+	RailwayServiceClient
+}
+
+// manifestSecretReader is the subset of the Vault client used to resolve a manifest's secret references.
+type manifestSecretReader interface {
+	GetSecretValue(ctx context.Context, userID, key string) (string, error)
+	GetAllEnvironmentSecrets(ctx context.Context, userID, envID string) (map[string]string, error)
+}
+
+// manifestSecretError reports a secret reference the manifest gets wrong, such as a missing key,
+// as opposed to a failure reading Vault.
+type manifestSecretError struct {
+	msg string
+}
+
+func (e *manifestSecretError) Error() string {
+	return e.msg
+}
+
+// ApplyManifestResponse describes the environment created from a manifest.
+type ApplyManifestResponse struct {
+	EnvironmentID        string                   `json:"environmentId"`        // Mirage internal environment ID
+	RailwayEnvironmentID string                   `json:"railwayEnvironmentId"` // Railway's environment ID
+	RailwayProjectID     string                   `json:"railwayProjectId"`
+	Services             []ServiceProvisionResult `json:"services"` // Per-service outcome in manifest order
+	Warnings             []string                 `json:"warnings,omitempty"`
+}
+
+// ExportEnvironmentManifest returns a declarative manifest describing an environment's services,
+// variables and TTL. Variables backed by environment secrets are exported as environment secret
+// references, never as values. The :id parameter is the Railway environment ID.
+//
+// GET /api/v1/environments/:id/manifest?format=yaml|json
+// Response: the manifest document (YAML by default)
+func (c *EnvironmentController) ExportEnvironmentManifest(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "railway environment id required"})
+		return
+	}
+	format := strings.ToLower(ctx.DefaultQuery("format", manifest.FormatYAML))
+	if format != manifest.FormatYAML && format != manifest.FormatJSON {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "format must be yaml or json"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	// Look up the Mirage environment by Railway ID with ownership check
+	var env store.Environment
+	err = c.DB.Preload("Services", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to query environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment"})
+		return
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before exporting environments",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	// Unlike the snapshot, a manifest without its variables is misleading, so fail instead
+	vars, err := rwClient.GetAllEnvironmentAndServiceVariables(ctx, railway.GetAllEnvironmentAndServiceVariablesInput{
+		ProjectID:     env.RailwayProjectID,
+		EnvironmentID: env.RailwayEnvironmentID,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to fetch variables for manifest")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	var secretKeys map[string]string
+	if c.Vault != nil {
+		secretKeys, err = c.Vault.GetAllEnvironmentSecrets(ctx, user.ID, env.ID)
+		if err != nil {
+			log.Error().Err(err).Str("mirage_env_id", env.ID).Msg("failed to read environment secrets for manifest")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment secrets"})
+			return
+		}
+	}
+
+	links, err := loadServiceLinks(ctx, c.DB, user.ID, env.ID)
+	if err != nil {
+		log.Error().Err(err).Str("mirage_env_id", env.ID).Msg("failed to load service links for manifest")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve service links"})
+		return
+	}
+
+	data, err := manifest.Encode(buildManifest(env, vars, secretKeys, links), format)
+	if err != nil {
+		log.Error().Err(err).Str("mirage_env_id", env.ID).Msg("failed to encode manifest")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to encode manifest"})
+		return
+	}
+
+	contentType := contentTypeYAML
+	if format == manifest.FormatJSON {
+		contentType = contentTypeJSON
+	}
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// ApplyManifest provisions a new environment from a YAML or JSON manifest. Secret references
+// are resolved from the user's secrets before anything is created in Railway.
+//
+// POST /api/v1/environments/apply
+// Request body: the manifest document
+// Response: ApplyManifestResponse
+func (c *EnvironmentController) ApplyManifest(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+	m, err := manifest.Parse(body)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := m.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid manifest", "details": strings.Split(err.Error(), "\n")})
+		return
+	}
+	if m.ProjectID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "projectId is required to apply a manifest"})
+		return
+	}
+
+	// Resolve secret references up front so a missing secret creates nothing
+	var secrets, envSecrets map[string]string
+	if len(m.SecretRefs()) > 0 || m.SecretsFrom != "" {
+		if c.Vault == nil {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "secret storage not configured"})
+			return
+		}
+		secrets, envSecrets, err = c.resolveManifestSecrets(ctx, c.Vault, user.ID, m)
+		var secretErr *manifestSecretError
+		if errors.As(err, &secretErr) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": secretErr.Error()})
+			return
+		} else if err != nil {
+			log.Error().Err(err).Msg("failed to resolve manifest secret references")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve secrets"})
+			return
+		}
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before creating environments",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	result, err := c.applyManifest(ctx, rwClient, user.ID, m, secrets, envSecrets)
+	if err != nil {
+		if isProvisionPlanError(err) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if result.RailwayEnvironmentID == "" {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "partial": result})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// resolveManifestSecrets reads the values m's secret references point to: user secrets by key,
+// and the environment secrets of the SecretsFrom environment, which must belong to userID.
+func (c *EnvironmentController) resolveManifestSecrets(ctx context.Context, rv manifestSecretReader, userID string, m manifest.Manifest) (secrets, envSecrets map[string]string, err error) {
+	secrets = make(map[string]string)
+	for _, key := range m.SecretRefs() {
+		value, err := rv.GetSecretValue(ctx, userID, key)
+		if err == vault.ErrSecretNotFound {
+			return nil, nil, &manifestSecretError{msg: fmt.Sprintf("secret %q not found", key)}
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("read secret %s: %w", key, err)
+		}
+		secrets[key] = value
+	}
+	if m.SecretsFrom == "" {
+		return secrets, nil, nil
+	}
+
+	var source store.Environment
+	err = c.DB.WithContext(ctx).Select("id").Where("railway_environment_id = ? AND user_id = ?", m.SecretsFrom, userID).First(&source).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, &manifestSecretError{msg: fmt.Sprintf("secretsFrom environment %s not found", m.SecretsFrom)}
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("look up secretsFrom environment: %w", err)
+	}
+	envSecrets, err = rv.GetAllEnvironmentSecrets(ctx, userID, source.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read environment secrets: %w", err)
+	}
+	return secrets, envSecrets, nil
+}
+
+// buildManifest describes env as a manifest. Railway-injected variables are dropped, service
+// variables identical to an environment-level one are omitted, and any variable named after an
+// environment secret is exported as a reference to that secret of env. Variables recorded in
+// links are exported as their ${{service.VAR}} references rather than the rendered values,
+// which point at env's services and may hold their credentials.
+func buildManifest(env store.Environment, vars railway.GetAllEnvironmentAndServiceVariablesResult, secrets map[string]string, links []ServiceLink) manifest.Manifest {
+	m := manifest.Manifest{
+		APIVersion: manifest.APIVersion,
+		Kind:       manifest.KindEnvironment,
+		Name:       env.Name,
+		Type:       string(env.Type),
+		ProjectID:  env.RailwayProjectID,
+		TTLSeconds: env.TTLSeconds,
+		Services:   make([]manifest.Service, 0, len(env.Services)),
+	}
+
+	exportable := func(k string) bool {
+		return !strings.HasPrefix(k, railwaySystemVariablePrefix)
+	}
+
+	envVars := make(map[string]manifest.Variable)
+	for k, v := range vars.EnvironmentVariables {
+		if exportable(k) {
+			envVars[k] = manifest.Variable{Value: v}
+		}
+	}
+	// Environment secrets are injected into every service, so they belong at the environment level
+	for k := range secrets {
+		envVars[k] = manifest.Variable{EnvSecretRef: k}
+	}
+	if len(secrets) > 0 {
+		m.SecretsFrom = env.RailwayEnvironmentID
+	}
+	if len(envVars) > 0 {
+		m.Variables = envVars
+	}
+
+	services := make(map[string]bool, len(env.Services))
+	for _, svc := range env.Services {
+		services[svc.Name] = true
+	}
+	linked := make(map[string]map[string]string)
+	for _, l := range links {
+		if !services[l.Service] || !services[l.Target] {
+			continue
+		}
+		if linked[l.Service] == nil {
+			linked[l.Service] = make(map[string]string)
+		}
+		linked[l.Service][l.Variable] = l.Value
+	}
+
+	serviceVars := make(map[string]map[string]string, len(vars.ServiceVariables))
+	for _, sv := range vars.ServiceVariables {
+		serviceVars[sv.ServiceID] = sv.Variables
+	}
+
+	for _, svc := range env.Services {
+		ms := manifest.Service{Name: svc.Name}
+		switch svc.DeploymentType {
+		case store.DeploymentTypeDatabase:
+			ms.Database = svc.DatabaseEngine
+		case store.DeploymentTypeDockerImage:
+			ms.Image = svc.DockerImage
+			if ms.Image == "" && svc.ImageName != "" {
+				ms.Image = buildImageReference(serviceModelToSpec(svc))
+			}
+		default:
+			ms.Repo = svc.SourceRepo
+			ms.Branch = svc.SourceBranch
+			if svc.DockerfilePath != nil {
+				ms.DockerfilePath = *svc.DockerfilePath
+			}
+		}
+
+		own := make(map[string]manifest.Variable)
+		for k, v := range serviceVars[svc.RailwayServiceID] {
+			if !exportable(k) || isDatabaseTemplateVariable(ms.Database, k) {
+				continue
+			}
+			if _, isSecret := secrets[k]; isSecret {
+				continue
+			}
+			if shared, ok := envVars[k]; ok && shared.Value == v {
+				continue
+			}
+			own[k] = manifest.Variable{Value: v}
+		}
+		for k, ref := range linked[svc.Name] {
+			own[k] = manifest.Variable{Value: ref}
+		}
+		if len(own) > 0 {
+			ms.Variables = own
+		}
+		m.Services = append(m.Services, ms)
+	}
+	return m
+}
+
+// applyManifest creates the environment and services described by m. Secret references must
+// already be resolved into secrets and envSecrets. Environment-level variables read from
+// environment secrets become environment secrets of the new environment too. Services are
+// provisioned like POST /provision/services: in dependency order, with their links recorded and
+// services that can't be persisted destroyed. Failures after the Railway environment exists
+// return the partial result alongside the error.
+func (c *EnvironmentController) applyManifest(ctx context.Context, rw manifestApplier, userID string, m manifest.Manifest, secrets, envSecrets map[string]string) (ApplyManifestResponse, error) {
+	result := ApplyManifestResponse{RailwayProjectID: m.ProjectID}
+
+	envVars, err := manifest.Resolve(m.Variables, secrets, envSecrets)
+	if err != nil {
+		return result, err
+	}
+	specs := make([]ServiceSpec, 0, len(m.Services))
+	for _, s := range m.Services {
+		spec, err := manifestServiceToSpec(s, secrets, envSecrets)
+		if err != nil {
+			return result, fmt.Errorf("service %s: %w", s.Name, err)
+		}
+		if err := validateServiceSpec(spec); err != nil {
+			return result, err
+		}
+		specs = append(specs, spec)
+	}
+	// Check references before creating anything; provisionServices plans again against the new environment
+	if _, err := planProvisioning(specs, nil); err != nil {
+		return result, err
+	}
+
+	created, err := rw.CreateEnvironment(ctx, railway.CreateEnvironmentInput{ProjectID: m.ProjectID, Name: m.Name})
+	if err != nil {
+		return result, fmt.Errorf("create environment: %w", err)
+	}
+	result.RailwayEnvironmentID = created.EnvironmentID
+
+	env, err := c.persistManifestEnvironment(userID, m, created.EnvironmentID)
+	if err != nil {
+		log.Error().Err(err).
+			Str("railway_env_id", created.EnvironmentID).
+			Msg("failed to persist environment from manifest")
+		return result, fmt.Errorf("persist environment: %w", err)
+	}
+	result.EnvironmentID = env.ID
+
+	if copied := inheritedEnvironmentSecrets(m.Variables, envVars); len(copied) > 0 && c.Vault != nil {
+		if err := c.Vault.BulkStoreEnvironmentSecrets(ctx, userID, env.ID, copied); err != nil {
+			log.Warn().Err(err).Str("env_id", env.ID).Msg("failed to store environment secrets from manifest")
+			result.Warnings = append(result.Warnings, "environment secrets were not stored")
+		}
+	}
+
+	sc := &ServicesController{DB: c.DB, Vault: c.Vault, Concurrency: c.Concurrency}
+	results, err := sc.provisionServices(ctx, rw, userID, ProvisionServicesRequest{
+		ProjectID:            m.ProjectID,
+		EnvironmentID:        env.ID,
+		RailwayEnvironmentID: created.EnvironmentID,
+		Services:             specs,
+	}, created.EnvironmentID, envVars)
+	result.Services = results
+	if err != nil {
+		return result, err
+	}
+
+	log.Info().
+		Str("env_id", env.ID).
+		Str("railway_env_id", created.EnvironmentID).
+		Int("service_count", len(results)).
+		Msg("applied environment manifest")
+
+	return result, nil
+}
+
+// persistManifestEnvironment stores an environment created from a manifest with metadata recording its source.
+func (c *EnvironmentController) persistManifestEnvironment(userID string, m manifest.Manifest, railwayEnvID string) (store.Environment, error) {
+	envType := store.EnvironmentTypeDev // Default to dev, matching ProvisionEnvironment
+	if m.Type != "" {
+		envType = store.EnvironmentType(m.Type)
+	}
+
+	now := time.Now()
+	env := store.Environment{
+		ID:                   uuid.New().String(),
+		UserID:               userID,
+		Name:                 m.Name,
+		Type:                 envType,
+		Status:               status.StatusCreating,
+		RailwayProjectID:     m.ProjectID,
+		RailwayEnvironmentID: railwayEnvID,
+		TTLSeconds:           m.TTLSeconds,
+		ExpiresAt:            store.ComputeExpiresAt(now, m.TTLSeconds),
+		CreatedAt:            now,
+		UpdatedAt:            now,
+	}
+
+	err := c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&env).Error; err != nil {
+			return err
+		}
+		provisionOutputsJSON, _ := json.Marshal(map[string]interface{}{
+			"projectId":     m.ProjectID,
+			"environmentId": railwayEnvID,
+			"source":        manifestProvisionSource,
+		})
+		metadata := store.EnvironmentMetadata{
+			ID:                   uuid.New().String(),
+			UserID:               userID,
+			EnvironmentID:        env.ID,
+			ProvisionOutputsJSON: provisionOutputsJSON,
+			CreatedAt:            now,
+			UpdatedAt:            now,
+		}
+		return tx.Create(&metadata).Error
+	})
+	return env, err
+}
+
+// inheritedEnvironmentSecrets returns the resolved values of the environment-level variables
+// that reference environment secrets.
+func inheritedEnvironmentSecrets(vars map[string]manifest.Variable, resolved map[string]string) map[string]string {
+	inherited := make(map[string]string)
+	for name, v := range vars {
+		if v.EnvSecretRef != "" {
+			inherited[name] = resolved[name]
+		}
+	}
+	return inherited
+}
+
+// manifestServiceToSpec converts a manifest service into the ServiceSpec that provisions it.
+func manifestServiceToSpec(s manifest.Service, secrets, envSecrets map[string]string) (ServiceSpec, error) {
+	vars, err := manifest.Resolve(s.Variables, secrets, envSecrets)
+	if err != nil {
+		return ServiceSpec{}, err
+	}
+	spec := ServiceSpec{Name: s.Name, EnvVars: vars}
+	if s.Database != "" {
+		spec.Database = &DatabaseSpec{Engine: s.Database}
+		return spec, nil
+	}
+	if s.Image != "" {
+		name, tag := manifest.SplitImage(s.Image)
+		spec.ImageName = &name
+		if tag != "" {
+			spec.ImageTag = &tag
+		}
+		return spec, nil
+	}
+	repo, branch := s.Repo, s.Branch
+	spec.Repo = &repo
+	spec.Branch = &branch
+	if s.DockerfilePath != "" {
+		path := s.DockerfilePath
+		spec.DockerfilePath = &path
+	}
+	return spec, nil
+}
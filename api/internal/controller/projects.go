@@ -1,7 +1,10 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -10,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/jobs"
 	"github.com/stwalsh4118/mirageapi/internal/railway"
 	"github.com/stwalsh4118/mirageapi/internal/status"
 	"github.com/stwalsh4118/mirageapi/internal/store"
@@ -439,12 +443,44 @@ func (c *EnvironmentController) ProvisionProject(ctx *gin.Context) {
 		return
 	}
 
-	// Step 1: Create the Railway project
-	res, err := rwClient.CreateProject(ctx, railway.CreateProjectInput{DefaultEnvironmentName: req.DefaultEnvironmentName, Name: req.Name})
+	finish, ok := beginIdempotentRequest(ctx, c.DB, c.IdempotencyRetention, user.ID, idempotencyScopeProject, req.RequestID, req)
+	if !ok {
+		return
+	}
+	defer finish()
+
+	resp, err := c.provisionProject(ctx, rwClient, user.ID, req)
 	if err != nil {
-		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		code := http.StatusBadGateway
+		if errors.Is(err, errProjectNotPersisted) {
+			code = http.StatusInternalServerError
+			// The project exists in Railway; a retry would create another
+			keepIdempotentResponse(ctx)
+		}
+		ctx.JSON(code, gin.H{"error": err.Error()})
 		return
 	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// errProjectNotPersisted marks a project that exists in Railway but whose base environment
+// could not be stored.
+var errProjectNotPersisted = errors.New("project created but failed to persist to database")
+
+// projectProvisioner is the subset of the Railway client used to create a project.
+type projectProvisioner interface {
+	CreateProject(ctx context.Context, in railway.CreateProjectInput) (railway.CreateProjectResult, error)
+	GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error)
+}
+
+// provisionProject creates a Railway project, resolves its default environment and persists
+// that environment and its metadata.
+func (c *EnvironmentController) provisionProject(ctx context.Context, rw projectProvisioner, userID string, req ProvisionProjectRequest) (ProvisionProjectResponse, error) {
+	// Step 1: Create the Railway project
+	res, err := rw.CreateProject(ctx, railway.CreateProjectInput{DefaultEnvironmentName: req.DefaultEnvironmentName, Name: req.Name})
+	if err != nil {
+		return ProvisionProjectResponse{}, err
+	}
 
 	// Step 2: Explicitly fetch the default environment from Railway
 	// Railway mutation responses can be unreliable, so we explicitly query for the environment
@@ -460,17 +496,15 @@ func (c *EnvironmentController) ProvisionProject(ctx *gin.Context) {
 			Str("project_id", res.ProjectID).
 			Msg("base environment ID not in mutation response, fetching explicitly")
 
-		pd, err := rwClient.GetProjectWithDetailsByID(ctx, res.ProjectID)
+		pd, err := rw.GetProjectWithDetailsByID(ctx, res.ProjectID)
 		if err != nil {
 			log.Error().Err(err).Str("project_id", res.ProjectID).Msg("failed to fetch project details for default environment")
-			ctx.JSON(http.StatusBadGateway, gin.H{"error": "project created but failed to retrieve default environment: " + err.Error()})
-			return
+			return ProvisionProjectResponse{}, fmt.Errorf("project created but failed to retrieve default environment: %w", err)
 		}
 
 		if len(pd.Environments) == 0 {
 			log.Error().Str("project_id", res.ProjectID).Msg("project created but has no environments")
-			ctx.JSON(http.StatusBadGateway, gin.H{"error": "project created but no default environment found"})
-			return
+			return ProvisionProjectResponse{}, errors.New("project created but no default environment found")
 		}
 
 		// Find the environment by name (or take first one)
@@ -495,7 +529,7 @@ func (c *EnvironmentController) ProvisionProject(ctx *gin.Context) {
 	if c.DB != nil {
 		env := store.Environment{
 			ID:                   uuid.New().String(),
-			UserID:               user.ID, // Set from authenticated user
+			UserID:               userID, // Set from authenticated user
 			Name:                 envName,
 			Type:                 store.EnvironmentTypeProd, // Base environment defaults to prod
 			Status:               status.StatusCreating,
@@ -522,7 +556,7 @@ func (c *EnvironmentController) ProvisionProject(ctx *gin.Context) {
 
 			metadata := store.EnvironmentMetadata{
 				ID:                   uuid.New().String(),
-				UserID:               user.ID, // Set from authenticated user
+				UserID:               userID, // Set from authenticated user
 				EnvironmentID:        env.ID,
 				WizardInputsJSON:     nil, // No wizard inputs for project creation
 				ProvisionOutputsJSON: provisionOutputsJSON,
@@ -549,31 +583,30 @@ func (c *EnvironmentController) ProvisionProject(ctx *gin.Context) {
 				Str("project_id", res.ProjectID).
 				Str("railway_env_id", railwayEnvID).
 				Msg("failed to persist environment to database after Railway project creation")
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "project created but failed to persist to database: " + txErr.Error()})
-			return
+			return ProvisionProjectResponse{}, fmt.Errorf("%w: %w", errProjectNotPersisted, txErr)
 		}
 
 		// Return Mirage environment ID for frontend use (foreign keys)
-		ctx.JSON(http.StatusOK, ProvisionProjectResponse{
+		return ProvisionProjectResponse{
 			ProjectID:            res.ProjectID,
 			BaseEnvironmentID:    env.ID,       // Mirage ID for foreign keys
 			RailwayEnvironmentID: railwayEnvID, // Railway ID for Railway API calls
 			Name:                 res.Name,
-		})
-		return
+		}, nil
 	}
 
 	// If DB is nil, return Railway ID for backward compatibility
-	ctx.JSON(http.StatusOK, ProvisionProjectResponse{
+	return ProvisionProjectResponse{
 		ProjectID:            res.ProjectID,
 		BaseEnvironmentID:    railwayEnvID,
 		RailwayEnvironmentID: railwayEnvID,
 		Name:                 res.Name,
-	})
+	}, nil
 }
 
 // DeleteRailwayEnvironment deletes a Railway environment by its Railway environment ID.
-// After successful Railway deletion, cleans up the database: services, metadata, and environment records.
+// After successful Railway deletion, deletes the environment's volumes and cleans up the database:
+// services, volumes, metadata, and environment records.
 func (c *EnvironmentController) DeleteRailwayEnvironment(ctx *gin.Context) {
 	if c.Railway == nil {
 		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
@@ -621,65 +654,17 @@ func (c *EnvironmentController) DeleteRailwayEnvironment(ctx *gin.Context) {
 		return
 	}
 
-	// Step 1: Delete from Railway first (fail fast if Railway API fails)
+	// Delete from Railway first (fail fast if Railway API fails), then clean up our records
 	log.Info().
 		Str("railway_env_id", railwayEnvID).
 		Str("user_id", user.ID).
 		Msg("deleting railway environment")
-	if err := rwClient.DestroyEnvironment(ctx, railway.DestroyEnvironmentInput{EnvironmentID: railwayEnvID}); err != nil {
+	if err := jobs.DestroyEnvironment(ctx, c.DB, rwClient, c.Vault, &env); err != nil {
 		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("railway delete environment failed")
 		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Step 2: Clean up database (Railway deletion succeeded, so clean up our records)
-	// Note: env was already fetched above with ownership verification
-	if c.DB != nil {
-
-		// Use transaction to ensure atomic cleanup
-		txErr := c.DB.Transaction(func(tx *gorm.DB) error {
-			// Delete all services for this environment
-			result := tx.Where("environment_id = ?", env.ID).Delete(&store.Service{})
-			if result.Error != nil {
-				return result.Error
-			}
-			log.Info().
-				Str("mirage_env_id", env.ID).
-				Int64("services_deleted", result.RowsAffected).
-				Msg("deleted services from database")
-
-			// Delete metadata for this environment (may not exist for old environments)
-			result = tx.Where("environment_id = ?", env.ID).Delete(&store.EnvironmentMetadata{})
-			if result.Error != nil {
-				return result.Error
-			}
-			if result.RowsAffected > 0 {
-				log.Info().
-					Str("mirage_env_id", env.ID).
-					Msg("deleted environment metadata from database")
-			}
-
-			// Delete the environment itself
-			if err := tx.Delete(&env).Error; err != nil {
-				return err
-			}
-			log.Info().
-				Str("mirage_env_id", env.ID).
-				Str("railway_env_id", railwayEnvID).
-				Msg("deleted environment from database")
-
-			return nil
-		})
-
-		if txErr != nil {
-			log.Error().Err(txErr).
-				Str("railway_env_id", railwayEnvID).
-				Str("mirage_env_id", env.ID).
-				Msg("failed to clean up database after Railway environment deletion")
-			// Don't fail the request - Railway resource was deleted successfully
-		}
-	}
-
 	ctx.Status(http.StatusNoContent)
 }
 
@@ -769,25 +754,13 @@ func (c *EnvironmentController) DeleteRailwayProject(ctx *gin.Context) {
 			totalServices := int64(0)
 			totalMetadata := int64(0)
 
-			for _, env := range envs {
-				// Delete all services for this environment
-				result := tx.Where("environment_id = ?", env.ID).Delete(&store.Service{})
-				if result.Error != nil {
-					return result.Error
-				}
-				totalServices += result.RowsAffected
-
-				// Delete metadata for this environment (may not exist)
-				result = tx.Where("environment_id = ?", env.ID).Delete(&store.EnvironmentMetadata{})
-				if result.Error != nil {
-					return result.Error
-				}
-				totalMetadata += result.RowsAffected
-
-				// Delete the environment itself
-				if err := tx.Delete(&env).Error; err != nil {
+			for i := range envs {
+				res, err := store.DeleteEnvironmentRecords(tx, &envs[i])
+				if err != nil {
 					return err
 				}
+				totalServices += res.ServicesDeleted
+				totalMetadata += res.MetadataDeleted
 			}
 
 			log.Info().
@@ -807,6 +780,14 @@ func (c *EnvironmentController) DeleteRailwayProject(ctx *gin.Context) {
 				Msg("failed to clean up database after Railway project deletion")
 			// Don't fail the request - Railway resource was deleted successfully
 		}
+
+		if c.Vault != nil {
+			for _, env := range envs {
+				if err := c.Vault.DeleteAllEnvironmentSecrets(ctx, user.ID, env.ID); err != nil {
+					log.Warn().Err(err).Str("mirage_env_id", env.ID).Msg("failed to delete environment secrets")
+				}
+			}
+		}
 	}
 
 	ctx.Status(http.StatusNoContent)
@@ -34,6 +34,22 @@ func (c *SecretsController) RegisterRoutes(r *gin.RouterGroup) {
 		secrets.GET("/github/status", c.GetGitHubTokenStatus)
 		secrets.POST("/github/validate", c.ValidateGitHubToken)
 		secrets.DELETE("/github", c.DeleteGitHubToken)
+
+		// Docker registry credentials management
+		secrets.POST("/docker", c.StoreDockerCredentials)
+		secrets.GET("/docker", c.ListDockerRegistries)
+		secrets.GET("/docker/:registry", c.GetDockerCredentialsStatus)
+		secrets.DELETE("/docker/:registry", c.DeleteDockerCredentials)
+
+		// Generic user secrets
+		secrets.GET("/custom", c.ListCustomSecrets)
+		secrets.POST("/custom", c.StoreCustomSecret)
+		secrets.GET("/custom/:key", c.GetCustomSecret)
+		secrets.DELETE("/custom/:key", c.DeleteCustomSecret)
+		secrets.PATCH("/custom/:key/metadata", c.UpdateCustomSecretMetadata)
+		secrets.GET("/custom/:key/versions", c.ListCustomSecretVersions)
+		secrets.GET("/custom/:key/versions/:version", c.GetCustomSecretVersion)
+		secrets.POST("/custom/:key/rollback", c.RollbackCustomSecret)
 	}
 }
 
@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+)
+
+// StoreCustomSecretRequest is the request payload for storing a generic secret
+type StoreCustomSecretRequest struct {
+	Key        string   `json:"key" binding:"required"`
+	Value      string   `json:"value" binding:"required"`
+	Tags       []string `json:"tags,omitempty"`
+	SecretType string   `json:"secret_type,omitempty"`
+}
+
+// UpdateCustomSecretMetadataRequest replaces the tags or type of a generic secret.
+// Fields left out are kept; an empty tags list clears the tags.
+type UpdateCustomSecretMetadataRequest struct {
+	Tags       *[]string `json:"tags,omitempty"`
+	SecretType string    `json:"secret_type,omitempty"`
+}
+
+// secretMetadata returns the metadata update to apply; Tags stays nil when they were left out.
+func (r UpdateCustomSecretMetadataRequest) secretMetadata() vault.SecretMetadata {
+	metadata := vault.SecretMetadata{SecretType: r.SecretType}
+	if r.Tags != nil {
+		metadata.Tags = *r.Tags
+		if metadata.Tags == nil {
+			metadata.Tags = []string{}
+		}
+	}
+	return metadata
+}
+
+// RollbackCustomSecretRequest names the version to restore
+type RollbackCustomSecretRequest struct {
+	Version int `json:"version" binding:"required"`
+}
+
+// ListCustomSecretsResponse lists secret metadata (values are never listed)
+type ListCustomSecretsResponse struct {
+	Secrets []vault.SecretMetadata `json:"secrets"`
+}
+
+// ListCustomSecretVersionsResponse lists the versions of a secret, newest first
+type ListCustomSecretVersionsResponse struct {
+	Key      string `json:"key"`
+	Versions []int  `json:"versions"`
+}
+
+// ListCustomSecrets lists the user's generic secrets. Repeat ?tag= (or pass a
+// comma-separated list) to only return secrets carrying every given tag.
+//
+// GET /api/v1/secrets/custom?tag=payments&tag=prod
+// Response: {"secrets": [{"key": "stripe-key", "tags": ["payments", "prod"], "version": 3, ...}]}
+func (c *SecretsController) ListCustomSecrets(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+
+	secrets, err := c.Vault.ListSecrets(ctx, user.ID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Msg("failed to list secrets")
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to list secrets",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ListCustomSecretsResponse{
+		Secrets: filterSecretsByTags(secrets, parseTagQuery(ctx.QueryArray("tag"))),
+	})
+}
+
+// StoreCustomSecret creates a generic secret or stores a new version of an existing one.
+//
+// POST /api/v1/secrets/custom
+// Request body: {"key": "stripe-key", "value": "sk_live_xxx", "tags": ["payments"]}
+// Response: {"key": "stripe-key", "version": 1, "tags": ["payments"], ...}
+func (c *SecretsController) StoreCustomSecret(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+
+	var req StoreCustomSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	err := c.Vault.StoreSecret(ctx, user.ID, req.Key, req.Value, vault.SecretMetadata{
+		Tags:       req.Tags,
+		SecretType: req.SecretType,
+	})
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, req.Key, "failed to store secret")
+		return
+	}
+
+	metadata, err := c.Vault.GetSecretMetadata(ctx, user.ID, req.Key)
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, req.Key, "failed to read stored secret")
+		return
+	}
+
+	log.Info().
+		Str("user_id", user.ID).
+		Str("key", req.Key).
+		Int("version", metadata.Version).
+		Msg("successfully stored secret")
+
+	ctx.JSON(http.StatusOK, metadata)
+}
+
+// GetCustomSecret returns the current value and metadata of a generic secret.
+//
+// GET /api/v1/secrets/custom/:key
+// Response: {"key": "stripe-key", "value": "sk_live_xxx", "metadata": {...}}
+func (c *SecretsController) GetCustomSecret(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	secret, err := c.Vault.GetSecret(ctx, user.ID, key)
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to get secret")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, secret)
+}
+
+// DeleteCustomSecret soft-deletes a generic secret. Its version history is kept.
+//
+// DELETE /api/v1/secrets/custom/:key
+func (c *SecretsController) DeleteCustomSecret(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	if err := c.Vault.DeleteSecret(ctx, user.ID, key); err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to delete secret")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// UpdateCustomSecretMetadata replaces a secret's tags or type without creating a new version.
+//
+// PATCH /api/v1/secrets/custom/:key/metadata
+// Request body: {"tags": ["payments", "prod"]}
+// Response: {"key": "stripe-key", "tags": ["payments", "prod"], ...}
+func (c *SecretsController) UpdateCustomSecretMetadata(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	var req UpdateCustomSecretMetadataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	err := c.Vault.UpdateSecretMetadata(ctx, user.ID, key, req.secretMetadata())
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to update secret metadata")
+		return
+	}
+
+	metadata, err := c.Vault.GetSecretMetadata(ctx, user.ID, key)
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to read secret metadata")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, metadata)
+}
+
+// ListCustomSecretVersions lists the version numbers of a secret, newest first.
+//
+// GET /api/v1/secrets/custom/:key/versions
+// Response: {"key": "stripe-key", "versions": [3, 2, 1]}
+func (c *SecretsController) ListCustomSecretVersions(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	versions, err := c.Vault.ListSecretVersions(ctx, user.ID, key)
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to list secret versions")
+		return
+	}
+	if len(versions) == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "secret not found"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ListCustomSecretVersionsResponse{Key: key, Versions: versions})
+}
+
+// GetCustomSecretVersion returns the value of a specific version of a secret.
+//
+// GET /api/v1/secrets/custom/:key/versions/:version
+// Response: {"key": "stripe-key", "value": "sk_live_old", "metadata": {"version": 1, ...}}
+func (c *SecretsController) GetCustomSecretVersion(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil || version <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+		return
+	}
+
+	secret, err := c.Vault.GetSecretVersion(ctx, user.ID, key, version)
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to get secret version")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, secret)
+}
+
+// RollbackCustomSecret restores an earlier version by writing its value as a new version.
+//
+// POST /api/v1/secrets/custom/:key/rollback
+// Request body: {"version": 1}
+// Response: {"key": "stripe-key", "version": 4, ...}
+func (c *SecretsController) RollbackCustomSecret(ctx *gin.Context) {
+	user, ok := c.resolveCustomSecretsUser(ctx)
+	if !ok {
+		return
+	}
+	key := ctx.Param("key")
+
+	var req RollbackCustomSecretRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Version <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+		return
+	}
+
+	if err := c.Vault.RollbackSecret(ctx, user.ID, key, req.Version); err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to roll back secret")
+		return
+	}
+
+	metadata, err := c.Vault.GetSecretMetadata(ctx, user.ID, key)
+	if err != nil {
+		respondCustomSecretError(ctx, err, user.ID, key, "failed to read secret metadata")
+		return
+	}
+
+	log.Info().
+		Str("user_id", user.ID).
+		Str("key", key).
+		Int("from_version", req.Version).
+		Int("new_version", metadata.Version).
+		Msg("successfully rolled back secret")
+
+	ctx.JSON(http.StatusOK, metadata)
+}
+
+// resolveCustomSecretsUser checks Vault availability and authentication for the generic secret handlers.
+// It writes the error response and returns ok=false when either check fails.
+func (c *SecretsController) resolveCustomSecretsUser(ctx *gin.Context) (*store.User, bool) {
+	if c.Vault == nil {
+		log.Error().Msg("vault client not configured in secrets controller")
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "secret management not available",
+		})
+		return nil, false
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get current user")
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+		return nil, false
+	}
+
+	return user, true
+}
+
+// respondCustomSecretError maps Vault errors for generic secrets to HTTP responses.
+func respondCustomSecretError(ctx *gin.Context, err error, userID, key, message string) {
+	switch {
+	case errors.Is(err, vault.ErrSecretNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "secret not found"})
+	case errors.Is(err, vault.ErrVersionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "secret version not found"})
+	case errors.Is(err, vault.ErrInvalidSecret):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error().
+			Err(err).
+			Str("user_id", userID).
+			Str("key", key).
+			Msg(message)
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"details": err.Error(),
+		})
+	}
+}
+
+// parseTagQuery flattens repeated and comma-separated tag query values into lowercase tags.
+func parseTagQuery(values []string) []string {
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			if t := strings.ToLower(strings.TrimSpace(tag)); t != "" {
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags
+}
+
+// filterSecretsByTags keeps the secrets that carry every tag in tags.
+// An empty tag list matches every secret.
+func filterSecretsByTags(secrets []vault.SecretMetadata, tags []string) []vault.SecretMetadata {
+	if len(tags) == 0 {
+		return secrets
+	}
+
+	filtered := make([]vault.SecretMetadata, 0, len(secrets))
+	for _, secret := range secrets {
+		have := make(map[string]bool, len(secret.Tags))
+		for _, tag := range secret.Tags {
+			have[tag] = true
+		}
+		matches := true
+		for _, tag := range tags {
+			if !have[tag] {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, secret)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+)
+
+func TestFilterSecretsByTags(t *testing.T) {
+	secrets := []vault.SecretMetadata{
+		{Key: "stripe", Tags: []string{"payments", "prod"}},
+		{Key: "stripe-test", Tags: []string{"payments", "staging"}},
+		{Key: "sendgrid", Tags: []string{"email", "prod"}},
+		{Key: "untagged", Tags: []string{}},
+	}
+
+	keys := func(list []vault.SecretMetadata) []string {
+		out := []string{}
+		for _, s := range list {
+			out = append(out, s.Key)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"stripe", "stripe-test", "sendgrid", "untagged"}, keys(filterSecretsByTags(secrets, nil)))
+	assert.Equal(t, []string{"stripe", "stripe-test"}, keys(filterSecretsByTags(secrets, []string{"payments"})))
+	assert.Equal(t, []string{"stripe"}, keys(filterSecretsByTags(secrets, []string{"payments", "prod"})))
+	assert.Empty(t, filterSecretsByTags(secrets, []string{"missing"}))
+}
+
+func TestParseTagQuery(t *testing.T) {
+	assert.Nil(t, parseTagQuery(nil))
+	assert.Equal(t, []string{"payments", "prod", "email"}, parseTagQuery([]string{"Payments, prod", " ", "email"}))
+}
+
+func TestUpdateCustomSecretMetadataRequest_OmittedTagsAreKept(t *testing.T) {
+	var req UpdateCustomSecretMetadataRequest
+	assert.NoError(t, json.Unmarshal([]byte(`{"secret_type":"api_key"}`), &req))
+	metadata := req.secretMetadata()
+	assert.Nil(t, metadata.Tags)
+	assert.Equal(t, "api_key", metadata.SecretType)
+
+	req = UpdateCustomSecretMetadataRequest{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"tags":[]}`), &req))
+	assert.Equal(t, []string{}, req.secretMetadata().Tags)
+}
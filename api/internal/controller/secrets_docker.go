@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+)
+
+// StoreDockerCredentialsRequest is the request payload for storing registry credentials
+type StoreDockerCredentialsRequest struct {
+	Registry string `json:"registry" binding:"required"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Email    string `json:"email,omitempty"`
+}
+
+// StoreDockerCredentialsResponse is the response for successful credential storage
+type StoreDockerCredentialsResponse struct {
+	Success  bool   `json:"success"`
+	Registry string `json:"registry"`
+	StoredAt string `json:"stored_at"`
+	Message  string `json:"message"`
+}
+
+// ListDockerRegistriesResponse lists the registries a user has credentials for
+type ListDockerRegistriesResponse struct {
+	Registries []string `json:"registries"`
+}
+
+// DockerCredentialsStatusResponse describes stored credentials without exposing the password
+type DockerCredentialsStatusResponse struct {
+	Configured bool   `json:"configured"`
+	Registry   string `json:"registry"`
+	Username   string `json:"username,omitempty"`
+	Email      string `json:"email,omitempty"`
+}
+
+// DeleteDockerCredentialsResponse is the response for credential deletion
+type DeleteDockerCredentialsResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// StoreDockerCredentials stores or updates the user's credentials for a container registry.
+//
+// POST /api/v1/secrets/docker
+// Request body: {"registry": "ghcr.io", "username": "octocat", "password": "ghp_xxx"}
+// Response: {"success": true, "registry": "ghcr.io", "stored_at": "2024-01-01T00:00:00Z"}
+func (c *SecretsController) StoreDockerCredentials(ctx *gin.Context) {
+	if c.Vault == nil {
+		log.Error().Msg("vault client not configured in secrets controller")
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "secret management not available",
+		})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get current user")
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+		return
+	}
+
+	// Parse request body
+	var req StoreDockerCredentialsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		log.Warn().Err(err).Str("user_id", user.ID).Msg("invalid request body for store docker credentials")
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	registry := vault.NormalizeRegistry(req.Registry)
+	err = c.Vault.StoreDockerCredentials(ctx, user.ID, vault.DockerCredentials{
+		Registry: registry,
+		Username: req.Username,
+		Password: req.Password,
+		Email:    req.Email,
+	})
+	if err != nil {
+		if errors.Is(err, vault.ErrInvalidSecret) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().
+			Err(err).
+			Str("user_id", user.ID).
+			Str("registry", registry).
+			Msg("failed to store docker credentials")
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to store docker credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Info().
+		Str("user_id", user.ID).
+		Str("registry", registry).
+		Msg("successfully stored docker credentials")
+
+	ctx.JSON(http.StatusOK, StoreDockerCredentialsResponse{
+		Success:  true,
+		Registry: registry,
+		StoredAt: getCurrentTimestamp(),
+		Message:  "Docker credentials stored successfully",
+	})
+}
+
+// ListDockerRegistries lists the registries the user has stored credentials for.
+//
+// GET /api/v1/secrets/docker
+// Response: {"registries": ["docker.io", "ghcr.io"]}
+func (c *SecretsController) ListDockerRegistries(ctx *gin.Context) {
+	if c.Vault == nil {
+		log.Error().Msg("vault client not configured in secrets controller")
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "secret management not available",
+		})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get current user")
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+		return
+	}
+
+	registries, err := c.Vault.ListDockerRegistries(ctx, user.ID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("user_id", user.ID).
+			Msg("failed to list docker registries")
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to list docker registries",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ListDockerRegistriesResponse{
+		Registries: registries,
+	})
+}
+
+// GetDockerCredentialsStatus reports whether credentials exist for a registry.
+// The password is never returned.
+//
+// GET /api/v1/secrets/docker/:registry
+// Response: {"configured": true, "registry": "ghcr.io", "username": "octocat"}
+func (c *SecretsController) GetDockerCredentialsStatus(ctx *gin.Context) {
+	if c.Vault == nil {
+		log.Error().Msg("vault client not configured in secrets controller")
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "secret management not available",
+		})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get current user")
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+		return
+	}
+
+	registry := vault.NormalizeRegistry(ctx.Param("registry"))
+	creds, err := c.Vault.GetDockerCredentials(ctx, user.ID, registry)
+	if err != nil {
+		if err == vault.ErrSecretNotFound {
+			ctx.JSON(http.StatusOK, DockerCredentialsStatusResponse{
+				Configured: false,
+				Registry:   registry,
+			})
+			return
+		}
+		if errors.Is(err, vault.ErrInvalidSecret) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().
+			Err(err).
+			Str("user_id", user.ID).
+			Str("registry", registry).
+			Msg("failed to get docker credentials status")
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to check docker credentials status",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, DockerCredentialsStatusResponse{
+		Configured: true,
+		Registry:   creds.Registry,
+		Username:   creds.Username,
+		Email:      creds.Email,
+	})
+}
+
+// DeleteDockerCredentials removes the user's credentials for a registry.
+//
+// DELETE /api/v1/secrets/docker/:registry
+// Response: {"success": true, "message": "Docker credentials deleted successfully"}
+func (c *SecretsController) DeleteDockerCredentials(ctx *gin.Context) {
+	if c.Vault == nil {
+		log.Error().Msg("vault client not configured in secrets controller")
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "secret management not available",
+		})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get current user")
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"error": "authentication required",
+		})
+		return
+	}
+
+	registry := vault.NormalizeRegistry(ctx.Param("registry"))
+	err = c.Vault.DeleteDockerCredentials(ctx, user.ID, registry)
+	if err != nil {
+		if err == vault.ErrSecretNotFound {
+			ctx.JSON(http.StatusNotFound, gin.H{
+				"error":   "no docker credentials configured",
+				"message": "There are no credentials stored for " + registry,
+			})
+			return
+		}
+		if errors.Is(err, vault.ErrInvalidSecret) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().
+			Err(err).
+			Str("user_id", user.ID).
+			Str("registry", registry).
+			Msg("failed to delete docker credentials")
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed to delete docker credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Info().
+		Str("user_id", user.ID).
+		Str("registry", registry).
+		Msg("successfully deleted docker credentials")
+
+	ctx.JSON(http.StatusOK, DeleteDockerCredentialsResponse{
+		Success: true,
+		Message: "Docker credentials deleted successfully",
+	})
+}
@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// Service actions
+const (
+	serviceActionRedeploy = "redeploy"
+	serviceActionRestart  = "restart"
+	serviceActionRollback = "rollback"
+)
+
+// defaultDeploymentHistoryLimit is how many deployments are listed when no limit is given
+const defaultDeploymentHistoryLimit = 20
+
+var (
+	// errNoDeployments is returned when a service has never been deployed in its environment
+	errNoDeployments = errors.New("service has no deployments in its environment")
+	// errDeploymentNotFound is returned when a rollback target is not one of the service's deployments
+	errDeploymentNotFound = errors.New("deployment not found for service")
+	// errActionNotAllowed is returned when Railway reports the deployment can't take the action
+	errActionNotAllowed = errors.New("action not allowed for deployment")
+)
+
+// serviceDeploymentClient is the subset of the Railway client used to operate service deployments.
+type serviceDeploymentClient interface {
+	ListServiceDeployments(ctx context.Context, in railway.ListServiceDeploymentsInput) ([]railway.Deployment, error)
+	RedeployDeployment(ctx context.Context, deploymentID string) (railway.Deployment, error)
+	RestartDeployment(ctx context.Context, deploymentID string) error
+	RollbackDeployment(ctx context.Context, deploymentID string) error
+}
+
+// DeploymentDTO is one entry of a service's deploy history
+type DeploymentDTO struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"` // Railway deployment status, e.g. SUCCESS, CRASHED, REMOVED
+	CreatedAt   string `json:"createdAt"`
+	CanRedeploy bool   `json:"canRedeploy"`
+	CanRollback bool   `json:"canRollback"`
+}
+
+// RollbackServiceRequest selects the deployment to roll back to
+type RollbackServiceRequest struct {
+	DeploymentID string `json:"deploymentId" binding:"required"`
+}
+
+// ServiceActionResponse reports the outcome of a redeploy, restart or rollback
+type ServiceActionResponse struct {
+	ServiceID       string `json:"serviceId"`
+	Action          string `json:"action"`
+	DeploymentID    string `json:"deploymentId"`              // Deployment the action was applied to
+	NewDeploymentID string `json:"newDeploymentId,omitempty"` // Set for redeploys
+	Status          string `json:"status"`                    // Service status after the action
+}
+
+// ListServiceDeployments returns the service's deploy history in its environment, newest first.
+// GET /api/v1/services/:id/deployments?limit=20
+func (c *ServicesController) ListServiceDeployments(ctx *gin.Context) {
+	limit := defaultDeploymentHistoryLimit
+	if raw := ctx.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > railway.MaxDeploymentHistory {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", railway.MaxDeploymentHistory)})
+			return
+		}
+		limit = n
+	}
+
+	svc, env, rwClient, ok := c.serviceActionTarget(ctx)
+	if !ok {
+		return
+	}
+
+	deployments, err := rwClient.ListServiceDeployments(ctx, railway.ListServiceDeploymentsInput{
+		ServiceID:     svc.RailwayServiceID,
+		EnvironmentID: env.RailwayEnvironmentID,
+		Limit:         limit,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("service_id", svc.ID).Msg("failed to list service deployments")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to list deployments", "message": err.Error()})
+		return
+	}
+
+	out := make([]DeploymentDTO, 0, len(deployments))
+	for _, d := range deployments {
+		out = append(out, DeploymentDTO{
+			ID:          d.ID,
+			Status:      d.Status,
+			CreatedAt:   d.CreatedAt,
+			CanRedeploy: d.CanRedeploy,
+			CanRollback: d.CanRollback,
+		})
+	}
+	ctx.JSON(http.StatusOK, out)
+}
+
+// RedeployService rebuilds and redeploys the service's latest deployment.
+// POST /api/v1/services/:id/redeploy
+func (c *ServicesController) RedeployService(ctx *gin.Context) {
+	c.handleServiceAction(ctx, serviceActionRedeploy, "")
+}
+
+// RestartService restarts the service's latest deployment without rebuilding it.
+// POST /api/v1/services/:id/restart
+func (c *ServicesController) RestartService(ctx *gin.Context) {
+	c.handleServiceAction(ctx, serviceActionRestart, "")
+}
+
+// RollbackService makes one of the service's previous deployments active again.
+// POST /api/v1/services/:id/rollback
+// Request body: {"deploymentId": "..."}
+func (c *ServicesController) RollbackService(ctx *gin.Context) {
+	var req RollbackServiceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.handleServiceAction(ctx, serviceActionRollback, req.DeploymentID)
+}
+
+// handleServiceAction runs action for the service in the :id route param and writes the response.
+func (c *ServicesController) handleServiceAction(ctx *gin.Context, action, deploymentID string) {
+	svc, env, rwClient, ok := c.serviceActionTarget(ctx)
+	if !ok {
+		return
+	}
+
+	resp, err := runServiceAction(ctx, c.DB, rwClient, svc, env.RailwayEnvironmentID, action, deploymentID)
+	switch {
+	case errors.Is(err, errNoDeployments), errors.Is(err, errActionNotAllowed):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errDeploymentNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Error().Err(err).Str("service_id", svc.ID).Str("action", action).Msg("service action failed")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("failed to %s service", action), "message": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
+
+// serviceActionTarget loads the service in the :id route param for the current user, its
+// environment's Railway IDs and the user's Railway client, writing the error response on failure.
+func (c *ServicesController) serviceActionTarget(ctx *gin.Context) (store.Service, store.Environment, *railway.Client, bool) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return store.Service{}, store.Environment{}, nil, false
+	}
+	serviceID := ctx.Param("id")
+	if serviceID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "service id required"})
+		return store.Service{}, store.Environment{}, nil, false
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return store.Service{}, store.Environment{}, nil, false
+	}
+
+	// Query service with ownership check
+	var svc store.Service
+	err = c.DB.Where("id = ? AND user_id = ?", serviceID, user.ID).First(&svc).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "service not found"})
+		return store.Service{}, store.Environment{}, nil, false
+	} else if err != nil {
+		log.Error().Err(err).Str("service_id", serviceID).Msg("failed to query service")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve service"})
+		return store.Service{}, store.Environment{}, nil, false
+	}
+
+	var env store.Environment
+	err = c.DB.Select("id", "railway_project_id", "railway_environment_id").Where("id = ? AND user_id = ?", svc.EnvironmentID, user.ID).First(&env).Error
+	if err != nil {
+		log.Error().Err(err).Str("service_id", svc.ID).Str("env_id", svc.EnvironmentID).Msg("failed to load service environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve service environment"})
+		return store.Service{}, store.Environment{}, nil, false
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return store.Service{}, store.Environment{}, nil, false
+	}
+	return svc, env, rwClient, true
+}
+
+// runServiceAction applies action to the service's deployment in railwayEnvID: the latest one for
+// redeploy and restart, deploymentID for rollback. Railway's CanRedeploy and CanRollback flags are
+// checked first so invalid requests fail with a clear error instead of a GraphQL one. On success
+// the service is marked as creating until the status poller sees the new deployment settle.
+func runServiceAction(ctx context.Context, db *gorm.DB, rw serviceDeploymentClient, svc store.Service, railwayEnvID, action, deploymentID string) (ServiceActionResponse, error) {
+	history, err := rw.ListServiceDeployments(ctx, railway.ListServiceDeploymentsInput{
+		ServiceID:     svc.RailwayServiceID,
+		EnvironmentID: railwayEnvID,
+		Limit:         railway.MaxDeploymentHistory,
+	})
+	if err != nil {
+		return ServiceActionResponse{}, err
+	}
+
+	var target *railway.Deployment
+	if action == serviceActionRollback {
+		for i := range history {
+			if history[i].ID == deploymentID {
+				target = &history[i]
+				break
+			}
+		}
+		if target == nil {
+			return ServiceActionResponse{}, errDeploymentNotFound
+		}
+	} else {
+		if len(history) == 0 {
+			return ServiceActionResponse{}, errNoDeployments
+		}
+		target = &history[0]
+	}
+
+	resp := ServiceActionResponse{ServiceID: svc.ID, Action: action, DeploymentID: target.ID}
+	switch action {
+	case serviceActionRedeploy:
+		if !target.CanRedeploy {
+			return ServiceActionResponse{}, fmt.Errorf("%w: deployment %s cannot be redeployed", errActionNotAllowed, target.ID)
+		}
+		next, err := rw.RedeployDeployment(ctx, target.ID)
+		if err != nil {
+			return ServiceActionResponse{}, err
+		}
+		resp.NewDeploymentID = next.ID
+	case serviceActionRestart:
+		if err := rw.RestartDeployment(ctx, target.ID); err != nil {
+			return ServiceActionResponse{}, err
+		}
+	case serviceActionRollback:
+		if !target.CanRollback {
+			return ServiceActionResponse{}, fmt.Errorf("%w: deployment %s cannot be rolled back to", errActionNotAllowed, target.ID)
+		}
+		if err := rw.RollbackDeployment(ctx, target.ID); err != nil {
+			return ServiceActionResponse{}, err
+		}
+	default:
+		return ServiceActionResponse{}, fmt.Errorf("unknown service action %q", action)
+	}
+
+	log.Info().
+		Str("service_id", svc.ID).
+		Str("action", action).
+		Str("deployment_id", target.ID).
+		Str("new_deployment_id", resp.NewDeploymentID).
+		Msg("service action applied")
+
+	resp.Status = status.StatusCreating
+	if err := db.WithContext(ctx).Model(&store.Service{}).Where("id = ?", svc.ID).Update("status", resp.Status).Error; err != nil {
+		// Railway already accepted the action; the poller will correct the status
+		log.Warn().Err(err).Str("service_id", svc.ID).Msg("failed to update service status after action")
+	}
+	return resp, nil
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeDeploymentClient struct {
+	history    []railway.Deployment
+	listInput  railway.ListServiceDeploymentsInput
+	redeployed []string
+	restarted  []string
+	rolledBack []string
+}
+
+func (f *fakeDeploymentClient) ListServiceDeployments(ctx context.Context, in railway.ListServiceDeploymentsInput) ([]railway.Deployment, error) {
+	f.listInput = in
+	return f.history, nil
+}
+
+func (f *fakeDeploymentClient) RedeployDeployment(ctx context.Context, deploymentID string) (railway.Deployment, error) {
+	f.redeployed = append(f.redeployed, deploymentID)
+	return railway.Deployment{ID: "d-new"}, nil
+}
+
+func (f *fakeDeploymentClient) RestartDeployment(ctx context.Context, deploymentID string) error {
+	f.restarted = append(f.restarted, deploymentID)
+	return nil
+}
+
+func (f *fakeDeploymentClient) RollbackDeployment(ctx context.Context, deploymentID string) error {
+	f.rolledBack = append(f.rolledBack, deploymentID)
+	return nil
+}
+
+func newServiceActionFixture(t *testing.T) (*ServicesController, store.Service) {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	svc := store.Service{ID: "svc-1", UserID: "user-1", EnvironmentID: "env-1", Name: "api", Status: "active", RailwayServiceID: "rw-svc"}
+	require.NoError(t, db.Create(&svc).Error)
+	return &ServicesController{DB: db}, svc
+}
+
+func TestRunServiceAction_RedeploysLatestInEnvironment(t *testing.T) {
+	c, svc := newServiceActionFixture(t)
+	rw := &fakeDeploymentClient{history: []railway.Deployment{
+		{ID: "d2", CanRedeploy: true},
+		{ID: "d1", CanRollback: true},
+	}}
+
+	resp, err := runServiceAction(context.Background(), c.DB, rw, svc, "rw-env", serviceActionRedeploy, "")
+	require.NoError(t, err)
+
+	assert.Equal(t, railway.ListServiceDeploymentsInput{ServiceID: "rw-svc", EnvironmentID: "rw-env", Limit: railway.MaxDeploymentHistory}, rw.listInput)
+	assert.Equal(t, []string{"d2"}, rw.redeployed)
+	assert.Equal(t, ServiceActionResponse{ServiceID: "svc-1", Action: serviceActionRedeploy, DeploymentID: "d2", NewDeploymentID: "d-new", Status: "creating"}, resp)
+
+	var after store.Service
+	require.NoError(t, c.DB.First(&after, "id = ?", "svc-1").Error)
+	assert.Equal(t, "creating", after.Status)
+}
+
+func TestRunServiceAction_RollbackChecksTargetAndCapability(t *testing.T) {
+	c, svc := newServiceActionFixture(t)
+	rw := &fakeDeploymentClient{history: []railway.Deployment{
+		{ID: "d3"},
+		{ID: "d2"},
+		{ID: "d1", CanRollback: true},
+	}}
+
+	_, err := runServiceAction(context.Background(), c.DB, rw, svc, "rw-env", serviceActionRollback, "d-unknown")
+	assert.ErrorIs(t, err, errDeploymentNotFound)
+
+	_, err = runServiceAction(context.Background(), c.DB, rw, svc, "rw-env", serviceActionRollback, "d2")
+	assert.ErrorIs(t, err, errActionNotAllowed)
+	assert.Empty(t, rw.rolledBack)
+
+	resp, err := runServiceAction(context.Background(), c.DB, rw, svc, "rw-env", serviceActionRollback, "d1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d1"}, rw.rolledBack)
+	assert.Equal(t, "d1", resp.DeploymentID)
+}
+
+func TestRunServiceAction_RestartWithoutDeployments(t *testing.T) {
+	c, svc := newServiceActionFixture(t)
+	rw := &fakeDeploymentClient{}
+
+	_, err := runServiceAction(context.Background(), c.DB, rw, svc, "rw-env", serviceActionRestart, "")
+	assert.ErrorIs(t, err, errNoDeployments)
+	assert.Empty(t, rw.restarted)
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// railwaySharedNamespace is Railway's reference namespace for environment-level variables,
+// e.g. ${{shared.API_KEY}}. It names no service so it isn't linked or validated.
+const railwaySharedNamespace = "shared"
+
+// serviceReferencePattern matches Railway variable references such as ${{api.RAILWAY_PRIVATE_DOMAIN}},
+// tolerating whitespace inside the braces. Group 1 is the service name, group 2 the variable.
+var serviceReferencePattern = regexp.MustCompile(`\$\{\{\s*([^.{}\s]+)\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+var (
+	// errInvalidServiceReference is returned when a variable references its own service or one
+	// that is neither being provisioned nor already in the environment
+	errInvalidServiceReference = errors.New("invalid service variable reference")
+	// errServiceReferenceCycle is returned when services reference each other in a loop
+	errServiceReferenceCycle = errors.New("service variable references form a cycle")
+	// errDuplicateServiceName is returned when a request names two services the same, which
+	// would make references to them ambiguous
+	errDuplicateServiceName = errors.New("duplicate service name")
+)
+
+// isProvisionPlanError reports whether err rejects the request's services as planned, rather
+// than a failure to provision them.
+func isProvisionPlanError(err error) bool {
+	return errors.Is(err, errInvalidServiceReference) || errors.Is(err, errServiceReferenceCycle) || errors.Is(err, errDuplicateServiceName)
+}
+
+// ServiceLink records that one service's variable references another service. Value is the full
+// variable value in Railway's reference syntax so it can be replayed verbatim.
+type ServiceLink struct {
+	Service  string `json:"service"`
+	Variable string `json:"variable"`
+	Target   string `json:"target"`
+	Value    string `json:"value"`
+}
+
+// provisionPlan orders a provisioning request so referenced services are created first.
+type provisionPlan struct {
+	specs []ServiceSpec // Request specs with references normalized to Railway's syntax
+	waves [][]int       // Request indices; each wave only references earlier waves
+	deps  [][]int       // Request indices each service references
+	links []ServiceLink
+}
+
+// planServiceLinks validates the variable references in specs against the other specs and the
+// names of services already in the environment, then groups specs into creation waves.
+// Services in the same wave don't reference each other and can be created concurrently.
+func planServiceLinks(specs []ServiceSpec, existing map[string]bool) (provisionPlan, error) {
+	byName := make(map[string]int, len(specs))
+	for i, s := range specs {
+		if _, dup := byName[s.Name]; dup {
+			return provisionPlan{}, fmt.Errorf("%w: %s", errDuplicateServiceName, s.Name)
+		}
+		byName[s.Name] = i
+	}
+
+	plan := provisionPlan{specs: make([]ServiceSpec, len(specs)), deps: make([][]int, len(specs))}
+	for i, s := range specs {
+		plan.specs[i] = s
+		if len(s.EnvVars) == 0 {
+			continue
+		}
+		vars := make(map[string]string, len(s.EnvVars))
+		seen := make(map[int]bool)
+		for _, name := range sortedKeys(s.EnvVars) {
+			value, links := normalizeServiceReferences(s.Name, name, s.EnvVars[name])
+			vars[name] = value
+			for _, l := range links {
+				j, requested := byName[l.Target]
+				switch {
+				case l.Target == s.Name:
+					return provisionPlan{}, fmt.Errorf("%w: service %s variable %s references itself; use ${{VAR}} for its own variables", errInvalidServiceReference, s.Name, name)
+				case requested:
+					if !seen[j] {
+						seen[j] = true
+						plan.deps[i] = append(plan.deps[i], j)
+					}
+				case !existing[l.Target]:
+					return provisionPlan{}, fmt.Errorf("%w: service %s variable %s references %q", errInvalidServiceReference, s.Name, name, l.Target)
+				}
+				plan.links = append(plan.links, l)
+			}
+		}
+		plan.specs[i].EnvVars = vars
+	}
+
+	waves, err := referenceWaves(plan.deps)
+	if err != nil {
+		return provisionPlan{}, err
+	}
+	plan.waves = waves
+	return plan, nil
+}
+
+// referenceWaves groups nodes 0..len(deps)-1 into waves where every node's dependencies are in
+// earlier waves. Nodes keep their index order within a wave.
+func referenceWaves(deps [][]int) ([][]int, error) {
+	level := make([]int, len(deps))
+	state := make([]int, len(deps)) // 0 unvisited, 1 in progress, 2 done
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case 1:
+			return errServiceReferenceCycle
+		case 2:
+			return nil
+		}
+		state[i] = 1
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+			if level[j]+1 > level[i] {
+				level[i] = level[j] + 1
+			}
+		}
+		state[i] = 2
+		return nil
+	}
+
+	var waves [][]int
+	for i := range deps {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	for i, l := range level {
+		for len(waves) <= l {
+			waves = append(waves, nil)
+		}
+		waves[l] = append(waves[l], i)
+	}
+	return waves, nil
+}
+
+// normalizeServiceReferences rewrites the references in value to Railway's ${{service.VAR}} form
+// and returns one link per referenced service.
+func normalizeServiceReferences(service, variable, value string) (string, []ServiceLink) {
+	matches := serviceReferencePattern.FindAllStringSubmatch(value, -1)
+	if len(matches) == 0 {
+		return value, nil
+	}
+	normalized := serviceReferencePattern.ReplaceAllString(value, "$${{$1.$2}}")
+
+	var links []ServiceLink
+	targets := make(map[string]bool)
+	for _, m := range matches {
+		target := m[1]
+		if target == railwaySharedNamespace || targets[target] {
+			continue
+		}
+		targets[target] = true
+		links = append(links, ServiceLink{Service: service, Variable: variable, Target: target, Value: normalized})
+	}
+	return normalized, links
+}
+
+// environmentServiceNames returns the names of the services already recorded for an environment.
+func environmentServiceNames(ctx context.Context, db *gorm.DB, environmentID string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	if db == nil || environmentID == "" {
+		return names, nil
+	}
+	var services []store.Service
+	if err := db.WithContext(ctx).Select("name").Where("environment_id = ?", environmentID).Find(&services).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		names[s.Name] = true
+	}
+	return names, nil
+}
+
+// loadServiceLinks returns the service links recorded in an environment's metadata.
+func loadServiceLinks(ctx context.Context, db *gorm.DB, userID, environmentID string) ([]ServiceLink, error) {
+	var meta store.EnvironmentMetadata
+	err := db.WithContext(ctx).Select("service_links_json").Where("environment_id = ? AND user_id = ?", environmentID, userID).First(&meta).Error
+	if err == gorm.ErrRecordNotFound || (err == nil && len(meta.ServiceLinksJSON) == 0) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var links []ServiceLink
+	if err := json.Unmarshal(meta.ServiceLinksJSON, &links); err != nil {
+		return nil, fmt.Errorf("decode service links: %w", err)
+	}
+	return links, nil
+}
+
+// recordServiceLinks merges links into the environment's metadata, replacing earlier links of the
+// same service variables. Metadata is created when the environment has none yet.
+func recordServiceLinks(ctx context.Context, db *gorm.DB, userID, environmentID string, links []ServiceLink) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var meta store.EnvironmentMetadata
+		err := tx.Where("environment_id = ? AND user_id = ?", environmentID, userID).First(&meta).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		var merged []ServiceLink
+		if len(meta.ServiceLinksJSON) > 0 {
+			if err := json.Unmarshal(meta.ServiceLinksJSON, &merged); err != nil {
+				return fmt.Errorf("decode service links: %w", err)
+			}
+		}
+
+		replaced := make(map[[2]string]bool, len(links))
+		for _, l := range links {
+			replaced[[2]string{l.Service, l.Variable}] = true
+		}
+		kept := merged[:0]
+		for _, l := range merged {
+			if !replaced[[2]string{l.Service, l.Variable}] {
+				kept = append(kept, l)
+			}
+		}
+		linksJSON, err := json.Marshal(append(kept, links...))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if meta.ID == "" {
+			meta = store.EnvironmentMetadata{
+				ID:               uuid.New().String(),
+				UserID:           userID,
+				EnvironmentID:    environmentID,
+				ServiceLinksJSON: linksJSON,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			return tx.Create(&meta).Error
+		}
+		return tx.Model(&meta).Updates(map[string]interface{}{"service_links_json": linksJSON, "updated_at": now}).Error
+	})
+}
+
+// orderServicesByLinks returns services reordered so that each comes after the services its
+// links reference, keeping the original order otherwise. On a cycle the original order is kept.
+func orderServicesByLinks(services []store.Service, links []ServiceLink) []store.Service {
+	if len(links) == 0 {
+		return services
+	}
+	byName := make(map[string]int, len(services))
+	for i, s := range services {
+		byName[s.Name] = i
+	}
+	deps := make([][]int, len(services))
+	for _, l := range links {
+		i, ok := byName[l.Service]
+		j, tok := byName[l.Target]
+		if ok && tok && i != j {
+			deps[i] = append(deps[i], j)
+		}
+	}
+	waves, err := referenceWaves(deps)
+	if err != nil {
+		return services
+	}
+	ordered := make([]store.Service, 0, len(services))
+	for _, wave := range waves {
+		for _, i := range wave {
+			ordered = append(ordered, services[i])
+		}
+	}
+	return ordered
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func imageSpec(name string, vars map[string]string) ServiceSpec {
+	image := name
+	return ServiceSpec{Name: name, ImageName: &image, EnvVars: vars}
+}
+
+func TestPlanServiceLinks_WavesAndNormalization(t *testing.T) {
+	specs := []ServiceSpec{
+		imageSpec("web", map[string]string{"API_URL": "http://${{ api.RAILWAY_PRIVATE_DOMAIN }}:8080"}),
+		imageSpec("api", map[string]string{"DATABASE_URL": "${{postgres.DATABASE_URL}}", "KEY": "${{shared.API_KEY}}"}),
+		imageSpec("postgres", nil),
+		imageSpec("worker", map[string]string{"QUEUE": "${{redis.REDIS_URL}}"}),
+	}
+
+	plan, err := planServiceLinks(specs, map[string]bool{"redis": true})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]int{{2, 3}, {1}, {0}}, plan.waves)
+	assert.Equal(t, "http://${{api.RAILWAY_PRIVATE_DOMAIN}}:8080", plan.specs[0].EnvVars["API_URL"])
+	assert.Equal(t, "http://${{ api.RAILWAY_PRIVATE_DOMAIN }}:8080", specs[0].EnvVars["API_URL"], "request specs must not be modified")
+	assert.ElementsMatch(t, []ServiceLink{
+		{Service: "web", Variable: "API_URL", Target: "api", Value: "http://${{api.RAILWAY_PRIVATE_DOMAIN}}:8080"},
+		{Service: "api", Variable: "DATABASE_URL", Target: "postgres", Value: "${{postgres.DATABASE_URL}}"},
+		{Service: "worker", Variable: "QUEUE", Target: "redis", Value: "${{redis.REDIS_URL}}"},
+	}, plan.links)
+}
+
+func TestPlanServiceLinks_RejectsInvalidReferences(t *testing.T) {
+	_, err := planServiceLinks([]ServiceSpec{imageSpec("api", map[string]string{"DB": "${{postgres.DATABASE_URL}}"})}, nil)
+	assert.ErrorIs(t, err, errInvalidServiceReference)
+
+	_, err = planServiceLinks([]ServiceSpec{imageSpec("api", map[string]string{"SELF": "${{api.PORT}}"})}, nil)
+	assert.ErrorIs(t, err, errInvalidServiceReference)
+
+	_, err = planServiceLinks([]ServiceSpec{
+		imageSpec("a", map[string]string{"B": "${{b.URL}}"}),
+		imageSpec("b", map[string]string{"A": "${{a.URL}}"}),
+	}, nil)
+	assert.ErrorIs(t, err, errServiceReferenceCycle)
+
+	_, err = planServiceLinks([]ServiceSpec{imageSpec("api", nil), imageSpec("api", nil)}, nil)
+	assert.ErrorIs(t, err, errDuplicateServiceName)
+}
+
+func TestProvisionServiceLinks_CreatesTargetsFirstAndRecordsLinks(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	var apiVars map[string]string
+	rw := &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, in.Name)
+			if in.Name == "api" {
+				apiVars = in.Variables
+			}
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+	}
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 4
+	specs := []ServiceSpec{
+		imageSpec("api", map[string]string{"DATABASE_URL": "${{ postgres.DATABASE_URL }}"}),
+		imageSpec("postgres", nil),
+	}
+
+	_, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{EnvironmentID: "env-1", Services: specs}, "rw-env-1", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"postgres", "api"}, order)
+	assert.Equal(t, "${{postgres.DATABASE_URL}}", apiVars["DATABASE_URL"])
+	links, err := loadServiceLinks(context.Background(), c.DB, "user-1", "env-1")
+	require.NoError(t, err)
+	assert.Equal(t, []ServiceLink{{Service: "api", Variable: "DATABASE_URL", Target: "postgres", Value: "${{postgres.DATABASE_URL}}"}}, links)
+}
+
+func TestProvisionServiceLinks_SkipsServicesReferencingFailedOnes(t *testing.T) {
+	rw := &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			if in.Name == "postgres" {
+				return railway.CreateServiceResult{}, errors.New("quota exceeded")
+			}
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+	}
+	c := newProvisioningController(t, rw)
+	specs := []ServiceSpec{
+		imageSpec("api", map[string]string{"DATABASE_URL": "${{postgres.DATABASE_URL}}"}),
+		imageSpec("postgres", nil),
+		imageSpec("cache", nil),
+	}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{EnvironmentID: "env-1", Services: specs}, "rw-env-1", nil)
+	require.Error(t, err)
+	assert.Equal(t, serviceProvisionSkipped, results[0].Status)
+	assert.Contains(t, results[0].Error, "postgres")
+	assert.Equal(t, serviceProvisionFailed, results[1].Status)
+	assert.Equal(t, serviceProvisionCreated, results[2].Status)
+}
+
+func TestProvisionServiceLinks_AtomicRollbackDropsLinks(t *testing.T) {
+	var destroyed []string
+	rw := failingOn("worker", &destroyed)
+	c := newProvisioningController(t, rw)
+	specs := []ServiceSpec{
+		imageSpec("postgres", nil),
+		imageSpec("api", map[string]string{"DATABASE_URL": "${{postgres.DATABASE_URL}}"}),
+		imageSpec("worker", map[string]string{"API_URL": "${{api.RAILWAY_PRIVATE_DOMAIN}}"}),
+	}
+
+	_, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{EnvironmentID: "env-1", Services: specs, Atomic: true}, "rw-env-1", nil)
+	require.Error(t, err)
+
+	assert.Equal(t, []string{"rw-api", "rw-postgres"}, destroyed)
+	links, err := loadServiceLinks(context.Background(), c.DB, "user-1", "env-1")
+	require.NoError(t, err)
+	assert.Empty(t, links, "rolled back services keep no links")
+}
+
+func TestCloneEnvironment_ReplaysServiceLinks(t *testing.T) {
+	c, source := seedCloneSource(t)
+	links := []ServiceLink{{Service: "api", Variable: "REDIS_URL", Target: "cache", Value: "${{cache.REDIS_URL}}"}}
+	require.NoError(t, recordServiceLinks(context.Background(), c.DB, "user-1", source.ID, links))
+	rw := &fakeEnvironmentCloner{vars: railway.GetAllEnvironmentAndServiceVariablesResult{
+		ServiceVariables: []railway.ServiceVariables{
+			{ServiceID: "rw-svc-api", ServiceName: "api", Variables: map[string]string{"REDIS_URL": "redis://cache.railway.internal:6379"}},
+		},
+	}}
+
+	result, err := c.cloneEnvironment(context.Background(), rw, "user-1", source, cloneOptions{Name: "feature-x"})
+	require.NoError(t, err)
+
+	require.Len(t, rw.serviceInputs, 2)
+	assert.Equal(t, "cache", rw.serviceInputs[0].Name, "referenced service is created first")
+	assert.Equal(t, "${{cache.REDIS_URL}}", rw.serviceInputs[1].Variables["REDIS_URL"])
+
+	cloned, err := loadServiceLinks(context.Background(), c.DB, "user-1", result.EnvironmentID)
+	require.NoError(t, err)
+	assert.Equal(t, links, cloned)
+}
+
+func TestRecordServiceLinks_ReplacesSameVariable(t *testing.T) {
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, recordServiceLinks(ctx, db, "user-1", "env-1", []ServiceLink{
+		{Service: "api", Variable: "DB", Target: "pg", Value: "${{pg.URL}}"},
+		{Service: "web", Variable: "API", Target: "api", Value: "${{api.URL}}"},
+	}))
+	require.NoError(t, recordServiceLinks(ctx, db, "user-1", "env-1", []ServiceLink{
+		{Service: "api", Variable: "DB", Target: "pg2", Value: "${{pg2.URL}}"},
+	}))
+
+	links, err := loadServiceLinks(ctx, db, "user-1", "env-1")
+	require.NoError(t, err)
+	assert.Equal(t, []ServiceLink{
+		{Service: "web", Variable: "API", Target: "api", Value: "${{api.URL}}"},
+		{Service: "api", Variable: "DB", Target: "pg2", Value: "${{pg2.URL}}"},
+	}, links)
+}
@@ -3,8 +3,13 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -17,10 +22,15 @@ import (
 	"gorm.io/gorm"
 )
 
+// railwayDockerfilePathVar is the Railway system variable that selects the Dockerfile for a build.
+const railwayDockerfilePathVar = "RAILWAY_DOCKERFILE_PATH"
+
 // RailwayServiceClient defines the interface for Railway service operations needed by the controller.
 type RailwayServiceClient interface {
 	CreateService(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error)
 	DestroyService(ctx context.Context, in railway.DestroyServiceInput) error
+	CreateServiceDomain(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error)
+	volumeClient
 }
 
 // ServicesController handles Railway service provisioning endpoints.
@@ -28,12 +38,27 @@ type ServicesController struct {
 	Railway RailwayServiceClient
 	DB      *gorm.DB
 	Vault   *vault.Client
+	// IdempotencyRetention is how long provisioning responses are kept for replay (default 24h)
+	IdempotencyRetention time.Duration
+	// Concurrency bounds how many services are created in Railway at once (default DefaultProvisionConcurrency)
+	Concurrency int
 }
 
+// DefaultProvisionConcurrency is the number of services created in parallel when no limit is configured.
+const DefaultProvisionConcurrency = 4
+
 // RegisterRoutes registers service-related routes under the provided router group.
 func (c *ServicesController) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/provision/services", c.ProvisionServices)
 	r.GET("/services/:id", c.GetService)
+	r.GET("/services/:id/deployments", c.ListServiceDeployments)
+	r.POST("/services/:id/redeploy", c.RedeployService)
+	r.POST("/services/:id/restart", c.RestartService)
+	r.POST("/services/:id/rollback", c.RollbackService)
+	r.POST("/services/:id/domains", c.AddServiceDomain)
+	r.PUT("/services/:id/variables", c.PutServiceVariables)
+	r.PATCH("/services/:id/variables", c.PatchServiceVariables)
+	r.DELETE("/services/:id/variables", c.DeleteServiceVariables)
 	r.DELETE("/railway/service/:id", c.DeleteRailwayService)
 }
 
@@ -58,6 +83,16 @@ type ServiceSpec struct {
 
 	// Dockerfile path for monorepo builds (optional, relative to repo root)
 	DockerfilePath *string `json:"dockerfilePath,omitempty"`
+
+	// Database template deployment (replaces repo and image fields)
+	Database *DatabaseSpec `json:"database,omitempty"`
+
+	// Persistent volumes (optional)
+	Volumes []VolumeSpec `json:"volumes,omitempty"`
+
+	// Networking (optional)
+	ExposedPorts   []int `json:"exposedPorts,omitempty"`   // Ports the service listens on
+	GenerateDomain bool  `json:"generateDomain,omitempty"` // Create a Railway domain routed to the first exposed port
 }
 
 // ProvisionServicesRequest creates one or more services in a given environment.
@@ -67,14 +102,17 @@ type ProvisionServicesRequest struct {
 	RailwayEnvironmentID string        `json:"railwayEnvironmentId"` // Railway ID for Railway API calls
 	Services             []ServiceSpec `json:"services"`
 	RequestID            string        `json:"requestId"`
+	Atomic               bool          `json:"atomic,omitempty"` // Optional: destroy already-created services when one fails
 }
 
 type ProvisionServicesResponse struct {
-	ServiceIDs []string `json:"serviceIds"`
+	ServiceIDs []string                 `json:"serviceIds"`
+	Services   []ServiceProvisionResult `json:"services"` // Per-service outcome in request order
 }
 
-// ProvisionServices creates services sequentially and returns their IDs.
+// ProvisionServices creates services concurrently and returns their IDs in request order.
 // Supports both repository-based and Docker image-based deployments.
+// With atomic set, a failure destroys the services already created instead of leaving them behind.
 func (c *ServicesController) ProvisionServices(ctx *gin.Context) {
 	if c.Railway == nil {
 		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
@@ -125,6 +163,23 @@ func (c *ServicesController) ProvisionServices(ctx *gin.Context) {
 		}
 	}
 
+	finish, ok := beginIdempotentRequest(ctx, c.DB, c.IdempotencyRetention, user.ID, idempotencyScopeServices, req.RequestID, req)
+	if !ok {
+		return
+	}
+	defer finish()
+
+	// Load environment secrets from Vault so sensitive values never travel in the request body
+	var envSecrets map[string]string
+	if c.Vault != nil && req.EnvironmentID != "" {
+		envSecrets, err = c.Vault.GetAllEnvironmentSecrets(ctx, user.ID, req.EnvironmentID)
+		if err != nil {
+			log.Error().Err(err).Str("environment_id", req.EnvironmentID).Msg("failed to load environment secrets")
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "failed to load environment secrets"})
+			return
+		}
+	}
+
 	// Validate each service has either repo OR image fields
 	for i, s := range req.Services {
 		if err := validateServiceSpec(s); err != nil {
@@ -147,112 +202,469 @@ func (c *ServicesController) ProvisionServices(ctx *gin.Context) {
 			Msg("RailwayEnvironmentID not provided, using EnvironmentID for Railway API (may fail if it's a Mirage ID)")
 	}
 
-	ids := make([]string, 0, len(req.Services))
-	for _, s := range req.Services {
-		input := railway.CreateServiceInput{
-			ProjectID:     req.ProjectID,
-			EnvironmentID: railwayEnvID, // Use Railway environment ID for Railway API
-			Name:          s.Name,
+	results, err := c.provisionServices(ctx, rwClient, user.ID, req, railwayEnvID, envSecrets)
+	if err != nil {
+		if isProvisionPlanError(err) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var perr *serviceProvisionError
+		if !errors.As(err, &perr) {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+			return
+		}
+		partial := createdServiceIDs(results)
+		body := gin.H{
+			"error":    perr.Err.Error(),
+			"service":  perr.Service,
+			"partial":  partial,
+			"services": results,
 		}
+		if req.Atomic {
+			body["rolledBack"] = len(perr.Orphaned) == 0
+		}
+		if len(perr.Orphaned) > 0 {
+			body["orphaned"] = perr.Orphaned
+		}
+		if len(partial) > 0 || len(perr.Orphaned) > 0 {
+			// A retry would create the services that exist again, so replay this outcome instead
+			keepIdempotentResponse(ctx)
+		}
+		ctx.JSON(http.StatusBadGateway, body)
+		return
+	}
+	ctx.JSON(http.StatusOK, ProvisionServicesResponse{ServiceIDs: createdServiceIDs(results), Services: results})
+}
+
+// Per-service provisioning outcomes reported in ServiceProvisionResult.Status
+const (
+	serviceProvisionCreated    = "created"
+	serviceProvisionFailed     = "failed"
+	serviceProvisionSkipped    = "skipped"     // Not attempted because an atomic request already failed
+	serviceProvisionRolledBack = "rolled_back" // Created, then destroyed by an atomic rollback
+	serviceProvisionOrphaned   = "orphaned"    // Created, but could not be destroyed during cleanup
+)
+
+// ServiceProvisionResult reports what happened to one requested service.
+type ServiceProvisionResult struct {
+	Name             string `json:"name"`
+	Status           string `json:"status"`
+	RailwayServiceID string `json:"railwayServiceId,omitempty"`
+	ServiceID        string `json:"serviceId,omitempty"` // Mirage ID, empty when not persisted
+	Domain           string `json:"domain,omitempty"`    // Generated Railway domain, when requested and created
+	Error            string `json:"error,omitempty"`
+
+	imageAuthStored bool
+	volumes         []railway.Volume
+}
+
+// serviceProvisionError reports the first service (in request order) that failed provisioning
+// and any Railway services that could not be cleaned up afterwards.
+type serviceProvisionError struct {
+	Service  string
+	Err      error
+	Orphaned []string // Railway service IDs that could not be destroyed
+}
 
-		// Configure based on deployment type
-		if s.ImageName != nil {
-			// Docker image deployment
-			imageRef := buildImageReference(s)
-			input.Image = &imageRef
+func (e *serviceProvisionError) Error() string {
+	return fmt.Sprintf("provision service %s: %v", e.Service, e.Err)
+}
 
-			// Add registry credentials if provided
-			if s.RegistryUsername != nil && s.RegistryPassword != nil {
-				input.RegistryCredentials = &railway.RegistryCredentials{
-					Username: *s.RegistryUsername,
-					Password: *s.RegistryPassword,
+func (e *serviceProvisionError) Unwrap() error { return e.Err }
+
+// provisionServices creates the requested services in Railway concurrently, bounded by the
+// controller's concurrency limit, then persists them in request order. Services referencing
+// others through ${{service.VAR}} variables are created in a later wave than their targets and
+// skipped if a target failed. A Railway service whose
+// database row can't be written is destroyed so Railway and the database stay in sync. Results
+// are returned in request order; in atomic mode any failure stops new creations and destroys
+// every service already created.
+func (c *ServicesController) provisionServices(ctx context.Context, rw RailwayServiceClient, userID string, req ProvisionServicesRequest, railwayEnvID string, envSecrets map[string]string) ([]ServiceProvisionResult, error) {
+	existing, err := environmentServiceNames(ctx, c.DB, req.EnvironmentID)
+	if err != nil {
+		return nil, fmt.Errorf("load environment services: %w", err)
+	}
+	plan, err := planProvisioning(req.Services, existing)
+	if err != nil {
+		return nil, err
+	}
+	req.Services = plan.specs
+
+	results := make([]ServiceProvisionResult, len(req.Services))
+	errs := make([]error, len(req.Services))
+	var aborted atomic.Bool
+
+	// Waves run one after another so referenced services exist before the services that use them
+	for _, wave := range plan.waves {
+		limit := c.Concurrency
+		if limit <= 0 {
+			limit = DefaultProvisionConcurrency
+		}
+		if limit > len(wave) {
+			limit = len(wave)
+		}
+
+		// Workers take services in request order, so a limit of 1 behaves like a sequential loop
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < limit; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					s := req.Services[i]
+					results[i] = ServiceProvisionResult{Name: s.Name}
+					if req.Atomic && aborted.Load() {
+						results[i].Status = serviceProvisionSkipped
+						continue
+					}
+					if dep := failedDependency(plan.deps[i], results); dep != "" {
+						results[i].Status = serviceProvisionSkipped
+						results[i].Error = fmt.Sprintf("referenced service %s was not created", dep)
+						continue
+					}
+					railwayServiceID, imageAuthStored, err := c.createRailwayService(ctx, rw, userID, s, req.ProjectID, railwayEnvID, envSecrets)
+					if err != nil {
+						aborted.Store(true)
+						results[i].Status = serviceProvisionFailed
+						results[i].Error = err.Error()
+						errs[i] = err
+						continue
+					}
+					volumes, err := createServiceVolumes(ctx, rw, s, req.ProjectID, railwayEnvID, railwayServiceID)
+					if err != nil {
+						// A stateful service without its volumes would lose data, so don't keep it
+						aborted.Store(true)
+						errs[i] = fmt.Errorf("create volumes: %w", err)
+						results[i].Status = serviceProvisionFailed
+						results[i].Error = errs[i].Error()
+						if derr := destroyRailwayService(ctx, rw, railwayServiceID); derr != nil {
+							results[i].Status = serviceProvisionOrphaned
+							results[i].RailwayServiceID = railwayServiceID
+						}
+						continue
+					}
+					results[i].Status = serviceProvisionCreated
+					results[i].RailwayServiceID = railwayServiceID
+					results[i].imageAuthStored = imageAuthStored
+					results[i].volumes = volumes
+					if s.GenerateDomain {
+						results[i].Domain = generateServiceDomain(ctx, rw, s, railwayEnvID, railwayServiceID)
+					}
 				}
-			}
-		} else {
-			// Repository-based deployment
-			input.Repo = s.Repo
-			input.Branch = s.Branch
+			}()
+		}
+		for _, i := range wave {
+			next <- i
 		}
+		close(next)
+		wg.Wait()
+	}
 
-		// Merge user-specified environment variables
-		// These are added first so system variables can override them if needed
-		if len(s.EnvVars) > 0 {
-			if input.Variables == nil {
-				input.Variables = make(map[string]string)
-			}
-			for k, v := range s.EnvVars {
-				input.Variables[k] = v
+	// Persist sequentially so database writes stay ordered and single-threaded
+	var orphaned []string
+	for _, r := range results {
+		if r.Status == serviceProvisionOrphaned {
+			orphaned = append(orphaned, r.RailwayServiceID)
+		}
+	}
+	for i, s := range req.Services {
+		if results[i].Status != serviceProvisionCreated || c.DB == nil {
+			continue
+		}
+		serviceID, err := c.persistProvisionedService(userID, s, req.EnvironmentID, results[i])
+		if err != nil {
+			log.Error().Err(err).
+				Str("service_name", s.Name).
+				Str("railway_service_id", results[i].RailwayServiceID).
+				Msg("failed to persist service after Railway service creation, destroying it")
+			// The Railway service has no matching row, so reconcile it regardless of mode
+			errs[i] = fmt.Errorf("persist service: %w", err)
+			results[i].Error = errs[i].Error()
+			results[i].Status = serviceProvisionFailed
+			if derr := destroyRailwayService(ctx, rw, results[i].RailwayServiceID); derr != nil {
+				results[i].Status = serviceProvisionOrphaned
+				orphaned = append(orphaned, results[i].RailwayServiceID)
+			} else {
+				deleteRailwayVolumes(ctx, rw, results[i].volumes)
 			}
-			log.Debug().
-				Str("service", s.Name).
-				Int("env_var_count", len(s.EnvVars)).
-				Msg("adding user-specified environment variables")
+			continue
 		}
+		results[i].ServiceID = serviceID
+	}
 
-		// Set system variables (these override user variables if there's a conflict)
-		// Set Dockerfile path if specified (repository deployments only)
-		if s.DockerfilePath != nil && *s.DockerfilePath != "" {
-			if input.Variables == nil {
-				input.Variables = make(map[string]string)
-			}
-			input.Variables["RAILWAY_DOCKERFILE_PATH"] = *s.DockerfilePath
-			log.Info().
-				Str("service", s.Name).
-				Str("dockerfile_path", *s.DockerfilePath).
-				Msg("setting RAILWAY_DOCKERFILE_PATH system variable for service")
+	var perr *serviceProvisionError
+	for i, err := range errs {
+		if err != nil {
+			perr = &serviceProvisionError{Service: req.Services[i].Name, Err: err}
+			break
 		}
+	}
+	if perr != nil && req.Atomic {
+		orphaned = append(orphaned, c.rollbackProvisionedServices(ctx, rw, results)...)
+		log.Warn().
+			Str("failed_service", perr.Service).
+			Strs("orphaned", orphaned).
+			Msg("rolled back service provisioning")
+	}
+	// Only services still created after any rollback keep their links
+	c.recordProvisionedLinks(ctx, userID, req.EnvironmentID, plan.links, results)
+	if perr == nil {
+		return results, nil
+	}
+	perr.Orphaned = orphaned
+	return results, perr
+}
 
-		// Log final variable set if any variables are present
-		if len(input.Variables) > 0 {
-			log.Debug().
-				Str("service", s.Name).
-				Interface("variables", input.Variables).
-				Msg("creating service with merged variables")
+// failedDependency returns the name of the first service in deps that wasn't created, or "".
+func failedDependency(deps []int, results []ServiceProvisionResult) string {
+	for _, j := range deps {
+		if results[j].Status != serviceProvisionCreated {
+			return results[j].Name
 		}
+	}
+	return ""
+}
 
-		out, err := rwClient.CreateService(ctx, input)
-		if err != nil {
-			ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "service": s.Name, "partial": ids})
-			return
+// recordProvisionedLinks stores the links of the services that were created in the environment's
+// metadata so clones can replay the references. A failure only loses the links, not the services.
+func (c *ServicesController) recordProvisionedLinks(ctx context.Context, userID, environmentID string, links []ServiceLink, results []ServiceProvisionResult) {
+	if c.DB == nil || len(links) == 0 {
+		return
+	}
+	created := make(map[string]bool, len(results))
+	for _, r := range results {
+		if r.Status == serviceProvisionCreated {
+			created[r.Name] = true
+		}
+	}
+	var kept []ServiceLink
+	for _, l := range links {
+		if created[l.Service] {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) == 0 {
+		return
+	}
+	if err := recordServiceLinks(ctx, c.DB, userID, environmentID, kept); err != nil {
+		log.Warn().Err(err).Str("environment_id", environmentID).Msg("failed to record service links")
+	}
+}
+
+// createRailwayService creates a single service in Railway, falling back to registry
+// credentials stored in Vault when none were provided inline.
+func (c *ServicesController) createRailwayService(ctx context.Context, rw RailwayServiceClient, userID string, s ServiceSpec, projectID, railwayEnvID string, envSecrets map[string]string) (string, bool, error) {
+	input := buildServiceInput(s, projectID, railwayEnvID, envSecrets)
+	imageAuthStored := false
+	if input.Image != nil && input.RegistryCredentials == nil {
+		if creds := c.lookupStoredRegistryCredentials(ctx, userID, s); creds != nil {
+			input.RegistryCredentials = creds
+			imageAuthStored = true
+		}
+	}
+
+	out, err := rw.CreateService(ctx, input)
+	if err != nil {
+		return "", false, err
+	}
+	return out.ServiceID, imageAuthStored, nil
+}
+
+// generateServiceDomain creates a Railway domain routed to the service's first exposed port and
+// returns its hostname. A failed domain doesn't fail the service, which is already running; the
+// domain can be created later via POST /services/:id/domains.
+func generateServiceDomain(ctx context.Context, rw RailwayServiceClient, s ServiceSpec, railwayEnvID, railwayServiceID string) string {
+	port := s.ExposedPorts[0]
+	domain, err := rw.CreateServiceDomain(ctx, railway.CreateServiceDomainInput{
+		EnvironmentID: railwayEnvID,
+		ServiceID:     railwayServiceID,
+		TargetPort:    &port,
+	})
+	if err != nil {
+		log.Warn().Err(err).
+			Str("service_name", s.Name).
+			Str("railway_service_id", railwayServiceID).
+			Msg("failed to generate domain for provisioned service")
+		return ""
+	}
+	return domain.Domain
+}
+
+// persistProvisionedService stores a service created in Railway with the caller's ownership.
+// If the drift reconciler already adopted the service while it was being provisioned, the
+// adopted row is replaced so the service is recorded once, with its full configuration.
+func (c *ServicesController) persistProvisionedService(userID string, s ServiceSpec, environmentID string, result ServiceProvisionResult) (string, error) {
+	serviceModel, err := serviceSpecToModel(s, environmentID, result.RailwayServiceID)
+	if err != nil {
+		return "", err
+	}
+	serviceModel.UserID = userID
+	serviceModel.ImageAuthStored = result.imageAuthStored
+	serviceModel.Volumes = volumeModels(userID, result.volumes)
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		adopted := tx.Where("environment_id = ? AND railway_service_id = ?", environmentID, result.RailwayServiceID).Delete(&store.Service{})
+		if adopted.Error != nil {
+			return adopted.Error
 		}
-		ids = append(ids, out.ServiceID)
-
-		// Persist service to database with UserID
-		if c.DB != nil {
-			serviceModel, err := serviceSpecToModel(s, req.EnvironmentID, out.ServiceID)
-			if err != nil {
-				log.Error().Err(err).
-					Str("service_name", s.Name).
-					Str("railway_service_id", out.ServiceID).
-					Msg("failed to convert service spec to model")
-				// Continue - Railway service was created successfully
-				continue
+		if adopted.RowsAffected > 0 {
+			log.Info().Str("railway_service_id", result.RailwayServiceID).Msg("replacing service adopted during provisioning")
+		}
+		return tx.Create(&serviceModel).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	log.Info().
+		Str("service_id", serviceModel.ID).
+		Str("service_name", serviceModel.Name).
+		Str("railway_service_id", result.RailwayServiceID).
+		Str("user_id", userID).
+		Str("deployment_type", string(serviceModel.DeploymentType)).
+		Msg("persisted service to database with ownership")
+	return serviceModel.ID, nil
+}
+
+// rollbackProvisionedServices destroys every created service in reverse request order and
+// returns the Railway IDs that could not be destroyed. Those keep their database rows so they
+// can still be found and deleted later.
+func (c *ServicesController) rollbackProvisionedServices(ctx context.Context, rw RailwayServiceClient, results []ServiceProvisionResult) []string {
+	var orphaned []string
+	for i := len(results) - 1; i >= 0; i-- {
+		r := &results[i]
+		if r.Status != serviceProvisionCreated {
+			continue
+		}
+		if err := destroyRailwayService(ctx, rw, r.RailwayServiceID); err != nil {
+			r.Status = serviceProvisionOrphaned
+			orphaned = append(orphaned, r.RailwayServiceID)
+			continue
+		}
+		r.Status = serviceProvisionRolledBack
+		deleteRailwayVolumes(ctx, rw, r.volumes)
+		if c.DB != nil && r.ServiceID != "" {
+			if err := c.DB.Where("service_id = ?", r.ServiceID).Delete(&store.Volume{}).Error; err != nil {
+				log.Error().Err(err).Str("service_id", r.ServiceID).Msg("failed to delete volumes of rolled back service")
 			}
+			if err := c.DB.Where("id = ?", r.ServiceID).Delete(&store.Service{}).Error; err != nil {
+				log.Error().Err(err).Str("service_id", r.ServiceID).Msg("failed to delete rolled back service")
+			}
+			r.ServiceID = ""
+		}
+	}
+	return orphaned
+}
 
-			// Set UserID from authenticated user
-			serviceModel.UserID = user.ID
+// destroyRailwayService removes a service during cleanup. Cleanup must finish even if the
+// request that triggered it was cancelled.
+func destroyRailwayService(ctx context.Context, rw RailwayServiceClient, railwayServiceID string) error {
+	err := rw.DestroyService(context.WithoutCancel(ctx), railway.DestroyServiceInput{ServiceID: railwayServiceID})
+	if err != nil {
+		log.Error().Err(err).Str("railway_service_id", railwayServiceID).Msg("failed to destroy railway service during cleanup")
+	}
+	return err
+}
 
-			if err := c.DB.Create(&serviceModel).Error; err != nil {
-				log.Error().Err(err).
-					Str("service_name", s.Name).
-					Str("railway_service_id", out.ServiceID).
-					Msg("failed to persist service to database after Railway service creation")
-				// Don't fail the request - Railway service was created successfully
-			} else {
-				log.Info().
-					Str("service_id", serviceModel.ID).
-					Str("service_name", serviceModel.Name).
-					Str("railway_service_id", out.ServiceID).
-					Str("user_id", user.ID).
-					Str("deployment_type", string(serviceModel.DeploymentType)).
-					Msg("persisted service to database with ownership")
+// createdServiceIDs returns, in request order, the Railway IDs of services that were created and kept.
+func createdServiceIDs(results []ServiceProvisionResult) []string {
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		if r.Status == serviceProvisionCreated {
+			ids = append(ids, r.RailwayServiceID)
+		}
+	}
+	return ids
+}
+
+// buildServiceInput builds the Railway input that creates spec in an environment.
+// Variables are merged in increasing precedence: baseVars (e.g. environment secrets),
+// the spec's own variables, then system variables derived from the spec.
+func buildServiceInput(s ServiceSpec, projectID, railwayEnvID string, baseVars map[string]string) railway.CreateServiceInput {
+	input := railway.CreateServiceInput{
+		ProjectID:     projectID,
+		EnvironmentID: railwayEnvID, // Use Railway environment ID for Railway API
+		Name:          s.Name,
+	}
+
+	// Configure based on deployment type
+	if s.ImageName != nil {
+		// Docker image deployment
+		imageRef := buildImageReference(s)
+		input.Image = &imageRef
+
+		// Add registry credentials if provided
+		if s.RegistryUsername != nil && s.RegistryPassword != nil {
+			input.RegistryCredentials = &railway.RegistryCredentials{
+				Username: *s.RegistryUsername,
+				Password: *s.RegistryPassword,
 			}
 		}
+	} else {
+		// Repository-based deployment
+		input.Repo = s.Repo
+		input.Branch = s.Branch
+	}
+
+	// Merge base variables first so per-service variables can override them. Databases only get
+	// their template variables, keeping application secrets out of the database container.
+	if len(baseVars) > 0 && s.Database == nil {
+		input.Variables = make(map[string]string, len(baseVars))
+		for k, v := range baseVars {
+			input.Variables[k] = v
+		}
+		log.Debug().
+			Str("service", s.Name).
+			Int("secret_count", len(baseVars)).
+			Msg("adding environment secrets")
+	}
+
+	// Merge user-specified environment variables
+	// These are added before system variables so system variables can override them if needed
+	if len(s.EnvVars) > 0 {
+		if input.Variables == nil {
+			input.Variables = make(map[string]string)
+		}
+		for k, v := range s.EnvVars {
+			input.Variables[k] = v
+		}
+		log.Debug().
+			Str("service", s.Name).
+			Int("env_var_count", len(s.EnvVars)).
+			Msg("adding user-specified environment variables")
+	}
+
+	// Set system variables (these override user variables if there's a conflict)
+	// Set Dockerfile path if specified (repository deployments only)
+	if s.DockerfilePath != nil && *s.DockerfilePath != "" {
+		if input.Variables == nil {
+			input.Variables = make(map[string]string)
+		}
+		input.Variables[railwayDockerfilePathVar] = *s.DockerfilePath
+		log.Info().
+			Str("service", s.Name).
+			Str("dockerfile_path", *s.DockerfilePath).
+			Msg("setting RAILWAY_DOCKERFILE_PATH system variable for service")
 	}
-	ctx.JSON(http.StatusOK, ProvisionServicesResponse{ServiceIDs: ids})
+
+	// Log final variable names if any variables are present (values may contain secrets)
+	if len(input.Variables) > 0 {
+		log.Debug().
+			Str("service", s.Name).
+			Strs("variables", variableNames(input.Variables)).
+			Msg("creating service with merged variables")
+	}
+
+	return input
 }
 
-// validateServiceSpec ensures a service has either repo OR image configuration, not both or neither.
+// Valid TCP port range for exposed ports
+const (
+	minPort = 1
+	maxPort = 65535
+)
+
+// validateServiceSpec ensures a service has exactly one of repo, image or database configuration.
 func validateServiceSpec(s ServiceSpec) error {
 	hasRepo := s.Repo != nil && *s.Repo != ""
 	hasImage := s.ImageName != nil && *s.ImageName != ""
@@ -261,8 +673,15 @@ func validateServiceSpec(s ServiceSpec) error {
 		return fmt.Errorf("service '%s': cannot specify both repository and image deployment options", s.Name)
 	}
 
-	if !hasRepo && !hasImage {
-		return fmt.Errorf("service '%s': must specify either repository (repo+branch) or image (imageName) deployment", s.Name)
+	if s.Database != nil {
+		if hasRepo || hasImage || s.RegistryUsername != nil || s.RegistryPassword != nil {
+			return fmt.Errorf("service '%s': cannot specify both database and repository or image deployment options", s.Name)
+		}
+		if _, err := lookupDatabaseTemplate(s.Database.Engine); err != nil {
+			return fmt.Errorf("service '%s': %w", s.Name, err)
+		}
+	} else if !hasRepo && !hasImage {
+		return fmt.Errorf("service '%s': must specify either repository (repo+branch), image (imageName) or database deployment", s.Name)
 	}
 
 	// Additional validation for repo deployment
@@ -276,6 +695,18 @@ func validateServiceSpec(s ServiceSpec) error {
 		return fmt.Errorf("service '%s': both registryUsername and registryPassword must be provided together", s.Name)
 	}
 
+	for _, port := range s.ExposedPorts {
+		if port < minPort || port > maxPort {
+			return fmt.Errorf("service '%s': exposed port %d must be between %d and %d", s.Name, port, minPort, maxPort)
+		}
+	}
+	if s.GenerateDomain && len(s.ExposedPorts) == 0 {
+		return fmt.Errorf("service '%s': generateDomain requires at least one exposed port", s.Name)
+	}
+	if err := validateVolumeSpecs(s.Volumes); err != nil {
+		return fmt.Errorf("service '%s': %w", s.Name, err)
+	}
+
 	// Validate Dockerfile path if provided (only valid for repo deployments)
 	if s.DockerfilePath != nil && *s.DockerfilePath != "" {
 		if !hasRepo {
@@ -314,6 +745,68 @@ func buildImageReference(s ServiceSpec) string {
 	return imageRef
 }
 
+// variableNames returns the sorted keys of a variable map for logging without values.
+func variableNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageRegistryForSpec returns the normalized registry hostname an image will be pulled from.
+// An explicit imageRegistry wins; otherwise the first path component of the image name is used
+// when it looks like a hostname (contains "." or ":" or is "localhost"), defaulting to Docker Hub.
+func imageRegistryForSpec(s ServiceSpec) string {
+	if s.ImageRegistry != nil && *s.ImageRegistry != "" {
+		return vault.NormalizeRegistry(*s.ImageRegistry)
+	}
+	if s.ImageName == nil {
+		return vault.DefaultDockerRegistry
+	}
+	if first, _, found := strings.Cut(*s.ImageName, "/"); found {
+		if strings.ContainsAny(first, ".:") || first == "localhost" {
+			return vault.NormalizeRegistry(first)
+		}
+	}
+	return vault.DefaultDockerRegistry
+}
+
+// lookupStoredRegistryCredentials returns the user's Vault-stored credentials for the registry
+// a service image is pulled from. Missing credentials are expected for public images, so any
+// failure is logged and nil is returned to let provisioning continue unauthenticated.
+func (c *ServicesController) lookupStoredRegistryCredentials(ctx context.Context, userID string, s ServiceSpec) *railway.RegistryCredentials {
+	return lookupStoredRegistryCredentials(ctx, c.Vault, userID, s)
+}
+
+// lookupStoredRegistryCredentials is the controller-independent form of
+// ServicesController.lookupStoredRegistryCredentials, shared with environment cloning.
+func lookupStoredRegistryCredentials(ctx context.Context, vc *vault.Client, userID string, s ServiceSpec) *railway.RegistryCredentials {
+	if vc == nil {
+		return nil
+	}
+	registry := imageRegistryForSpec(s)
+	creds, err := vc.GetDockerCredentials(ctx, userID, registry)
+	if err != nil {
+		if err != vault.ErrSecretNotFound {
+			log.Warn().Err(err).
+				Str("service", s.Name).
+				Str("registry", registry).
+				Msg("failed to load stored registry credentials; continuing without")
+		}
+		return nil
+	}
+	log.Info().
+		Str("service", s.Name).
+		Str("registry", registry).
+		Msg("using stored registry credentials for image deployment")
+	return &railway.RegistryCredentials{
+		Username: creds.Username,
+		Password: creds.Password,
+	}
+}
+
 // validateDockerfilePath validates that a Dockerfile path is safe and relative.
 // Rejects absolute paths and parent directory traversal attempts.
 func validateDockerfilePath(path string) error {
@@ -371,7 +864,18 @@ func serviceSpecToModel(spec ServiceSpec, environmentID string, railwayServiceID
 	}
 
 	// Determine deployment type and set fields accordingly
-	if spec.ImageName != nil {
+	if spec.Database != nil {
+		// Database template deployment; the expanded spec carries the template image
+		service.DeploymentType = store.DeploymentTypeDatabase
+		service.DatabaseEngine = spec.Database.Engine
+		if spec.ImageName != nil {
+			service.DockerImage = buildImageReference(spec)
+			service.ImageName = *spec.ImageName
+		}
+		if spec.ImageTag != nil {
+			service.ImageTag = *spec.ImageTag
+		}
+	} else if spec.ImageName != nil {
 		// Docker image deployment
 		service.DeploymentType = store.DeploymentTypeDockerImage
 		service.DockerImage = buildImageReference(spec)
@@ -401,9 +905,15 @@ func serviceSpecToModel(spec ServiceSpec, environmentID string, railwayServiceID
 		}
 	}
 
-	// ExposedPortsJSON - initialize as empty array for now
-	// In the future, this could be extracted from service configuration
+	// ExposedPortsJSON is always a JSON array, empty when no ports were given
 	service.ExposedPortsJSON = "[]"
+	if len(spec.ExposedPorts) > 0 {
+		ports, err := json.Marshal(spec.ExposedPorts)
+		if err != nil {
+			return store.Service{}, fmt.Errorf("marshal exposed ports: %w", err)
+		}
+		service.ExposedPortsJSON = string(ports)
+	}
 
 	return service, nil
 }
@@ -462,6 +972,7 @@ func serviceModelToServiceDetailDTO(svc store.Service) ServiceDetailDTO {
 		ImageName:        svc.ImageName,
 		ImageTag:         svc.ImageTag,
 		ImageDigest:      svc.ImageDigest,
+		DatabaseEngine:   svc.DatabaseEngine,
 		HealthCheckPath:  svc.HealthCheckPath,
 		StartCommand:     svc.StartCommand,
 		CreatedAt:        svc.CreatedAt.UTC().Format(time.RFC3339),
@@ -511,6 +1022,15 @@ func (c *ServicesController) DeleteRailwayService(ctx *gin.Context) {
 		return
 	}
 
+	// Deleting a database destroys its data, so it must be confirmed explicitly
+	if service.DeploymentType == store.DeploymentTypeDatabase && ctx.Query(deleteDataQueryParam) != "true" {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"error":   "service is a database",
+			"message": fmt.Sprintf("deleting %s permanently destroys its data; retry with ?%s=true to confirm", service.Name, deleteDataQueryParam),
+		})
+		return
+	}
+
 	// Get user-specific Railway client
 	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
 	if err != nil {
@@ -528,20 +1048,36 @@ func (c *ServicesController) DeleteRailwayService(ctx *gin.Context) {
 		return
 	}
 
+	// Look up the service's volumes before its row goes away so they can be deleted with it
+	volumes, err := storedRailwayVolumes(ctx, c.DB, service.ID)
+	if err != nil {
+		log.Error().Err(err).Str("service_id", service.ID).Msg("failed to load service volumes")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load service volumes"})
+		return
+	}
+
 	// Step 1: Delete from Railway first (fail fast if Railway API fails)
 	log.Info().
 		Str("railway_service_id", railwayServiceID).
 		Str("user_id", user.ID).
+		Int("volume_count", len(volumes)).
 		Msg("deleting railway service")
 	if err := rwClient.DestroyService(ctx, railway.DestroyServiceInput{ServiceID: railwayServiceID}); err != nil {
 		log.Error().Err(err).Str("railway_service_id", railwayServiceID).Msg("railway delete service failed")
 		ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
 		return
 	}
+	if failed := deleteRailwayVolumes(ctx, rwClient, volumes); len(failed) > 0 {
+		log.Warn().Strs("railway_volume_ids", failed).Str("railway_service_id", railwayServiceID).Msg("volumes of deleted service were left in railway")
+	}
 
 	// Step 2: Clean up database (Railway deletion succeeded, so clean up our record)
 	// Note: service was already fetched above with ownership verification
 	if c.DB != nil {
+		if err := c.DB.Where("service_id = ?", service.ID).Delete(&store.Volume{}).Error; err != nil {
+			log.Error().Err(err).Str("service_id", service.ID).Msg("failed to delete service volumes from database")
+		}
+
 		// Delete the service from the database
 		result := c.DB.Where("railway_service_id = ?", railwayServiceID).Delete(&store.Service{})
 		if result.Error != nil {
@@ -4,20 +4,29 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
 )
 
 // mockRailwayClient implements RailwayServiceClient for testing
 type mockRailwayClient struct {
 	createServiceFunc  func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error)
 	destroyServiceFunc func(ctx context.Context, in railway.DestroyServiceInput) error
+	createDomainFunc   func(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error)
+	createVolumeFunc   func(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error)
+	deletedVolumes     []string
 }
 
 func (m *mockRailwayClient) CreateService(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
@@ -34,6 +43,25 @@ func (m *mockRailwayClient) DestroyService(ctx context.Context, in railway.Destr
 	return nil
 }
 
+func (m *mockRailwayClient) CreateServiceDomain(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error) {
+	if m.createDomainFunc != nil {
+		return m.createDomainFunc(ctx, in)
+	}
+	return railway.Domain{ID: "test-domain-id", Domain: "test.up.railway.app"}, nil
+}
+
+func (m *mockRailwayClient) CreateVolume(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error) {
+	if m.createVolumeFunc != nil {
+		return m.createVolumeFunc(ctx, in)
+	}
+	return railway.Volume{ID: "test-volume-id", MountPath: in.MountPath}, nil
+}
+
+func (m *mockRailwayClient) DeleteVolume(ctx context.Context, in railway.DeleteVolumeInput) error {
+	m.deletedVolumes = append(m.deletedVolumes, in.VolumeID)
+	return nil
+}
+
 func TestProvisionServices_RepoBasedDeployment(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -363,6 +391,30 @@ func TestBuildImageReference_FullReference(t *testing.T) {
 	assert.Equal(t, "docker.io/library/postgres:15-alpine", result)
 }
 
+func TestImageRegistryForSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		registry  *string
+		imageName string
+		expected  string
+	}{
+		{name: "docker hub default", imageName: "nginx", expected: "docker.io"},
+		{name: "docker hub namespace", imageName: "library/postgres", expected: "docker.io"},
+		{name: "registry in image name", imageName: "ghcr.io/user/app", expected: "ghcr.io"},
+		{name: "registry with port", imageName: "localhost:5000/app", expected: "localhost:5000"},
+		{name: "explicit registry", registry: ptrString("https://GHCR.io/"), imageName: "user/app", expected: "ghcr.io"},
+		{name: "docker hub alias", registry: ptrString("index.docker.io"), imageName: "user/app", expected: "docker.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imageName := tt.imageName
+			spec := ServiceSpec{ImageRegistry: tt.registry, ImageName: &imageName}
+			assert.Equal(t, tt.expected, imageRegistryForSpec(spec))
+		})
+	}
+}
+
 func TestValidateDockerfilePath_ValidPaths(t *testing.T) {
 	validPaths := []string{
 		"Dockerfile",
@@ -747,3 +799,246 @@ func TestProvisionServices_MultipleServicesWithDifferentEnvVars(t *testing.T) {
 func ptrString(s string) *string {
 	return &s
 }
+
+func newProvisioningController(t *testing.T, rw *mockRailwayClient) *ServicesController {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, db.Create(&store.Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: store.EnvironmentTypeDev}).Error)
+	return &ServicesController{Railway: rw, DB: db}
+}
+
+func threeImageServices() []ServiceSpec {
+	specs := make([]ServiceSpec, 0, 3)
+	for _, name := range []string{"a", "b", "c"} {
+		image := name
+		specs = append(specs, ServiceSpec{Name: name, ImageName: &image})
+	}
+	return specs
+}
+
+// failingOn returns a mock that creates services until it reaches the named one.
+func failingOn(name string, destroyed *[]string) *mockRailwayClient {
+	return &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			if in.Name == name {
+				return railway.CreateServiceResult{}, errors.New("quota exceeded")
+			}
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+		destroyServiceFunc: func(ctx context.Context, in railway.DestroyServiceInput) error {
+			*destroyed = append(*destroyed, in.ServiceID)
+			return nil
+		},
+	}
+}
+
+func resultStatuses(results []ServiceProvisionResult) []string {
+	statuses := make([]string, 0, len(results))
+	for _, r := range results {
+		statuses = append(statuses, r.Status)
+	}
+	return statuses
+}
+
+func TestProvisionServicesAtomic_RollsBackOnFailure(t *testing.T) {
+	var destroyed []string
+	rw := failingOn("c", &destroyed)
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 1 // Deterministic: c fails after a and b were created
+	req := ProvisionServicesRequest{EnvironmentID: "env-1", Services: threeImageServices(), Atomic: true}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", req, "rw-env-1", nil)
+
+	var perr *serviceProvisionError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "c", perr.Service)
+	assert.Empty(t, perr.Orphaned)
+	assert.Empty(t, createdServiceIDs(results))
+	assert.Equal(t, []string{serviceProvisionRolledBack, serviceProvisionRolledBack, serviceProvisionFailed}, resultStatuses(results))
+	assert.Equal(t, []string{"rw-b", "rw-a"}, destroyed)
+
+	var count int64
+	require.NoError(t, c.DB.Model(&store.Service{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestProvisionServicesAtomic_SkipsRemainingAfterFailure(t *testing.T) {
+	var destroyed []string
+	rw := failingOn("a", &destroyed)
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 1
+	req := ProvisionServicesRequest{EnvironmentID: "env-1", Services: threeImageServices(), Atomic: true}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", req, "rw-env-1", nil)
+
+	require.Error(t, err)
+	assert.Equal(t, serviceProvisionFailed, results[0].Status)
+	// Later services may start before the failure is observed; none may remain created
+	for _, r := range results[1:] {
+		assert.Contains(t, []string{serviceProvisionSkipped, serviceProvisionRolledBack}, r.Status)
+	}
+}
+
+func TestProvisionServicesAtomic_ReportsOrphans(t *testing.T) {
+	var destroyed []string
+	rw := failingOn("c", &destroyed)
+	rw.destroyServiceFunc = func(ctx context.Context, in railway.DestroyServiceInput) error {
+		if in.ServiceID == "rw-a" {
+			return errors.New("railway down")
+		}
+		return nil
+	}
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 1
+	req := ProvisionServicesRequest{EnvironmentID: "env-1", Services: threeImageServices(), Atomic: true}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", req, "rw-env-1", nil)
+
+	var perr *serviceProvisionError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, []string{"rw-a"}, perr.Orphaned)
+	assert.Equal(t, serviceProvisionOrphaned, results[0].Status)
+
+	// The orphan keeps its row so it can still be found and deleted later
+	var services []store.Service
+	require.NoError(t, c.DB.Find(&services).Error)
+	require.Len(t, services, 1)
+	assert.Equal(t, "rw-a", services[0].RailwayServiceID)
+}
+
+func TestProvisionServices_NonAtomicKeepsCreated(t *testing.T) {
+	var destroyed []string
+	rw := failingOn("b", &destroyed)
+	c := newProvisioningController(t, rw)
+	req := ProvisionServicesRequest{EnvironmentID: "env-1", Services: threeImageServices()}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", req, "rw-env-1", nil)
+
+	require.Error(t, err)
+	assert.Equal(t, []string{"rw-a", "rw-c"}, createdServiceIDs(results))
+	assert.Equal(t, []string{serviceProvisionCreated, serviceProvisionFailed, serviceProvisionCreated}, resultStatuses(results))
+	assert.Equal(t, "quota exceeded", results[1].Error)
+	assert.NotEmpty(t, results[0].ServiceID)
+	assert.Empty(t, destroyed)
+}
+
+func TestProvisionServices_DestroysServiceThatCannotBePersisted(t *testing.T) {
+	var destroyed []string
+	rw := failingOn("none", &destroyed)
+	c := newProvisioningController(t, rw)
+	// Fail only the service insert; provisioning still reads the services table beforehand
+	require.NoError(t, c.DB.Callback().Create().Before("gorm:create").Register("test:fail_service_insert", func(tx *gorm.DB) {
+		if tx.Statement.Table == "services" {
+			_ = tx.AddError(errors.New("disk full"))
+		}
+	}))
+	req := ProvisionServicesRequest{EnvironmentID: "env-1", Services: threeImageServices()[:1]}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", req, "rw-env-1", nil)
+
+	var perr *serviceProvisionError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "a", perr.Service)
+	assert.Contains(t, perr.Error(), "persist service")
+	assert.Empty(t, createdServiceIDs(results))
+	assert.Equal(t, []string{"rw-a"}, destroyed)
+}
+
+func TestProvisionServices_BoundedConcurrencyPreservesOrder(t *testing.T) {
+	const limit = 2
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	rw := &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+	}
+	c := newProvisioningController(t, rw)
+	c.Concurrency = limit
+
+	specs := make([]ServiceSpec, 0, 8)
+	want := make([]string, 0, 8)
+	for i := 0; i < 8; i++ {
+		name := fmt.Sprintf("svc-%d", i)
+		specs = append(specs, ServiceSpec{Name: name, ImageName: &name})
+		want = append(want, "rw-"+name)
+	}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{EnvironmentID: "env-1", Services: specs}, "rw-env-1", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, want, createdServiceIDs(results))
+	assert.LessOrEqual(t, maxInFlight, limit)
+	assert.Greater(t, maxInFlight, 1)
+}
+
+func TestProvisionServices_GeneratesDomainForExposedPort(t *testing.T) {
+	var domainInputs []railway.CreateServiceDomainInput
+	rw := &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+		createDomainFunc: func(ctx context.Context, in railway.CreateServiceDomainInput) (railway.Domain, error) {
+			domainInputs = append(domainInputs, in)
+			if in.ServiceID == "rw-flaky" {
+				return railway.Domain{}, errors.New("domain limit reached")
+			}
+			return railway.Domain{Domain: "web-dev.up.railway.app"}, nil
+		},
+	}
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 1
+	web, worker, flaky := "web", "worker", "flaky"
+	specs := []ServiceSpec{
+		{Name: "web", ImageName: &web, ExposedPorts: []int{3000, 9090}, GenerateDomain: true},
+		{Name: "worker", ImageName: &worker, ExposedPorts: []int{8080}},
+		{Name: "flaky", ImageName: &flaky, ExposedPorts: []int{80}, GenerateDomain: true},
+	}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{EnvironmentID: "env-1", Services: specs}, "rw-env-1", nil)
+
+	require.NoError(t, err, "a failed domain must not fail the service")
+	require.Len(t, domainInputs, 2)
+	assert.Equal(t, "rw-env-1", domainInputs[0].EnvironmentID)
+	assert.Equal(t, 3000, *domainInputs[0].TargetPort)
+	assert.Equal(t, "web-dev.up.railway.app", results[0].Domain)
+	assert.Empty(t, results[1].Domain)
+	assert.Empty(t, results[2].Domain)
+	assert.Equal(t, serviceProvisionCreated, results[2].Status)
+
+	var stored store.Service
+	require.NoError(t, c.DB.First(&stored, "id = ?", results[0].ServiceID).Error)
+	assert.Equal(t, "[3000,9090]", stored.ExposedPortsJSON)
+}
+
+func TestValidateServiceSpec_DomainNeedsPort(t *testing.T) {
+	image := "nginx"
+	assert.Error(t, validateServiceSpec(ServiceSpec{Name: "web", ImageName: &image, GenerateDomain: true}))
+	assert.Error(t, validateServiceSpec(ServiceSpec{Name: "web", ImageName: &image, ExposedPorts: []int{70000}}))
+	assert.NoError(t, validateServiceSpec(ServiceSpec{Name: "web", ImageName: &image, ExposedPorts: []int{80}, GenerateDomain: true}))
+}
+
+func TestPersistProvisionedService_ReplacesServiceAdoptedByDriftReconciler(t *testing.T) {
+	c := newProvisioningController(t, &mockRailwayClient{})
+	require.NoError(t, c.DB.Create(&store.Service{ID: "adopted", UserID: "user-1", EnvironmentID: "env-1", Name: "a", RailwayServiceID: "rw-a"}).Error)
+
+	id, err := c.persistProvisionedService("user-1", imageSpec("a", nil), "env-1", ServiceProvisionResult{RailwayServiceID: "rw-a"})
+	require.NoError(t, err)
+
+	var services []store.Service
+	require.NoError(t, c.DB.Where("railway_service_id = ?", "rw-a").Find(&services).Error)
+	require.Len(t, services, 1)
+	assert.Equal(t, id, services[0].ID)
+	assert.Equal(t, store.DeploymentTypeDockerImage, services[0].DeploymentType)
+}
@@ -0,0 +1,406 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// SaveTemplateRequest is the payload to mark an environment as a template.
+type SaveTemplateRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Description *string `json:"description,omitempty"`
+}
+
+// InstantiateTemplateRequest is the payload to create an environment from a template.
+type InstantiateTemplateRequest struct {
+	Name       string                 `json:"name" binding:"required"`
+	Branch     string                 `json:"branch,omitempty"`     // Optional: overrides the branch of repository-based services
+	ProjectID  string                 `json:"projectId,omitempty"`  // Optional: target Railway project, defaults to the template's project
+	EnvType    *store.EnvironmentType `json:"envType,omitempty"`    // Optional: defaults to the template environment type
+	TTLSeconds *int64                 `json:"ttlSeconds,omitempty"` // Optional: defaults to the template environment TTL
+}
+
+// SaveEnvironmentTemplate marks an environment as a template, or updates the name and
+// description of an existing one, and snapshots the environment's current variables for
+// instantiation. The :id parameter is the Railway environment ID.
+//
+// POST /api/v1/environments/:id/template
+// Request body: {"name": "api + worker + postgres", "description": "Standard backend stack"}
+// Response: TemplateListItemDTO
+func (c *EnvironmentController) SaveEnvironmentTemplate(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	railwayEnvID := ctx.Param("id")
+	if railwayEnvID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "railway environment id required"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req SaveTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "template name required"})
+		return
+	}
+
+	// Look up the Mirage environment by Railway ID with ownership check
+	var env store.Environment
+	err = c.DB.Preload("Services").Where("railway_environment_id = ? AND user_id = ?", railwayEnvID, user.ID).First(&env).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "environment not found"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("railway_env_id", railwayEnvID).Msg("failed to query environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment"})
+		return
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before creating environments",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	metadata, err := c.saveEnvironmentTemplate(ctx, rwClient, user.ID, env, name, req.Description)
+	if err != nil {
+		if errors.Is(err, errTemplateNotSaved) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save template"})
+		} else {
+			ctx.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, TemplateListItemDTO{
+		ID:                  metadata.ID,
+		EnvironmentID:       env.ID,
+		TemplateName:        name,
+		TemplateDescription: metadata.TemplateDescription,
+		EnvironmentName:     env.Name,
+		EnvironmentType:     string(env.Type),
+		ServiceCount:        len(env.Services),
+		CreatedAt:           metadata.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// errTemplateNotSaved marks a template that could not be stored, as opposed to one whose
+// variables could not be read from Railway.
+var errTemplateNotSaved = errors.New("failed to save template")
+
+// templateVariableReader is the subset of the Railway client used to snapshot a template's variables.
+type templateVariableReader interface {
+	GetAllEnvironmentAndServiceVariables(ctx context.Context, in railway.GetAllEnvironmentAndServiceVariablesInput) (railway.GetAllEnvironmentAndServiceVariablesResult, error)
+}
+
+// templateVariables is the variables snapshot stored with a template.
+type templateVariables struct {
+	Shared   map[string]string            `json:"shared,omitempty"`
+	Services map[string]map[string]string `json:"services,omitempty"` // Keyed by the template's Railway service ID
+}
+
+// saveEnvironmentTemplate marks env as a template named name, creating its metadata if it was
+// provisioned without any, and stores a snapshot of its variables taken now.
+func (c *EnvironmentController) saveEnvironmentTemplate(ctx context.Context, rw templateVariableReader, userID string, env store.Environment, name string, description *string) (store.EnvironmentMetadata, error) {
+	vars, err := rw.GetAllEnvironmentAndServiceVariables(ctx, railway.GetAllEnvironmentAndServiceVariablesInput{
+		ProjectID:     env.RailwayProjectID,
+		EnvironmentID: env.RailwayEnvironmentID,
+	})
+	if err != nil {
+		return store.EnvironmentMetadata{}, fmt.Errorf("read environment variables: %w", err)
+	}
+
+	// Environments provisioned without wizard inputs have no metadata yet
+	var metadata store.EnvironmentMetadata
+	err = c.DB.WithContext(ctx).Where("environment_id = ? AND user_id = ?", env.ID, userID).First(&metadata).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		log.Error().Err(err).Str("mirage_env_id", env.ID).Msg("failed to query environment metadata")
+		return store.EnvironmentMetadata{}, fmt.Errorf("%w: %w", errTemplateNotSaved, err)
+	}
+	now := time.Now()
+	if err == gorm.ErrRecordNotFound {
+		metadata = store.EnvironmentMetadata{
+			ID:            uuid.New().String(),
+			UserID:        userID,
+			EnvironmentID: env.ID,
+			CreatedAt:     now,
+		}
+	}
+
+	var links []ServiceLink
+	if len(metadata.ServiceLinksJSON) > 0 {
+		if err := json.Unmarshal(metadata.ServiceLinksJSON, &links); err != nil {
+			return store.EnvironmentMetadata{}, fmt.Errorf("%w: decode service links: %w", errTemplateNotSaved, err)
+		}
+	}
+	var envSecrets map[string]string
+	if c.Vault != nil {
+		if envSecrets, err = c.Vault.GetAllEnvironmentSecrets(ctx, userID, env.ID); err != nil {
+			return store.EnvironmentMetadata{}, fmt.Errorf("%w: read environment secrets: %w", errTemplateNotSaved, err)
+		}
+	}
+	snapshot, err := json.Marshal(snapshotTemplateVariables(env, vars, links, envSecrets))
+	if err != nil {
+		return store.EnvironmentMetadata{}, fmt.Errorf("%w: %w", errTemplateNotSaved, err)
+	}
+
+	metadata.IsTemplate = true
+	metadata.TemplateName = &name
+	metadata.TemplateDescription = description
+	metadata.TemplateVariablesJSON = snapshot
+	metadata.UpdatedAt = now
+	if err := c.DB.WithContext(ctx).Save(&metadata).Error; err != nil {
+		log.Error().Err(err).Str("mirage_env_id", env.ID).Msg("failed to save template metadata")
+		return store.EnvironmentMetadata{}, fmt.Errorf("%w: %w", errTemplateNotSaved, err)
+	}
+
+	log.Info().
+		Str("mirage_env_id", env.ID).
+		Str("metadata_id", metadata.ID).
+		Str("template_name", name).
+		Str("user_id", userID).
+		Msg("saved environment template")
+	return metadata, nil
+}
+
+// snapshotTemplateVariables picks the variables worth storing with a template. Left out are
+// Railway-injected variables, environment secrets (read from Vault at instantiation instead),
+// linked variables (replayed from the recorded references) and database template variables
+// (regenerated for every instance). Service values matching a shared variable are stored as a
+// ${{shared.X}} reference, like a clone.
+func snapshotTemplateVariables(env store.Environment, vars railway.GetAllEnvironmentAndServiceVariablesResult, links []ServiceLink, envSecrets map[string]string) templateVariables {
+	shared := cloneSharedVariables(vars.EnvironmentVariables)
+	for k := range envSecrets {
+		delete(shared, k)
+	}
+	serviceVars := make(map[string]map[string]string, len(vars.ServiceVariables))
+	for _, sv := range vars.ServiceVariables {
+		serviceVars[sv.ServiceID] = sv.Variables
+	}
+
+	snapshot := templateVariables{Shared: shared, Services: make(map[string]map[string]string, len(env.Services))}
+	for _, svc := range env.Services {
+		kept := make(map[string]string)
+		for k, v := range serviceVars[svc.RailwayServiceID] {
+			if _, secret := envSecrets[k]; secret || strings.HasPrefix(k, railwaySystemVariablePrefix) || isLinkedVariable(links, svc.Name, k) {
+				continue
+			}
+			if svc.DeploymentType == store.DeploymentTypeDatabase && isDatabaseTemplateVariable(svc.DatabaseEngine, k) {
+				continue
+			}
+			if sv, ok := shared[k]; ok && sv == v {
+				v = "${{" + railwaySharedNamespace + "." + k + "}}"
+			}
+			kept[k] = v
+		}
+		if len(kept) > 0 {
+			snapshot.Services[svc.RailwayServiceID] = kept
+		}
+	}
+	return snapshot
+}
+
+// isLinkedVariable reports whether the named service's variable is a recorded link.
+func isLinkedVariable(links []ServiceLink, service, variable string) bool {
+	for _, l := range links {
+		if l.Service == service && l.Variable == variable {
+			return true
+		}
+	}
+	return false
+}
+
+// InstantiateTemplate provisions a new environment from a template's stored wizard inputs and
+// service definitions, with an optional branch override. The :id parameter is the template's metadata
+// ID as returned by ListTemplates.
+//
+// POST /api/v1/templates/:id/instantiate
+// Request body: {"name": "feature-login", "branch": "feature/login"}
+// Response: CloneEnvironmentResponse
+func (c *EnvironmentController) InstantiateTemplate(ctx *gin.Context) {
+	if c.Railway == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "railway client not configured"})
+		return
+	}
+	templateID := ctx.Param("id")
+	if templateID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "template id required"})
+		return
+	}
+
+	// Get authenticated user
+	user, err := auth.GetCurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.TTLSeconds != nil && *req.TTLSeconds <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "ttlSeconds must be positive"})
+		return
+	}
+
+	// Look up the template with ownership check
+	var metadata store.EnvironmentMetadata
+	err = c.DB.Where("id = ? AND user_id = ? AND is_template = ?", templateID, user.ID, true).First(&metadata).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("template_id", templateID).Msg("failed to query template")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve template"})
+		return
+	}
+
+	var source store.Environment
+	err = c.DB.Preload("Services", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Services.Volumes").Where("id = ? AND user_id = ?", metadata.EnvironmentID, user.ID).First(&source).Error
+	if err == gorm.ErrRecordNotFound {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "template environment no longer exists"})
+		return
+	} else if err != nil {
+		log.Error().Err(err).Str("env_id", metadata.EnvironmentID).Msg("failed to query template environment")
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve template environment"})
+		return
+	}
+
+	// Get user-specific Railway client
+	rwClient, err := railway.GetRailwayClientForUser(ctx, user.ID, c.Vault, c.Railway)
+	if err != nil {
+		if err == railway.ErrNoRailwayToken {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Railway token not configured",
+				"message": "Please configure your Railway API token in settings before creating environments",
+			})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to get Railway client",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	result, err := c.instantiateTemplate(ctx, rwClient, user.ID, metadata, source, cloneOptions{
+		Name:       req.Name,
+		ProjectID:  req.ProjectID,
+		EnvType:    req.EnvType,
+		TTLSeconds: req.TTLSeconds,
+		Branch:     strings.TrimSpace(req.Branch),
+		TemplateID: metadata.ID,
+	})
+	if err != nil {
+		respondCloneError(ctx, err, result)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// templateWizardInputs holds the stored wizard inputs a template falls back on when the
+// instantiate request leaves them out.
+type templateWizardInputs struct {
+	EnvironmentType store.EnvironmentType `json:"environmentType"`
+	TTLHours        float64               `json:"ttl"`
+}
+
+// instantiateTemplate builds a new environment from what Mirage stored for the template: its
+// service rows, wizard inputs, service links, variables snapshot and Vault environment secrets.
+// The template environment's live Railway variables are not read, so later edits there do not
+// leak in. Like at provisioning, environment secrets go to every non-database service.
+func (c *EnvironmentController) instantiateTemplate(ctx context.Context, rw environmentCloner, userID string, template store.EnvironmentMetadata, source store.Environment, opts cloneOptions) (CloneEnvironmentResponse, error) {
+	if len(template.WizardInputsJSON) > 0 {
+		var inputs templateWizardInputs
+		if err := json.Unmarshal(template.WizardInputsJSON, &inputs); err != nil {
+			return newCloneResponse(source, opts), fmt.Errorf("decode template wizard inputs: %w", err)
+		}
+		if opts.EnvType == nil && inputs.EnvironmentType != "" {
+			opts.EnvType = &inputs.EnvironmentType
+		}
+		if opts.TTLSeconds == nil && inputs.TTLHours > 0 {
+			ttl := int64(inputs.TTLHours * time.Hour.Seconds())
+			opts.TTLSeconds = &ttl
+		}
+	}
+
+	var links []ServiceLink
+	if len(template.ServiceLinksJSON) > 0 {
+		if err := json.Unmarshal(template.ServiceLinksJSON, &links); err != nil {
+			return newCloneResponse(source, opts), fmt.Errorf("decode template service links: %w", err)
+		}
+	}
+
+	var snapshot templateVariables
+	if len(template.TemplateVariablesJSON) > 0 {
+		if err := json.Unmarshal(template.TemplateVariablesJSON, &snapshot); err != nil {
+			return newCloneResponse(source, opts), fmt.Errorf("decode template variables: %w", err)
+		}
+	}
+
+	var envSecrets map[string]string
+	if c.Vault != nil {
+		secrets, err := c.Vault.GetAllEnvironmentSecrets(ctx, userID, source.ID)
+		if err != nil {
+			return newCloneResponse(source, opts), fmt.Errorf("read template environment secrets: %w", err)
+		}
+		envSecrets = secrets
+	}
+
+	serviceVars := make(map[string]map[string]string, len(source.Services))
+	for _, svc := range source.Services {
+		vars := make(map[string]string, len(envSecrets)+len(snapshot.Services[svc.RailwayServiceID]))
+		if svc.DeploymentType != store.DeploymentTypeDatabase {
+			for k, v := range envSecrets {
+				vars[k] = v
+			}
+		}
+		for k, v := range snapshot.Services[svc.RailwayServiceID] {
+			vars[k] = v
+		}
+		serviceVars[svc.RailwayServiceID] = vars
+	}
+
+	return c.replayEnvironment(ctx, rw, userID, source, snapshot.Shared, serviceVars, links, opts)
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+func newTemplateRequest(t *testing.T, userID, param, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	auth.SetCurrentUser(ctx, &store.User{ID: userID})
+	ctx.Params = gin.Params{{Key: "id", Value: param}}
+	ctx.Request = httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	return ctx, w
+}
+
+func TestSaveEnvironmentTemplate_CreatesThenUpdates(t *testing.T) {
+	c, source := seedCloneSource(t)
+	rw := &fakeEnvironmentCloner{}
+
+	created, err := c.saveEnvironmentTemplate(context.Background(), rw, "user-1", source, "backend stack", nil)
+	require.NoError(t, err)
+
+	description := "api + worker"
+	_, err = c.saveEnvironmentTemplate(context.Background(), rw, "user-1", source, "renamed", &description)
+	require.NoError(t, err)
+
+	var all []store.EnvironmentMetadata
+	require.NoError(t, c.DB.Where("environment_id = ?", source.ID).Find(&all).Error)
+	require.Len(t, all, 1)
+	assert.Equal(t, created.ID, all[0].ID)
+	assert.True(t, all[0].IsTemplate)
+	assert.Equal(t, "renamed", *all[0].TemplateName)
+	assert.Equal(t, "api + worker", *all[0].TemplateDescription)
+}
+
+func TestSaveEnvironmentTemplate_SnapshotsVariables(t *testing.T) {
+	c, source := seedCloneSource(t)
+	require.NoError(t, c.DB.Create(&store.EnvironmentMetadata{
+		ID:               "meta-1",
+		UserID:           "user-1",
+		EnvironmentID:    source.ID,
+		ServiceLinksJSON: []byte(`[{"service":"api","variable":"CACHE_URL","target":"cache","value":"${{cache.REDIS_URL}}"}]`),
+	}).Error)
+	rw := &fakeEnvironmentCloner{vars: railway.GetAllEnvironmentAndServiceVariablesResult{
+		EnvironmentVariables: map[string]string{"REGION": "eu", "RAILWAY_ENVIRONMENT_NAME": "staging"},
+		ServiceVariables: []railway.ServiceVariables{
+			{ServiceID: "rw-svc-api", ServiceName: "api", Variables: map[string]string{
+				"PORT":                  "8080",
+				"REGION":                "eu",
+				"CACHE_URL":             "redis://cache.railway.internal:6379",
+				"RAILWAY_PUBLIC_DOMAIN": "api.up.railway.app",
+			}},
+		},
+	}}
+
+	meta, err := c.saveEnvironmentTemplate(context.Background(), rw, "user-1", source, "backend stack", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "meta-1", meta.ID)
+
+	var snapshot templateVariables
+	require.NoError(t, json.Unmarshal(meta.TemplateVariablesJSON, &snapshot))
+	assert.Equal(t, templateVariables{
+		Shared: map[string]string{"REGION": "eu"},
+		Services: map[string]map[string]string{
+			"rw-svc-api": {"PORT": "8080", "REGION": "${{shared.REGION}}"},
+		},
+	}, snapshot)
+}
+
+func TestSaveEnvironmentTemplate_VariableReadFailureSavesNothing(t *testing.T) {
+	c, source := seedCloneSource(t)
+	rw := &fakeEnvironmentCloner{varsErr: errors.New("railway down")}
+
+	_, err := c.saveEnvironmentTemplate(context.Background(), rw, "user-1", source, "backend stack", nil)
+	require.Error(t, err)
+	assert.False(t, errors.Is(err, errTemplateNotSaved))
+
+	var count int64
+	require.NoError(t, c.DB.Model(&store.EnvironmentMetadata{}).Where("environment_id = ?", source.ID).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestSaveEnvironmentTemplate_OtherUsersEnvironment(t *testing.T) {
+	c, source := seedCloneSource(t)
+	c.Railway = &mockRailwayClientForEnvironment{}
+
+	ctx, w := newTemplateRequest(t, "user-2", source.RailwayEnvironmentID, "/api/v1/environments/rw-env-src/template", `{"name":"stolen"}`)
+	c.SaveEnvironmentTemplate(ctx)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestInstantiateTemplate_RequiresTemplate(t *testing.T) {
+	c, _ := seedCloneSource(t)
+	c.Railway = &mockRailwayClientForEnvironment{}
+
+	ctx, w := newTemplateRequest(t, "user-1", "missing", "/api/v1/templates/missing/instantiate", `{"name":"copy"}`)
+	c.InstantiateTemplate(ctx)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCloneEnvironment_BranchOverride(t *testing.T) {
+	c, source := seedCloneSource(t)
+	rw := &fakeEnvironmentCloner{}
+
+	result, err := c.cloneEnvironment(context.Background(), rw, "user-1", source, cloneOptions{
+		Name:       "feature-login",
+		Branch:     "feature/login",
+		TemplateID: "tmpl-1",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, rw.serviceInputs, 2)
+	assert.Equal(t, "feature/login", *rw.serviceInputs[0].Branch)
+	assert.Nil(t, rw.serviceInputs[1].Branch, "image services have no branch")
+
+	var services []store.Service
+	require.NoError(t, c.DB.Where("environment_id = ?", result.EnvironmentID).Order("name").Find(&services).Error)
+	require.Len(t, services, 2)
+	assert.Equal(t, "feature/login", services[0].SourceBranch)
+
+	var meta store.EnvironmentMetadata
+	require.NoError(t, c.DB.First(&meta, "environment_id = ?", result.EnvironmentID).Error)
+	var outputs map[string]interface{}
+	require.NoError(t, json.Unmarshal(meta.ProvisionOutputsJSON, &outputs))
+	assert.Equal(t, "tmpl-1", outputs["templateId"])
+}
+
+func TestInstantiateTemplate_UsesStoredDefinitions(t *testing.T) {
+	c, source := seedCloneSource(t)
+	require.NoError(t, c.DB.Preload("Services", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Preload("Services.Volumes").First(&source, "id = ?", source.ID).Error)
+	template := store.EnvironmentMetadata{
+		ID:                    "tmpl-1",
+		UserID:                "user-1",
+		EnvironmentID:         source.ID,
+		IsTemplate:            true,
+		WizardInputsJSON:      []byte(`{"sourceType":"repository","environmentType":"dev","ttl":2}`),
+		ServiceLinksJSON:      []byte(`[{"service":"api","variable":"CACHE_URL","target":"cache","value":"${{cache.REDIS_URL}}"}]`),
+		TemplateVariablesJSON: []byte(`{"shared":{"REGION":"eu"},"services":{"rw-svc-api":{"PORT":"8080","REGION":"${{shared.REGION}}"}}}`),
+	}
+	require.NoError(t, c.DB.Create(&template).Error)
+	// Any read of the template environment's live Railway variables fails the instantiation
+	rw := &fakeEnvironmentCloner{varsErr: errors.New("live variables must not be read")}
+
+	result, err := c.instantiateTemplate(context.Background(), rw, "user-1", template, source, cloneOptions{Name: "feature-x", TemplateID: template.ID})
+	require.NoError(t, err)
+
+	require.Len(t, rw.serviceInputs, 2)
+	var api railway.CreateServiceInput
+	for _, in := range rw.serviceInputs {
+		if in.Name == "api" {
+			api = in
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"CACHE_URL":               "${{cache.REDIS_URL}}",
+		"PORT":                    "8080",
+		"REGION":                  "${{shared.REGION}}",
+		"RAILWAY_DOCKERFILE_PATH": "services/api/Dockerfile",
+	}, api.Variables)
+	require.Len(t, rw.sharedInputs, 1)
+	assert.Equal(t, map[string]string{"REGION": "eu"}, rw.sharedInputs[0].Variables)
+
+	var env store.Environment
+	require.NoError(t, c.DB.First(&env, "id = ?", result.EnvironmentID).Error)
+	assert.Equal(t, store.EnvironmentTypeDev, env.Type)
+	require.NotNil(t, env.TTLSeconds)
+	assert.Equal(t, int64(7200), *env.TTLSeconds)
+}
@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// errInvalidVariableName is returned for empty names and names reserved for Railway
+var errInvalidVariableName = errors.New("invalid variable name")
+
+// variableClient is the subset of the Railway client used to edit variables and redeploy afterwards.
+type variableClient interface {
+	serviceDeploymentClient
+	GetEnvironmentVariables(ctx context.Context, in railway.GetEnvironmentVariablesInput) (railway.GetEnvironmentVariablesResult, error)
+	UpsertVariableCollection(ctx context.Context, in railway.UpsertVariableCollectionInput) error
+	DeleteVariable(ctx context.Context, in railway.DeleteVariableInput) error
+}
+
+// VariableWriteOptions are shared by every variable write
+type VariableWriteOptions struct {
+	DryRun   bool `json:"dryRun"`   // Only report the diff, change nothing
+	Redeploy bool `json:"redeploy"` // Redeploy affected services once the change is applied
+}
+
+// PutVariablesRequest replaces every variable in scope with Variables
+type PutVariablesRequest struct {
+	VariableWriteOptions
+	Variables map[string]string `json:"variables" binding:"required"`
+}
+
+// PatchVariablesRequest sets the given variables; a null value deletes the variable
+type PatchVariablesRequest struct {
+	VariableWriteOptions
+	Variables map[string]*string `json:"variables" binding:"required"`
+}
+
+// DeleteVariablesRequest deletes the named variables
+type DeleteVariablesRequest struct {
+	VariableWriteOptions
+	Names []string `json:"names" binding:"required,min=1"`
+}
+
+// VariableDiff lists the variable names a write adds, updates and removes. Values are never
+// returned since they may be secrets.
+type VariableDiff struct {
+	Added     []string `json:"added"`
+	Updated   []string `json:"updated"`
+	Removed   []string `json:"removed"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// VariablesChangeResponse reports a variable write and the redeploys it triggered
+type VariablesChangeResponse struct {
+	DryRun           bool                    `json:"dryRun"`
+	Diff             VariableDiff            `json:"diff"`
+	Redeployed       []ServiceActionResponse `json:"redeployed,omitempty"`
+	RedeployFailures map[string]string       `json:"redeployFailures,omitempty"` // Service ID -> error
+}
+
+// variableEdit is a requested change: names mapped to a new value, or nil to delete. With replace
+// set, variables not named are deleted too.
+type variableEdit struct {
+	values  map[string]*string
+	replace bool
+}
+
+// PutEnvironmentVariables replaces the environment's shared variables.
+// PUT /api/v1/environments/:id/variables
+// Request body: {"variables": {"KEY": "value"}, "dryRun": false, "redeploy": false}
+func (c *EnvironmentController) PutEnvironmentVariables(ctx *gin.Context) {
+	var req PutVariablesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.editEnvironmentVariables(ctx, replaceEdit(req.Variables), req.VariableWriteOptions)
+}
+
+// PatchEnvironmentVariables sets or deletes (null value) individual shared variables.
+// PATCH /api/v1/environments/:id/variables
+// Request body: {"variables": {"KEY": "value", "OLD": null}, "dryRun": false, "redeploy": false}
+func (c *EnvironmentController) PatchEnvironmentVariables(ctx *gin.Context) {
+	var req PatchVariablesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.editEnvironmentVariables(ctx, variableEdit{values: req.Variables}, req.VariableWriteOptions)
+}
+
+// DeleteEnvironmentVariables deletes shared variables by name.
+// DELETE /api/v1/environments/:id/variables
+// Request body: {"names": ["KEY"], "dryRun": false, "redeploy": false}
+func (c *EnvironmentController) DeleteEnvironmentVariables(ctx *gin.Context) {
+	var req DeleteVariablesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.editEnvironmentVariables(ctx, deleteEdit(req.Names), req.VariableWriteOptions)
+}
+
+// editEnvironmentVariables applies edit to the environment in the :id route param (a Railway
+// environment ID). Shared variables can be used by any service, so a redeploy covers them all.
+func (c *EnvironmentController) editEnvironmentVariables(ctx *gin.Context, edit variableEdit, opts VariableWriteOptions) {
+	env, rwClient, ok := c.railwayEnvironmentTarget(ctx)
+	if !ok {
+		return
+	}
+
+	var services []store.Service
+	if opts.Redeploy {
+		if err := c.DB.WithContext(ctx).Where("environment_id = ?", env.ID).Order("created_at ASC").Find(&services).Error; err != nil {
+			log.Error().Err(err).Str("env_id", env.ID).Msg("failed to load services to redeploy")
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve environment services"})
+			return
+		}
+	}
+
+	scope := railway.GetEnvironmentVariablesInput{ProjectID: env.RailwayProjectID, EnvironmentID: env.RailwayEnvironmentID}
+	resp, err := applyVariableEdit(ctx, c.DB, rwClient, scope, services, edit, opts)
+	respondVariableEdit(ctx, resp, err, env.ID)
+}
+
+// PutServiceVariables replaces the service's own variables.
+// PUT /api/v1/services/:id/variables
+// Request body: {"variables": {"KEY": "value"}, "dryRun": false, "redeploy": false}
+func (c *ServicesController) PutServiceVariables(ctx *gin.Context) {
+	var req PutVariablesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.editServiceVariables(ctx, replaceEdit(req.Variables), req.VariableWriteOptions)
+}
+
+// PatchServiceVariables sets or deletes (null value) individual service variables.
+// PATCH /api/v1/services/:id/variables
+// Request body: {"variables": {"KEY": "value", "OLD": null}, "dryRun": false, "redeploy": false}
+func (c *ServicesController) PatchServiceVariables(ctx *gin.Context) {
+	var req PatchVariablesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.editServiceVariables(ctx, variableEdit{values: req.Variables}, req.VariableWriteOptions)
+}
+
+// DeleteServiceVariables deletes service variables by name.
+// DELETE /api/v1/services/:id/variables
+// Request body: {"names": ["KEY"], "dryRun": false, "redeploy": false}
+func (c *ServicesController) DeleteServiceVariables(ctx *gin.Context) {
+	var req DeleteVariablesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.editServiceVariables(ctx, deleteEdit(req.Names), req.VariableWriteOptions)
+}
+
+// editServiceVariables applies edit to the service in the :id route param.
+func (c *ServicesController) editServiceVariables(ctx *gin.Context, edit variableEdit, opts VariableWriteOptions) {
+	svc, env, rwClient, ok := c.serviceActionTarget(ctx)
+	if !ok {
+		return
+	}
+
+	scope := railway.GetEnvironmentVariablesInput{
+		ProjectID:     env.RailwayProjectID,
+		EnvironmentID: env.RailwayEnvironmentID,
+		ServiceID:     &svc.RailwayServiceID,
+	}
+	resp, err := applyVariableEdit(ctx, c.DB, rwClient, scope, []store.Service{svc}, edit, opts)
+	respondVariableEdit(ctx, resp, err, svc.ID)
+}
+
+// respondVariableEdit writes the result of applyVariableEdit.
+func respondVariableEdit(ctx *gin.Context, resp VariablesChangeResponse, err error, id string) {
+	switch {
+	case errors.Is(err, errInvalidVariableName):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		log.Error().Err(err).Str("id", id).Msg("failed to update variables")
+		ctx.JSON(http.StatusBadGateway, gin.H{"error": "failed to update variables", "message": err.Error()})
+	default:
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
+
+// replaceEdit turns a full variable set into an edit that also deletes unnamed variables.
+func replaceEdit(vars map[string]string) variableEdit {
+	values := make(map[string]*string, len(vars))
+	for k, v := range vars {
+		values[k] = &v
+	}
+	return variableEdit{values: values, replace: true}
+}
+
+// deleteEdit turns a list of names into an edit deleting them.
+func deleteEdit(names []string) variableEdit {
+	values := make(map[string]*string, len(names))
+	for _, n := range names {
+		values[n] = nil
+	}
+	return variableEdit{values: values}
+}
+
+// applyVariableEdit diffs edit against the variables currently in scope and, unless it's a dry
+// run, writes the difference: one collection upsert for added and updated variables, then one
+// delete per removed variable. Railway's own deploys are skipped so that, when requested, each
+// service in redeployTargets is redeployed exactly once after every write has landed. Redeploy
+// failures are reported per service rather than failing the request, since the variables are
+// already changed.
+func applyVariableEdit(ctx context.Context, db *gorm.DB, rw variableClient, scope railway.GetEnvironmentVariablesInput, redeployTargets []store.Service, edit variableEdit, opts VariableWriteOptions) (VariablesChangeResponse, error) {
+	for name := range edit.values {
+		if name == "" || strings.HasPrefix(name, railwaySystemVariablePrefix) {
+			return VariablesChangeResponse{}, fmt.Errorf("%w: %q (names must be non-empty and not start with %s)", errInvalidVariableName, name, railwaySystemVariablePrefix)
+		}
+	}
+
+	// Diff against the variables as written, so a reference sent back unchanged is not
+	// reported as updated just because Railway renders it to a different value
+	scope.Unrendered = true
+	current, err := rw.GetEnvironmentVariables(ctx, scope)
+	if err != nil {
+		return VariablesChangeResponse{}, err
+	}
+	set, diff := diffVariables(current.Variables, edit)
+	resp := VariablesChangeResponse{DryRun: opts.DryRun, Diff: diff}
+	if opts.DryRun {
+		return resp, nil
+	}
+
+	if len(set) > 0 {
+		if err := rw.UpsertVariableCollection(ctx, railway.UpsertVariableCollectionInput{
+			ProjectID:     scope.ProjectID,
+			EnvironmentID: scope.EnvironmentID,
+			ServiceID:     scope.ServiceID,
+			Variables:     set,
+			SkipDeploys:   true,
+		}); err != nil {
+			return VariablesChangeResponse{}, err
+		}
+	}
+	for _, name := range diff.Removed {
+		if err := rw.DeleteVariable(ctx, railway.DeleteVariableInput{
+			ProjectID:     scope.ProjectID,
+			EnvironmentID: scope.EnvironmentID,
+			ServiceID:     scope.ServiceID,
+			Name:          name,
+		}); err != nil {
+			return VariablesChangeResponse{}, err
+		}
+	}
+
+	if !opts.Redeploy || len(set)+len(diff.Removed) == 0 {
+		return resp, nil
+	}
+	for _, svc := range redeployTargets {
+		r, err := runServiceAction(ctx, db, rw, svc, scope.EnvironmentID, serviceActionRedeploy, "")
+		if err != nil {
+			if resp.RedeployFailures == nil {
+				resp.RedeployFailures = make(map[string]string)
+			}
+			resp.RedeployFailures[svc.ID] = err.Error()
+			log.Warn().Err(err).Str("service_id", svc.ID).Msg("failed to redeploy service after variable change")
+			continue
+		}
+		resp.Redeployed = append(resp.Redeployed, r)
+	}
+	return resp, nil
+}
+
+// diffVariables compares current with edit and returns the variables to write along with the
+// diff. Railway-injected variables are never reported or removed.
+func diffVariables(current map[string]string, edit variableEdit) (map[string]string, VariableDiff) {
+	set := make(map[string]string)
+	diff := VariableDiff{Added: []string{}, Updated: []string{}, Removed: []string{}}
+	for name, value := range edit.values {
+		old, exists := current[name]
+		switch {
+		case value == nil && exists:
+			diff.Removed = append(diff.Removed, name)
+		case value == nil:
+			// Deleting a variable that isn't there is a no-op
+		case !exists:
+			set[name] = *value
+			diff.Added = append(diff.Added, name)
+		case old != *value:
+			set[name] = *value
+			diff.Updated = append(diff.Updated, name)
+		default:
+			diff.Unchanged++
+		}
+	}
+	if edit.replace {
+		for name := range current {
+			if _, named := edit.values[name]; !named && !strings.HasPrefix(name, railwaySystemVariablePrefix) {
+				diff.Removed = append(diff.Removed, name)
+			}
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Updated)
+	sort.Strings(diff.Removed)
+	return set, diff
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeVariableClient struct {
+	fakeDeploymentClient
+	current  map[string]string // Variables as written
+	rendered map[string]string // Variables with references resolved, when they differ
+	upserted []railway.UpsertVariableCollectionInput
+	deleted  []string
+}
+
+func (f *fakeVariableClient) GetEnvironmentVariables(ctx context.Context, in railway.GetEnvironmentVariablesInput) (railway.GetEnvironmentVariablesResult, error) {
+	if !in.Unrendered && f.rendered != nil {
+		return railway.GetEnvironmentVariablesResult{Variables: f.rendered}, nil
+	}
+	return railway.GetEnvironmentVariablesResult{Variables: f.current}, nil
+}
+
+func (f *fakeVariableClient) UpsertVariableCollection(ctx context.Context, in railway.UpsertVariableCollectionInput) error {
+	f.upserted = append(f.upserted, in)
+	return nil
+}
+
+func (f *fakeVariableClient) DeleteVariable(ctx context.Context, in railway.DeleteVariableInput) error {
+	f.deleted = append(f.deleted, in.Name)
+	return nil
+}
+
+func TestDiffVariables_ReplaceKeepsRailwayVariables(t *testing.T) {
+	current := map[string]string{"KEEP": "1", "CHANGE": "old", "DROP": "x", "RAILWAY_PUBLIC_DOMAIN": "a.up.railway.app"}
+	set, diff := diffVariables(current, replaceEdit(map[string]string{"KEEP": "1", "CHANGE": "new", "ADD": "y"}))
+
+	assert.Equal(t, map[string]string{"CHANGE": "new", "ADD": "y"}, set)
+	assert.Equal(t, VariableDiff{Added: []string{"ADD"}, Updated: []string{"CHANGE"}, Removed: []string{"DROP"}, Unchanged: 1}, diff)
+}
+
+func TestApplyVariableEdit_DryRunWritesNothing(t *testing.T) {
+	rw := &fakeVariableClient{current: map[string]string{"OLD": "1"}}
+	scope := railway.GetEnvironmentVariablesInput{ProjectID: "p", EnvironmentID: "e"}
+
+	resp, err := applyVariableEdit(context.Background(), nil, rw, scope, nil, deleteEdit([]string{"OLD", "MISSING"}), VariableWriteOptions{DryRun: true})
+	require.NoError(t, err)
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, []string{"OLD"}, resp.Diff.Removed)
+	assert.Empty(t, rw.upserted)
+	assert.Empty(t, rw.deleted)
+}
+
+func TestApplyVariableEdit_DiffsReferencesAsWritten(t *testing.T) {
+	rw := &fakeVariableClient{
+		current:  map[string]string{"DATABASE_URL": "${{db.DATABASE_URL}}"},
+		rendered: map[string]string{"DATABASE_URL": "postgres://db.railway.internal:5432/app"},
+	}
+	scope := railway.GetEnvironmentVariablesInput{ProjectID: "p", EnvironmentID: "e"}
+
+	resp, err := applyVariableEdit(context.Background(), nil, rw, scope, nil, replaceEdit(map[string]string{"DATABASE_URL": "${{db.DATABASE_URL}}"}), VariableWriteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, VariableDiff{Added: []string{}, Updated: []string{}, Removed: []string{}, Unchanged: 1}, resp.Diff)
+	assert.Empty(t, rw.upserted)
+}
+
+func TestApplyVariableEdit_WritesThenRedeploysOnce(t *testing.T) {
+	c, svc := newServiceActionFixture(t)
+	rw := &fakeVariableClient{
+		fakeDeploymentClient: fakeDeploymentClient{history: []railway.Deployment{{ID: "d1", CanRedeploy: true}}},
+		current:              map[string]string{"OLD": "1", "LOG_LEVEL": "info"},
+	}
+	svcID := svc.RailwayServiceID
+	scope := railway.GetEnvironmentVariablesInput{ProjectID: "p", EnvironmentID: "e", ServiceID: &svcID}
+	debug := "debug"
+	edit := variableEdit{values: map[string]*string{"LOG_LEVEL": &debug, "OLD": nil}}
+
+	resp, err := applyVariableEdit(context.Background(), c.DB, rw, scope, []store.Service{svc}, edit, VariableWriteOptions{Redeploy: true})
+	require.NoError(t, err)
+	require.Len(t, rw.upserted, 1)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "debug"}, rw.upserted[0].Variables)
+	assert.True(t, rw.upserted[0].SkipDeploys)
+	assert.Equal(t, &svcID, rw.upserted[0].ServiceID)
+	assert.Equal(t, []string{"OLD"}, rw.deleted)
+	assert.Equal(t, []string{"d1"}, rw.redeployed)
+	require.Len(t, resp.Redeployed, 1)
+	assert.Empty(t, resp.RedeployFailures)
+}
+
+func TestApplyVariableEdit_RejectsRailwayNames(t *testing.T) {
+	rw := &fakeVariableClient{}
+	v := "x"
+	_, err := applyVariableEdit(context.Background(), nil, rw, railway.GetEnvironmentVariablesInput{}, nil,
+		variableEdit{values: map[string]*string{"RAILWAY_PUBLIC_DOMAIN": &v}}, VariableWriteOptions{})
+	assert.ErrorIs(t, err, errInvalidVariableName)
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// VolumeSpec mounts a persistent Railway volume into a service so its data survives redeploys.
+type VolumeSpec struct {
+	MountPath string `json:"mountPath"`        // Absolute path inside the container
+	SizeMB    int    `json:"sizeMB,omitempty"` // Minimum capacity needed; Railway sizes volumes by plan, so a smaller volume fails the service
+}
+
+// volumeClient creates and deletes Railway volumes.
+type volumeClient interface {
+	CreateVolume(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error)
+	DeleteVolume(ctx context.Context, in railway.DeleteVolumeInput) error
+}
+
+// validateVolumeSpecs checks that mount paths are absolute, clean and unique within a service.
+func validateVolumeSpecs(volumes []VolumeSpec) error {
+	seen := make(map[string]bool, len(volumes))
+	for _, v := range volumes {
+		if !path.IsAbs(v.MountPath) || path.Clean(v.MountPath) != v.MountPath || v.MountPath == "/" {
+			return fmt.Errorf("volume mount path %q must be an absolute, clean path below /", v.MountPath)
+		}
+		if seen[v.MountPath] {
+			return fmt.Errorf("volume mount path %s is used more than once", v.MountPath)
+		}
+		seen[v.MountPath] = true
+		if v.SizeMB < 0 {
+			return fmt.Errorf("volume at %s: sizeMB must not be negative", v.MountPath)
+		}
+	}
+	return nil
+}
+
+// createServiceVolumes creates and mounts the spec's volumes into a Railway service. On failure
+// the volumes already created are deleted, so the service is left without any.
+func createServiceVolumes(ctx context.Context, rw volumeClient, s ServiceSpec, projectID, railwayEnvID, railwayServiceID string) ([]railway.Volume, error) {
+	created := make([]railway.Volume, 0, len(s.Volumes))
+	for _, spec := range s.Volumes {
+		v, err := rw.CreateVolume(ctx, railway.CreateVolumeInput{
+			ProjectID:     projectID,
+			EnvironmentID: railwayEnvID,
+			ServiceID:     railwayServiceID,
+			MountPath:     spec.MountPath,
+		})
+		if err == nil && spec.SizeMB > 0 && v.SizeMB > 0 && v.SizeMB < spec.SizeMB {
+			created = append(created, v)
+			err = fmt.Errorf("volume at %s has %d MB, service needs %d MB", spec.MountPath, v.SizeMB, spec.SizeMB)
+		}
+		if err != nil {
+			deleteRailwayVolumes(ctx, rw, created)
+			return nil, err
+		}
+		created = append(created, v)
+	}
+	return created, nil
+}
+
+// deleteRailwayVolumes deletes volumes during cleanup and returns the IDs that could not be
+// deleted. Cleanup must finish even if the request that triggered it was cancelled.
+func deleteRailwayVolumes(ctx context.Context, rw volumeClient, volumes []railway.Volume) []string {
+	var failed []string
+	for _, v := range volumes {
+		if err := rw.DeleteVolume(context.WithoutCancel(ctx), railway.DeleteVolumeInput{VolumeID: v.ID}); err != nil {
+			log.Error().Err(err).Str("railway_volume_id", v.ID).Msg("failed to delete railway volume during cleanup")
+			failed = append(failed, v.ID)
+		}
+	}
+	return failed
+}
+
+// volumeModels converts volumes created in Railway into records owned by userID. GORM fills in
+// the service ID when they are created through store.Service.Volumes.
+func volumeModels(userID string, volumes []railway.Volume) []store.Volume {
+	if len(volumes) == 0 {
+		return nil
+	}
+	now := time.Now()
+	models := make([]store.Volume, 0, len(volumes))
+	for _, v := range volumes {
+		models = append(models, store.Volume{
+			ID:              uuid.New().String(),
+			UserID:          userID,
+			RailwayVolumeID: v.ID,
+			MountPath:       v.MountPath,
+			SizeMB:          v.SizeMB,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+		})
+	}
+	return models
+}
+
+// storedRailwayVolumes returns the Railway volumes recorded for serviceIDs, which is a service
+// ID, a slice of them or a subquery selecting them.
+func storedRailwayVolumes(ctx context.Context, db *gorm.DB, serviceIDs interface{}) ([]railway.Volume, error) {
+	var volumes []store.Volume
+	if err := db.WithContext(ctx).Where("service_id IN (?)", serviceIDs).Find(&volumes).Error; err != nil {
+		return nil, err
+	}
+	out := make([]railway.Volume, 0, len(volumes))
+	for _, v := range volumes {
+		if v.RailwayVolumeID != "" {
+			out = append(out, railway.Volume{ID: v.RailwayVolumeID, MountPath: v.MountPath, SizeMB: v.SizeMB})
+		}
+	}
+	return out, nil
+}
+
+// volumeSpecsFromModels returns specs that recreate a service's volumes, empty, elsewhere.
+func volumeSpecsFromModels(volumes []store.Volume) []VolumeSpec {
+	if len(volumes) == 0 {
+		return nil
+	}
+	specs := make([]VolumeSpec, 0, len(volumes))
+	for _, v := range volumes {
+		specs = append(specs, VolumeSpec{MountPath: v.MountPath})
+	}
+	return specs
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func TestValidateVolumeSpecs(t *testing.T) {
+	assert.NoError(t, validateVolumeSpecs([]VolumeSpec{{MountPath: "/data"}, {MountPath: "/var/cache", SizeMB: 1024}}))
+	assert.ErrorContains(t, validateVolumeSpecs([]VolumeSpec{{MountPath: "data"}}), "absolute")
+	assert.ErrorContains(t, validateVolumeSpecs([]VolumeSpec{{MountPath: "/"}}), "absolute")
+	assert.ErrorContains(t, validateVolumeSpecs([]VolumeSpec{{MountPath: "/data/../etc"}}), "absolute")
+	assert.ErrorContains(t, validateVolumeSpecs([]VolumeSpec{{MountPath: "/data"}, {MountPath: "/data"}}), "more than once")
+	assert.ErrorContains(t, validateVolumeSpecs([]VolumeSpec{{MountPath: "/data", SizeMB: -1}}), "negative")
+}
+
+func TestProvisionVolumes_PersistsVolumesWithService(t *testing.T) {
+	var volumeInputs []railway.CreateVolumeInput
+	rw := &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+		createVolumeFunc: func(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error) {
+			volumeInputs = append(volumeInputs, in)
+			return railway.Volume{ID: "rw-vol-1", MountPath: in.MountPath, SizeMB: 5000}, nil
+		},
+	}
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 1
+	spec := imageSpec("minio", nil)
+	spec.Volumes = []VolumeSpec{{MountPath: "/data", SizeMB: 1000}}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{ProjectID: "rw-proj", EnvironmentID: "env-1", Services: []ServiceSpec{spec}}, "rw-env-1", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []railway.CreateVolumeInput{{ProjectID: "rw-proj", EnvironmentID: "rw-env-1", ServiceID: "rw-minio", MountPath: "/data"}}, volumeInputs)
+	var volumes []store.Volume
+	require.NoError(t, c.DB.Find(&volumes).Error)
+	require.Len(t, volumes, 1)
+	assert.Equal(t, results[0].ServiceID, volumes[0].ServiceID)
+	assert.Equal(t, "rw-vol-1", volumes[0].RailwayVolumeID)
+	assert.Equal(t, "user-1", volumes[0].UserID)
+	assert.Equal(t, 5000, volumes[0].SizeMB)
+}
+
+func TestProvisionVolumes_UndersizedVolumeFailsService(t *testing.T) {
+	var destroyed []string
+	rw := &mockRailwayClient{
+		createServiceFunc: func(ctx context.Context, in railway.CreateServiceInput) (railway.CreateServiceResult, error) {
+			return railway.CreateServiceResult{ServiceID: "rw-" + in.Name}, nil
+		},
+		destroyServiceFunc: func(ctx context.Context, in railway.DestroyServiceInput) error {
+			destroyed = append(destroyed, in.ServiceID)
+			return nil
+		},
+		createVolumeFunc: func(ctx context.Context, in railway.CreateVolumeInput) (railway.Volume, error) {
+			return railway.Volume{ID: "rw-vol-1", MountPath: in.MountPath, SizeMB: 500}, nil
+		},
+	}
+	c := newProvisioningController(t, rw)
+	c.Concurrency = 1
+	spec := imageSpec("minio", nil)
+	spec.Volumes = []VolumeSpec{{MountPath: "/data", SizeMB: 1000}}
+
+	results, err := c.provisionServices(context.Background(), rw, "user-1", ProvisionServicesRequest{EnvironmentID: "env-1", Services: []ServiceSpec{spec}}, "rw-env-1", nil)
+	require.Error(t, err)
+
+	assert.Equal(t, serviceProvisionFailed, results[0].Status)
+	assert.Contains(t, results[0].Error, "service needs 1000 MB")
+	assert.Equal(t, []string{"rw-minio"}, destroyed)
+	assert.Equal(t, []string{"rw-vol-1"}, rw.deletedVolumes)
+	var count int64
+	require.NoError(t, c.DB.Model(&store.Service{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestPlanProvisioning_DatabaseGetsDataVolume(t *testing.T) {
+	plan, err := planProvisioning([]ServiceSpec{
+		{Name: "db", Database: &DatabaseSpec{Engine: DatabaseEnginePostgres}},
+		{Name: "cache", Database: &DatabaseSpec{Engine: DatabaseEngineRedis}, Volumes: []VolumeSpec{{MountPath: "/data"}}},
+	}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []VolumeSpec{{MountPath: "/var/lib/postgresql/data"}}, plan.specs[0].Volumes)
+	assert.Equal(t, []VolumeSpec{{MountPath: "/data"}}, plan.specs[1].Volumes)
+}
+
+func TestCloneEnvironment_RecreatesEmptyVolumes(t *testing.T) {
+	c, source := seedCloneSource(t)
+	require.NoError(t, c.DB.Create(&store.Volume{ID: "vol-src", UserID: "user-1", ServiceID: "svc-cache", RailwayVolumeID: "rw-vol-src", MountPath: "/data", SizeMB: 5000}).Error)
+	require.NoError(t, c.DB.Preload("Services").Preload("Services.Volumes").First(&source, "id = ?", source.ID).Error)
+	rw := &fakeEnvironmentCloner{}
+
+	result, err := c.cloneEnvironment(context.Background(), rw, "user-1", source, cloneOptions{Name: "feature-x"})
+	require.NoError(t, err)
+
+	require.Len(t, rw.volumeInputs, 1)
+	assert.Equal(t, railway.CreateVolumeInput{ProjectID: "rw-proj", EnvironmentID: "rw-env-clone", ServiceID: "rw-svc-clone-cache", MountPath: "/data"}, rw.volumeInputs[0])
+	var cache store.Service
+	require.NoError(t, c.DB.Preload("Volumes").First(&cache, "environment_id = ? AND name = ?", result.EnvironmentID, "cache").Error)
+	require.Len(t, cache.Volumes, 1)
+	assert.Equal(t, "rw-vol-clone-1", cache.Volumes[0].RailwayVolumeID)
+	assert.NotEqual(t, "vol-src", cache.Volumes[0].ID)
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/auth"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// webSocketAuthTimeout is how long a client has to send its auth message after connecting
+const webSocketAuthTimeout = 5 * time.Second
+
+// writeWebSocketMessage sends a typed WebSocketMessage to the client
+func writeWebSocketMessage(ctx context.Context, conn *websocket.Conn, msgType string, data interface{}) error {
+	msg := WebSocketMessage{
+		Type: msgType,
+		Data: data,
+	}
+
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal message: %w", err)
+	}
+
+	if err := conn.Write(ctx, websocket.MessageText, msgBytes); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+
+	return nil
+}
+
+// acceptAuthenticatedWebSocket upgrades the request and authenticates the client from its first
+// message ({"type":"auth","token":"..."}). On failure the client has already been told why and
+// the connection closed; on success the caller owns conn and must close it.
+func acceptAuthenticatedWebSocket(ginCtx *gin.Context, db *gorm.DB, allowedOrigins []string) (*websocket.Conn, *store.User, bool) {
+	// Defaults to wildcard for development
+	if len(allowedOrigins) == 0 {
+		allowedOrigins = []string{"*"}
+		log.Warn().Msg("no allowed origins configured for websocket, using wildcard (not recommended for production)")
+	}
+
+	conn, err := websocket.Accept(ginCtx.Writer, ginCtx.Request, &websocket.AcceptOptions{
+		OriginPatterns: allowedOrigins,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to upgrade to websocket")
+		return nil, nil, false
+	}
+
+	ctx := ginCtx.Request.Context()
+	reject := func(clientMsg, closeReason string) {
+		writeWebSocketMessage(ctx, conn, messageTypeError, clientMsg)
+		conn.Close(websocket.StatusPolicyViolation, closeReason)
+	}
+
+	authCtx, authCancel := context.WithTimeout(ctx, webSocketAuthTimeout)
+	defer authCancel()
+
+	_, authMsgBytes, err := conn.Read(authCtx)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to read auth message")
+		reject("authentication required", "no auth message received")
+		return nil, nil, false
+	}
+
+	var authMsg struct {
+		Type  string `json:"type"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(authMsgBytes, &authMsg); err != nil {
+		log.Error().Err(err).Msg("failed to parse auth message")
+		reject("invalid auth message format", "invalid auth message")
+		return nil, nil, false
+	}
+	if authMsg.Type != "auth" || authMsg.Token == "" {
+		log.Error().Msg("auth message missing type or token")
+		reject("invalid auth message", "invalid auth message")
+		return nil, nil, false
+	}
+
+	user, err := auth.VerifyAndLoadUser(ctx, db, authMsg.Token)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to verify auth token")
+		reject("authentication failed", "authentication failed")
+		return nil, nil, false
+	}
+
+	return conn, user, true
+}
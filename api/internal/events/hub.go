@@ -0,0 +1,106 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultSubscriberBuffer is the number of events buffered per subscriber before new ones are dropped.
+const DefaultSubscriberBuffer = 64
+
+// Event is a message published to a topic.
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Subscription receives the events published to one topic until it is closed.
+type Subscription struct {
+	C <-chan Event
+
+	hub   *Hub
+	topic string
+	ch    chan Event
+	once  sync.Once
+}
+
+// Close unsubscribes and closes C. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
+
+// Hub is an in-process pub/sub broker keyed by topic. Publishing never blocks: a subscriber
+// whose buffer is full misses events rather than stalling publishers such as background jobs.
+// With a Relay attached, events also reach subscribers on the other API replicas.
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+
+	forward func(topic string, e Event) // Set by a Relay; shares local events with other replicas
+}
+
+// NewHub creates an empty hub.
+func NewHub() *Hub {
+	return &Hub{topics: make(map[string]map[*Subscription]struct{})}
+}
+
+// Subscribe registers for events on topic. Callers must Close the subscription when done.
+func (h *Hub) Subscribe(topic string) *Subscription {
+	ch := make(chan Event, DefaultSubscriberBuffer)
+	sub := &Subscription{C: ch, hub: h, topic: topic, ch: ch}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[*Subscription]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+	return sub
+}
+
+// Publish delivers e to every current subscriber of topic, on every replica when a Relay is attached.
+func (h *Hub) Publish(topic string, e Event) {
+	h.deliver(topic, e)
+	h.mu.RLock()
+	forward := h.forward
+	h.mu.RUnlock()
+	if forward != nil {
+		forward(topic, e)
+	}
+}
+
+// deliver hands e to this process's subscribers of topic only.
+func (h *Hub) deliver(topic string, e Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.topics[topic] {
+		select {
+		case sub.ch <- e:
+		default:
+			log.Warn().Str("topic", topic).Str("event_type", e.Type).Msg("dropping event for slow subscriber")
+		}
+	}
+}
+
+// SubscriberCount returns the number of subscribers on topic.
+func (h *Hub) SubscriberCount(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if subs, ok := h.topics[sub.topic]; ok {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.topics, sub.topic)
+		}
+	}
+	// Closed under the lock so Publish can never send on a closed channel
+	close(sub.ch)
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func TestHub_PublishesToTopicSubscribers(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(JobTopic("j1"))
+	other := h.Subscribe(JobTopic("j2"))
+	defer other.Close()
+
+	h.PublishJobUpdated(store.ProvisioningJob{ID: "j1", Status: store.JobStatusRunning})
+
+	e := <-sub.C
+	job, ok := e.Data.(store.ProvisioningJob)
+	if e.Type != EventTypeJob || !ok || job.Status != store.JobStatusRunning {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	select {
+	case e := <-other.C:
+		t.Fatalf("expected no event on other topic, got %+v", e)
+	default:
+	}
+
+	sub.Close()
+	sub.Close() // idempotent
+	if _, open := <-sub.C; open {
+		t.Fatalf("expected channel closed after Close")
+	}
+	if n := h.SubscriberCount(JobTopic("j1")); n != 0 {
+		t.Fatalf("expected no subscribers after Close, got %d", n)
+	}
+}
+
+func TestHub_DropsEventsForFullSubscriber(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe("t")
+	defer sub.Close()
+
+	for i := 0; i < DefaultSubscriberBuffer+10; i++ {
+		h.Publish("t", Event{Type: "x"}) // must not block
+	}
+	if len(sub.C) != DefaultSubscriberBuffer {
+		t.Fatalf("expected %d buffered events, got %d", DefaultSubscriberBuffer, len(sub.C))
+	}
+}
+
+func TestHub_PublishEnvironmentUpdated(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(EnvironmentStatusTopic)
+	defer sub.Close()
+
+	h.PublishEnvironmentUpdated("env-1", "active")
+
+	e := <-sub.C
+	got, ok := e.Data.(EnvironmentStatus)
+	if e.Type != EventTypeEnvironmentStatus || !ok || got != (EnvironmentStatus{EnvironmentID: "env-1", Status: "active"}) {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
+
+func TestHub_PublishServiceUpdated(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(EnvironmentStatusTopic)
+	defer sub.Close()
+
+	h.PublishServiceUpdated("env-1", "svc-1", "error")
+
+	e := <-sub.C
+	got, ok := e.Data.(ServiceStatus)
+	if e.Type != EventTypeServiceStatus || !ok || got != (ServiceStatus{EnvironmentID: "env-1", ServiceID: "svc-1", Status: "error"}) {
+		t.Fatalf("unexpected event %+v", e)
+	}
+}
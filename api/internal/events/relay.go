@@ -0,0 +1,186 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultRelayInterval is how often a relay polls for events published by other replicas.
+	DefaultRelayInterval = 500 * time.Millisecond
+
+	// relayRetention is how long recorded events are kept before being pruned.
+	relayRetention = time.Minute
+
+	// relayBuffer is the number of local events waiting to be recorded before new ones are dropped.
+	relayBuffer = 256
+
+	// relayGrace re-reads events recorded shortly before the last poll, so rows committed late
+	// or stamped by a replica whose clock runs slightly behind are still delivered.
+	relayGrace = 5 * time.Second
+)
+
+// Relay shares a hub's events between API replicas through the database. Each replica records
+// the events it publishes and polls for the ones published elsewhere, so WebSocket clients see
+// job progress and status changes no matter which replica they are connected to or which one
+// runs the job or the status poller.
+type Relay struct {
+	db       *gorm.DB
+	hub      *Hub
+	origin   string
+	interval time.Duration
+	pending  chan store.HubEvent // Local events waiting to be recorded by the relay goroutine
+
+	since time.Time
+	seen  map[uint64]time.Time // Delivered event IDs, kept until they fall out of the grace window
+}
+
+// NewRelay attaches a relay to hub. origin must be unique per replica, e.g. its lease holder ID.
+// Events are recorded from now on; call Start to receive other replicas' events.
+func NewRelay(db *gorm.DB, hub *Hub, origin string, interval time.Duration) *Relay {
+	if interval <= 0 {
+		log.Warn().Dur("provided_interval", interval).Dur("default", DefaultRelayInterval).Msg("invalid relay interval; using default")
+		interval = DefaultRelayInterval
+	}
+	r := &Relay{
+		db:       db,
+		hub:      hub,
+		origin:   origin,
+		interval: interval,
+		pending:  make(chan store.HubEvent, relayBuffer),
+		since:    time.Now(),
+		seen:     make(map[uint64]time.Time),
+	}
+	hub.mu.Lock()
+	hub.forward = r.record
+	hub.mu.Unlock()
+	return r
+}
+
+// Start records local events and polls for other replicas' events in the background, and
+// returns a stop function.
+func (r *Relay) Start(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case row := <-r.pending:
+				if err := r.db.WithContext(ctx).Create(&row).Error; err != nil && ctx.Err() == nil {
+					log.Error().Err(err).Str("topic", row.Topic).Str("event_type", row.Type).Msg("failed to record event for relay")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		log.Info().Dur("interval", r.interval).Str("origin", r.origin).Msg("event relay started")
+		defer log.Info().Msg("event relay stopped")
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		lastPrune := time.Now()
+		for {
+			select {
+			case <-ticker.C:
+				if err := r.poll(ctx, time.Now()); err != nil && ctx.Err() == nil {
+					log.Error().Err(err).Msg("event relay poll failed")
+				}
+				if time.Since(lastPrune) >= relayRetention {
+					lastPrune = time.Now()
+					if _, err := store.PruneHubEvents(r.db.WithContext(ctx), lastPrune.Add(-relayRetention)); err != nil && ctx.Err() == nil {
+						log.Warn().Err(err).Msg("failed to prune relayed events")
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// record queues a locally published event to be stored for the other replicas. Like Publish it
+// never blocks: local subscribers already have the event, so when the queue is full, e.g. while
+// the database is slow, the event is dropped for the other replicas instead.
+func (r *Relay) record(topic string, e Event) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		log.Error().Err(err).Str("topic", topic).Str("event_type", e.Type).Msg("failed to encode event for relay")
+		return
+	}
+	select {
+	case r.pending <- store.HubEvent{Origin: r.origin, Topic: topic, Type: e.Type, DataJSON: data, CreatedAt: time.Now().UTC()}:
+	default:
+		log.Warn().Str("topic", topic).Str("event_type", e.Type).Msg("dropping event for other replicas: relay queue full")
+	}
+}
+
+// poll delivers events recorded by other replicas since the previous poll to local subscribers.
+func (r *Relay) poll(ctx context.Context, now time.Time) error {
+	cutoff := r.since.Add(-relayGrace)
+	rows, err := store.ListHubEventsSince(r.db.WithContext(ctx), r.origin, cutoff)
+	if err != nil {
+		return err
+	}
+	r.since = now
+	for id, at := range r.seen {
+		if at.Before(cutoff) {
+			delete(r.seen, id)
+		}
+	}
+
+	for _, row := range rows {
+		if _, ok := r.seen[row.ID]; ok {
+			continue
+		}
+		r.seen[row.ID] = row.CreatedAt
+		e, err := decodeEvent(row)
+		if err != nil {
+			log.Warn().Err(err).Uint64("event_id", row.ID).Str("event_type", row.Type).Msg("skipping undecodable relayed event")
+			continue
+		}
+		r.hub.deliver(row.Topic, e)
+	}
+	return nil
+}
+
+// decodeEvent restores the typed payload subscribers expect for the event's type.
+func decodeEvent(row store.HubEvent) (Event, error) {
+	var data interface{}
+	var err error
+	switch row.Type {
+	case EventTypeJob:
+		var job store.ProvisioningJob
+		err = json.Unmarshal(row.DataJSON, &job)
+		data = job
+	case EventTypeEnvironmentStatus:
+		var update EnvironmentStatus
+		err = json.Unmarshal(row.DataJSON, &update)
+		data = update
+	case EventTypeServiceStatus:
+		var update ServiceStatus
+		err = json.Unmarshal(row.DataJSON, &update)
+		data = update
+	default:
+		return Event{}, fmt.Errorf("unknown event type %q", row.Type)
+	}
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Type: row.Type, Data: data}, nil
+}
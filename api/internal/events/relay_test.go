@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func TestRelay_DeliversEventsAcrossReplicas(t *testing.T) {
+	// A file database shared by both "replicas"; :memory: would give each connection its own DB
+	db, err := store.Open(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	hubA, hubB := NewHub(), NewHub()
+	stopA := NewRelay(db, hubA, "replica-a", 10*time.Millisecond).Start(context.Background())
+	defer stopA()
+	stopB := NewRelay(db, hubB, "replica-b", 10*time.Millisecond).Start(context.Background())
+	defer stopB()
+
+	local := hubA.Subscribe(JobTopic("j1"))
+	defer local.Close()
+	remote := hubB.Subscribe(JobTopic("j1"))
+	defer remote.Close()
+	status := hubB.Subscribe(EnvironmentStatusTopic)
+	defer status.Close()
+
+	hubA.PublishJobUpdated(store.ProvisioningJob{ID: "j1", Status: store.JobStatusRunning, CurrentStep: "project"})
+	hubA.PublishEnvironmentUpdated("env-1", "deploying")
+
+	select {
+	case e := <-remote.C:
+		job, ok := e.Data.(store.ProvisioningJob)
+		if e.Type != EventTypeJob || !ok || job.Status != store.JobStatusRunning || job.CurrentStep != "project" {
+			t.Fatalf("unexpected relayed event %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for relayed job event")
+	}
+	select {
+	case e := <-status.C:
+		update, ok := e.Data.(EnvironmentStatus)
+		if !ok || update != (EnvironmentStatus{EnvironmentID: "env-1", Status: "deploying"}) {
+			t.Fatalf("unexpected relayed status event %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for relayed status event")
+	}
+
+	// The publishing replica's subscribers get the event once, not again through the relay
+	<-local.C
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case e := <-local.C:
+		t.Fatalf("expected no echo of a local event, got %+v", e)
+	case e := <-remote.C:
+		t.Fatalf("expected the relayed event once, got another %+v", e)
+	default:
+	}
+}
+
+func TestRelay_PublishDoesNotWaitForDatabase(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	hub := NewHub()
+	// Not started, so nothing drains the queue, as when the database stalls
+	r := NewRelay(db, hub, "replica-a", time.Second)
+
+	for i := 0; i < relayBuffer+10; i++ {
+		hub.PublishEnvironmentUpdated("env-1", "deploying")
+	}
+
+	if n := len(r.pending); n != relayBuffer {
+		t.Fatalf("expected a full queue of %d events, got %d", relayBuffer, n)
+	}
+}
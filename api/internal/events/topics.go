@@ -0,0 +1,65 @@
+package events
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+const (
+	// EventTypeJob is published whenever a provisioning job changes; Data is a store.ProvisioningJob.
+	EventTypeJob = "job"
+
+	// EventTypeEnvironmentStatus is published when the status poller sees an environment's
+	// status change; Data is an EnvironmentStatus.
+	EventTypeEnvironmentStatus = "environment_status"
+
+	// EventTypeServiceStatus is published when a service's latest deployment status changes;
+	// Data is a ServiceStatus.
+	EventTypeServiceStatus = "service_status"
+
+	// EnvironmentStatusTopic carries environment and service status changes for all
+	// environments. The poller doesn't know owners, so subscribers filter to the
+	// environments they may see.
+	EnvironmentStatusTopic = "environments:status"
+)
+
+// EnvironmentStatus is the payload of an EventTypeEnvironmentStatus event.
+type EnvironmentStatus struct {
+	EnvironmentID string // Mirage environment ID
+	Status        string
+}
+
+// ServiceStatus is the payload of an EventTypeServiceStatus event.
+type ServiceStatus struct {
+	EnvironmentID string // Mirage environment ID
+	ServiceID     string // Mirage service ID
+	Status        string
+}
+
+// JobTopic is the topic carrying updates for one provisioning job.
+func JobTopic(jobID string) string {
+	return "job:" + jobID
+}
+
+// PublishJobUpdated publishes a snapshot of job to its topic.
+func (h *Hub) PublishJobUpdated(job store.ProvisioningJob) {
+	h.Publish(JobTopic(job.ID), Event{Type: EventTypeJob, Data: job})
+}
+
+// PublishEnvironmentUpdated implements jobs.EnvironmentPublisher.
+func (h *Hub) PublishEnvironmentUpdated(environmentID string, newStatus string) {
+	log.Info().Str("env_id", environmentID).Str("status", newStatus).Msg("environment status updated")
+	h.Publish(EnvironmentStatusTopic, Event{
+		Type: EventTypeEnvironmentStatus,
+		Data: EnvironmentStatus{EnvironmentID: environmentID, Status: newStatus},
+	})
+}
+
+// PublishServiceUpdated implements jobs.EnvironmentPublisher.
+func (h *Hub) PublishServiceUpdated(environmentID string, serviceID string, newStatus string) {
+	log.Info().Str("env_id", environmentID).Str("service_id", serviceID).Str("status", newStatus).Msg("service status updated")
+	h.Publish(EnvironmentStatusTopic, Event{
+		Type: EventTypeServiceStatus,
+		Data: ServiceStatus{EnvironmentID: environmentID, ServiceID: serviceID, Status: newStatus},
+	})
+}
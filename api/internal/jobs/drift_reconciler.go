@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/reconcile"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+	"gorm.io/gorm"
+)
+
+// StartDriftReconciler starts a background loop that compares every environment's Mirage records
+// with Railway, using the owner's Railway client. Drift is logged; when apply is set the records
+// are also corrected to match Railway. It returns a stop function to halt the loop.
+func StartDriftReconciler(
+	ctx context.Context,
+	db *gorm.DB,
+	rw *railway.Client,
+	vaultClient *vault.Client,
+	interval time.Duration,
+	apply bool,
+) (stop func()) {
+	if db == nil || rw == nil {
+		log.Error().Msg("drift reconciler not started: nil dependency (db or railway client)")
+		return func() {}
+	}
+	if interval <= 0 {
+		log.Warn().Dur("provided_interval", interval).Msg("invalid drift reconcile interval; using minimum 1s")
+		interval = time.Second
+	}
+
+	r := &driftReconciler{
+		db: db,
+		clientFor: func(ctx context.Context, userID string) (reconcile.ProjectSource, error) {
+			return railway.GetRailwayClientForUser(ctx, userID, vaultClient, rw)
+		},
+		vault: vaultClient,
+		apply: apply,
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		log.Info().Dur("interval", interval).Bool("apply", apply).Msg("drift reconciler started")
+		defer log.Info().Msg("drift reconciler stopped")
+		for {
+			select {
+			case <-time.After(interval):
+				if err := r.reconcileOnce(ctx, time.Now()); err != nil {
+					log.Error().Err(err).Msg("drift reconciler iteration failed")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return cancel
+}
+
+// driftReconciler checks every user's environments against Railway using that user's client.
+type driftReconciler struct {
+	db        *gorm.DB
+	clientFor func(ctx context.Context, userID string) (reconcile.ProjectSource, error)
+	vault     *vault.Client
+	apply     bool
+}
+
+func (r *driftReconciler) reconcileOnce(ctx context.Context, now time.Time) error {
+	// Environments still being created or destroyed are expected to differ from Railway
+	var envs []store.Environment
+	err := r.db.WithContext(ctx).
+		Where("railway_project_id <> '' AND railway_environment_id <> '' AND status NOT IN ?", []string{status.StatusCreating, status.StatusDestroying}).
+		Order("user_id").
+		Find(&envs).Error
+	if err != nil {
+		return err
+	}
+
+	byUser := make(map[string][]store.Environment)
+	var users []string
+	for _, e := range envs {
+		if _, ok := byUser[e.UserID]; !ok {
+			users = append(users, e.UserID)
+		}
+		byUser[e.UserID] = append(byUser[e.UserID], e)
+	}
+
+	for _, userID := range users {
+		rw, err := r.clientFor(ctx, userID)
+		if err != nil {
+			ev := log.Warn()
+			if errors.Is(err, railway.ErrNoRailwayToken) {
+				ev = log.Debug() // Expected for users who haven't connected Railway yet
+			}
+			ev.Err(err).Str("user_id", userID).Msg("skipping drift check: no railway client for user")
+			continue
+		}
+		provisioning, err := r.hasActiveProvisioning(ctx, userID)
+		if err != nil {
+			log.Error().Err(err).Str("user_id", userID).Msg("failed to check in-flight provisioning for drift check")
+			continue
+		}
+		r.reconcileUser(ctx, rw, byUser[userID], !provisioning, now)
+	}
+	return nil
+}
+
+// hasActiveProvisioning reports whether userID has a provisioning job or an idempotent
+// provisioning request still running. Its services may already exist in Railway without a
+// Mirage record, so they must not be adopted yet.
+func (r *driftReconciler) hasActiveProvisioning(ctx context.Context, userID string) (bool, error) {
+	var jobs int64
+	err := r.db.WithContext(ctx).Model(&store.ProvisioningJob{}).
+		Where("user_id = ? AND status IN ?", userID, []store.JobStatus{store.JobStatusPending, store.JobStatusRunning}).
+		Count(&jobs).Error
+	if err != nil || jobs > 0 {
+		return jobs > 0, err
+	}
+	var requests int64
+	err = r.db.WithContext(ctx).Model(&store.IdempotencyRecord{}).
+		Where("user_id = ? AND status = ?", userID, store.IdempotencyStatusInProgress).
+		Count(&requests).Error
+	return requests > 0, err
+}
+
+// reconcileUser checks one user's environments, fetching each Railway project only once.
+// Services that exist only in Railway are adopted only when adopt is set.
+func (r *driftReconciler) reconcileUser(ctx context.Context, rw reconcile.ProjectSource, envs []store.Environment, adopt bool, now time.Time) {
+	projects := make(map[string]railway.ProjectDetails)
+	for i := range envs {
+		env := &envs[i]
+		details, ok := projects[env.RailwayProjectID]
+		if !ok {
+			d, err := rw.GetProjectWithDetailsByID(ctx, env.RailwayProjectID)
+			if err != nil {
+				log.Warn().Err(err).Str("env_id", env.ID).Str("railway_project_id", env.RailwayProjectID).Msg("failed to fetch project for drift check")
+				continue
+			}
+			details, projects[env.RailwayProjectID] = d, d
+		}
+
+		var services []store.Service
+		if err := r.db.WithContext(ctx).Where("environment_id = ?", env.ID).Find(&services).Error; err != nil {
+			log.Error().Err(err).Str("env_id", env.ID).Msg("failed to load services for drift check")
+			continue
+		}
+
+		report := reconcile.Detect(*env, services, details, now)
+		if !report.HasDrift() {
+			continue
+		}
+		log.Warn().
+			Str("env_id", env.ID).
+			Str("railway_env_id", env.RailwayEnvironmentID).
+			Bool("environment_missing", report.EnvironmentMissing).
+			Int("services_drifted", len(report.Services)).
+			Msg("environment drifted from railway")
+		if !r.apply {
+			continue
+		}
+		if !adopt {
+			report.Services = withoutExtraServices(report.Services)
+			if !report.HasDrift() {
+				continue
+			}
+		}
+
+		applied, err := reconcile.Apply(ctx, r.db, env, report, now)
+		if err != nil {
+			log.Error().Err(err).Str("env_id", env.ID).Msg("failed to apply environment drift")
+			continue
+		}
+		log.Info().
+			Str("env_id", env.ID).
+			Bool("environment_deleted", applied.EnvironmentDeleted).
+			Int64("services_deleted", applied.ServicesDeleted).
+			Int("services_created", applied.ServicesCreated).
+			Int("services_updated", applied.ServicesUpdated).
+			Int64("volumes_deleted", applied.VolumesDeleted).
+			Msg("applied environment drift")
+		if len(applied.DeletedVolumeIDs) > 0 {
+			log.Warn().
+				Str("env_id", env.ID).
+				Strs("railway_volume_ids", applied.DeletedVolumeIDs).
+				Msg("volume records of services missing from railway were deleted; the volumes may remain")
+		}
+		if applied.EnvironmentDeleted && r.vault != nil {
+			if err := r.vault.DeleteAllEnvironmentSecrets(ctx, env.UserID, env.ID); err != nil {
+				log.Warn().Err(err).Str("env_id", env.ID).Msg("failed to delete secrets of environment missing from railway")
+			}
+		}
+	}
+}
+
+// withoutExtraServices drops the services that exist only in Railway from drifts.
+func withoutExtraServices(drifts []reconcile.ServiceDrift) []reconcile.ServiceDrift {
+	kept := make([]reconcile.ServiceDrift, 0, len(drifts))
+	for _, d := range drifts {
+		if d.Kind != reconcile.DriftExtra {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/reconcile"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeProjectSource struct {
+	details railway.ProjectDetails
+	fetches int
+}
+
+func (f *fakeProjectSource) GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error) {
+	f.fetches++
+	return f.details, nil
+}
+
+func TestDriftReconciler_ReportsThenApplies(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	envs := []store.Environment{
+		{ID: "env-a", UserID: "alice", Name: "a", Type: store.EnvironmentTypeDev, Status: "active", RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-a"},
+		{ID: "env-b", UserID: "alice", Name: "b", Type: store.EnvironmentTypeDev, Status: "active", RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-b"},
+		{ID: "env-new", UserID: "alice", Name: "new", Type: store.EnvironmentTypeDev, Status: "creating", RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-new"},
+	}
+	for i := range envs {
+		if err := db.Create(&envs[i]).Error; err != nil {
+			t.Fatalf("create env failed: %v", err)
+		}
+	}
+	if err := db.Create(&store.Service{ID: "svc-a", UserID: "alice", EnvironmentID: "env-a", Name: "api", RailwayServiceID: "rw-api"}).Error; err != nil {
+		t.Fatalf("create service failed: %v", err)
+	}
+	if err := db.Create(&store.Service{ID: "svc-new", UserID: "alice", EnvironmentID: "env-new", Name: "api", RailwayServiceID: "rw-pending"}).Error; err != nil {
+		t.Fatalf("create service failed: %v", err)
+	}
+
+	// env-a's api was deleted in Railway; env-b is unchanged; env-new is still being created
+	alice := &fakeProjectSource{details: railway.ProjectDetails{Environments: []railway.ProjectEnvironment{{ID: "rw-a"}, {ID: "rw-b"}}}}
+	r := &driftReconciler{
+		db: db,
+		clientFor: func(ctx context.Context, userID string) (reconcile.ProjectSource, error) {
+			return alice, nil
+		},
+	}
+
+	if err := r.reconcileOnce(context.Background(), time.Now()); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if alice.fetches != 1 {
+		t.Fatalf("expected shared project fetched once, got %d", alice.fetches)
+	}
+	var count int64
+	db.Model(&store.Service{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected report-only run to leave services, got %d", count)
+	}
+
+	r.apply = true
+	if err := r.reconcileOnce(context.Background(), time.Now()); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	var remaining []store.Service
+	db.Find(&remaining)
+	if len(remaining) != 1 || remaining[0].ID != "svc-new" {
+		t.Fatalf("expected only the missing service removed, got %+v", remaining)
+	}
+}
+
+func TestDriftReconciler_DoesNotAdoptDuringProvisioning(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env := store.Environment{ID: "env-a", UserID: "alice", Name: "a", Type: store.EnvironmentTypeDev, Status: "active", RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-a"}
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	job := store.ProvisioningJob{ID: "job-1", UserID: "alice", Status: store.JobStatusRunning}
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("create job failed: %v", err)
+	}
+
+	// The job has created the worker in Railway but not yet recorded it
+	alice := &fakeProjectSource{details: railway.ProjectDetails{Environments: []railway.ProjectEnvironment{
+		{ID: "rw-a", Services: []railway.ServiceInstance{{ServiceID: "rw-worker", ServiceName: "worker"}}},
+	}}}
+	r := &driftReconciler{
+		db: db,
+		clientFor: func(ctx context.Context, userID string) (reconcile.ProjectSource, error) {
+			return alice, nil
+		},
+		apply: true,
+	}
+
+	if err := r.reconcileOnce(context.Background(), time.Now()); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	var count int64
+	db.Model(&store.Service{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected in-flight service not to be adopted, got %d services", count)
+	}
+
+	db.Model(&job).Update("status", store.JobStatusSucceeded)
+	if err := r.reconcileOnce(context.Background(), time.Now()); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	db.Model(&store.Service{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("expected the service to be adopted once provisioning finished, got %d services", count)
+	}
+}
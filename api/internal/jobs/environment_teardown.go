@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+	"gorm.io/gorm"
+)
+
+// EnvironmentDestroyer is the subset of the Railway client used to tear an environment down.
+type EnvironmentDestroyer interface {
+	DestroyEnvironment(ctx context.Context, in railway.DestroyEnvironmentInput) error
+	DeleteVolume(ctx context.Context, in railway.DeleteVolumeInput) error
+}
+
+// DestroyEnvironment deletes env in Railway together with its services' volumes, then removes
+// its records and Vault secrets. Shared by the delete endpoint and the TTL reaper so both leave
+// nothing behind. An environment Railway no longer knows is treated as deleted. An error means
+// nothing was deleted and the caller may retry; failures after the Railway delete are logged
+// instead, since the environment is already gone.
+func DestroyEnvironment(ctx context.Context, db *gorm.DB, rw EnvironmentDestroyer, vaultClient *vault.Client, env *store.Environment) error {
+	// Look up the volumes before their rows go away so they can be deleted with the environment
+	var volumes []store.Volume
+	err := db.WithContext(ctx).
+		Where("service_id IN (?)", db.Model(&store.Service{}).Select("id").Where("environment_id = ?", env.ID)).
+		Find(&volumes).Error
+	if err != nil {
+		return fmt.Errorf("load environment volumes: %w", err)
+	}
+
+	if env.RailwayEnvironmentID != "" {
+		err := rw.DestroyEnvironment(ctx, railway.DestroyEnvironmentInput{EnvironmentID: env.RailwayEnvironmentID})
+		if railway.IsNotFound(err) {
+			log.Info().Err(err).
+				Str("env_id", env.ID).
+				Str("railway_env_id", env.RailwayEnvironmentID).
+				Msg("environment already gone from railway, cleaning up records")
+		} else if err != nil {
+			return err
+		}
+	}
+	// Cleanup must finish even if the request that triggered it was cancelled
+	ctx = context.WithoutCancel(ctx)
+	for _, v := range volumes {
+		if v.RailwayVolumeID == "" {
+			continue
+		}
+		if err := rw.DeleteVolume(ctx, railway.DeleteVolumeInput{VolumeID: v.RailwayVolumeID}); err != nil && !railway.IsNotFound(err) {
+			log.Warn().Err(err).
+				Str("railway_volume_id", v.RailwayVolumeID).
+				Str("env_id", env.ID).
+				Msg("volume of deleted environment was left in railway")
+		}
+	}
+
+	txErr := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res, err := store.DeleteEnvironmentRecords(tx, env)
+		if err != nil {
+			return err
+		}
+		log.Info().
+			Str("env_id", env.ID).
+			Str("railway_env_id", env.RailwayEnvironmentID).
+			Int64("services_deleted", res.ServicesDeleted).
+			Int64("volumes_deleted", res.VolumesDeleted).
+			Int64("metadata_deleted", res.MetadataDeleted).
+			Msg("deleted environment from database")
+		return nil
+	})
+	if txErr != nil {
+		log.Error().Err(txErr).Str("env_id", env.ID).Msg("failed to clean up database after railway environment deletion")
+	}
+
+	// Environment secrets are keyed by the Mirage ID and have no other owner once it is gone
+	if vaultClient != nil {
+		if err := vaultClient.DeleteAllEnvironmentSecrets(ctx, env.UserID, env.ID); err != nil {
+			log.Warn().Err(err).Str("env_id", env.ID).Msg("failed to delete environment secrets")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type fakeEnvironmentDestroyer struct {
+	destroyErr     error
+	destroyedEnvs  []string
+	deletedVolumes []string
+}
+
+func (f *fakeEnvironmentDestroyer) DestroyEnvironment(ctx context.Context, in railway.DestroyEnvironmentInput) error {
+	if f.destroyErr != nil {
+		return f.destroyErr
+	}
+	f.destroyedEnvs = append(f.destroyedEnvs, in.EnvironmentID)
+	return nil
+}
+
+func (f *fakeEnvironmentDestroyer) DeleteVolume(ctx context.Context, in railway.DeleteVolumeInput) error {
+	f.deletedVolumes = append(f.deletedVolumes, in.VolumeID)
+	return nil
+}
+
+func TestDestroyEnvironment_DeletesVolumesAndRecords(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env := store.Environment{ID: "env-1", UserID: "u1", Name: "pr-1", Type: store.EnvironmentTypeEphemeral, RailwayEnvironmentID: "rw-env-1"}
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	svc := store.Service{ID: "svc-1", UserID: "u1", EnvironmentID: env.ID, Name: "db", Volumes: []store.Volume{{ID: "vol-1", UserID: "u1", RailwayVolumeID: "rw-vol-1", MountPath: "/data"}}}
+	if err := db.Create(&svc).Error; err != nil {
+		t.Fatalf("create service failed: %v", err)
+	}
+
+	failing := &fakeEnvironmentDestroyer{destroyErr: errors.New("railway unavailable")}
+	if err := DestroyEnvironment(context.Background(), db, failing, nil, &env); err == nil {
+		t.Fatalf("expected railway failure to be returned")
+	}
+	var volumes int64
+	db.Model(&store.Volume{}).Count(&volumes)
+	if len(failing.deletedVolumes) != 0 || volumes != 1 {
+		t.Fatalf("expected nothing to be deleted after a failed railway delete")
+	}
+
+	rw := &fakeEnvironmentDestroyer{}
+	if err := DestroyEnvironment(context.Background(), db, rw, nil, &env); err != nil {
+		t.Fatalf("destroy failed: %v", err)
+	}
+	if len(rw.destroyedEnvs) != 1 || rw.destroyedEnvs[0] != "rw-env-1" {
+		t.Fatalf("unexpected destroyed environments %v", rw.destroyedEnvs)
+	}
+	if len(rw.deletedVolumes) != 1 || rw.deletedVolumes[0] != "rw-vol-1" {
+		t.Fatalf("unexpected deleted volumes %v", rw.deletedVolumes)
+	}
+	var envs int64
+	db.Model(&store.Environment{}).Count(&envs)
+	db.Model(&store.Volume{}).Count(&volumes)
+	if envs != 0 || volumes != 0 {
+		t.Fatalf("expected records to be deleted, %d environments and %d volumes left", envs, volumes)
+	}
+}
+
+func TestDestroyEnvironment_AlreadyGoneFromRailway(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env := store.Environment{ID: "env-1", UserID: "u1", Name: "pr-1", Type: store.EnvironmentTypeEphemeral, RailwayEnvironmentID: "rw-env-1"}
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+
+	rw := &fakeEnvironmentDestroyer{destroyErr: errors.New("graphql: Environment not found")}
+	if err := DestroyEnvironment(context.Background(), db, rw, nil, &env); err != nil {
+		t.Fatalf("expected a missing railway environment to count as deleted, got %v", err)
+	}
+	var envs int64
+	db.Model(&store.Environment{}).Count(&envs)
+	if envs != 0 {
+		t.Fatalf("expected the environment record to be deleted, %d left", envs)
+	}
+}
@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// StartInterruptedJobSweeper starts a background loop that fails provisioning jobs whose
+// replica has stopped renewing its liveness lease and then deletes that lease, once at start
+// and then every interval. It returns a stop function to halt the loop.
+func StartInterruptedJobSweeper(ctx context.Context, db *gorm.DB, interval time.Duration) (stop func()) {
+	if db == nil {
+		log.Error().Msg("interrupted job sweeper not started: nil db")
+		return func() {}
+	}
+	if interval <= 0 {
+		log.Warn().Dur("provided_interval", interval).Msg("invalid sweeper interval; using minimum 1s")
+		interval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		log.Info().Dur("interval", interval).Msg("interrupted job sweeper started")
+		defer log.Info().Msg("interrupted job sweeper stopped")
+		for {
+			if n, err := store.FailInterruptedJobs(db.WithContext(ctx), time.Now()); err != nil {
+				if ctx.Err() == nil {
+					log.Error().Err(err).Msg("failed to mark interrupted provisioning jobs")
+				}
+			} else if n > 0 {
+				log.Warn().Int64("count", n).Msg("marked interrupted provisioning jobs as failed")
+			}
+			// Replicas that died never delete their liveness lease
+			if _, err := store.DeleteExpiredJobLeases(db.WithContext(ctx), replicaLeasePrefix, time.Now()); err != nil && ctx.Err() == nil {
+				log.Warn().Err(err).Msg("failed to delete expired replica leases")
+			}
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// DefaultLeaseTTL is how long a background job lease lasts without renewal.
+const DefaultLeaseTTL = 30 * time.Second
+
+// Lease names of the background jobs that must run on a single replica
+const (
+	LeaseStatusPoller    = "status_poller"
+	LeaseTTLReaper       = "ttl_reaper"
+	LeaseDriftReconciler = "drift_reconciler"
+	LeaseJobSweeper      = "job_sweeper"
+)
+
+// replicaLeasePrefix names the per-replica liveness leases every replica holds while it runs.
+const replicaLeasePrefix = "replica:"
+
+// leaseRenewDivisor sets how often leases are renewed relative to their TTL, leaving room
+// for a couple of missed renewals before the lease lapses.
+const leaseRenewDivisor = 3
+
+// Leases runs named background jobs on at most one API replica at a time, using DB-backed
+// leases. A replica that loses its lease stops the job; another replica starts it once the
+// previous holder's lease expires or is released.
+type Leases struct {
+	db     *gorm.DB
+	holder string
+	ttl    time.Duration
+
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewLeases creates a lease manager for this replica. holder must be unique per replica.
+func NewLeases(db *gorm.DB, holder string, ttl time.Duration) *Leases {
+	if ttl <= 0 {
+		log.Warn().Dur("provided_ttl", ttl).Dur("default", DefaultLeaseTTL).Msg("invalid lease ttl; using default")
+		ttl = DefaultLeaseTTL
+	}
+	return &Leases{db: db, holder: holder, ttl: ttl, held: make(map[string]bool)}
+}
+
+// NewLeaseHolderID returns an ID for this process, e.g. "api-7f9c-1a2b3c4d".
+func NewLeaseHolderID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "mirage-api"
+	}
+	return fmt.Sprintf("%s-%s", host, uuid.New().String()[:8])
+}
+
+// Holder returns this replica's lease holder ID.
+func (l *Leases) Holder() string {
+	return l.holder
+}
+
+// Heartbeat holds this replica's own liveness lease until stop is called, so other replicas
+// can tell it is still alive and leave its provisioning jobs alone. The lease is named after
+// this process, so stop deletes it rather than leaving it for another holder.
+func (l *Leases) Heartbeat(ctx context.Context) (stop func()) {
+	name := replicaLeasePrefix + l.holder
+	stopRun := l.Run(ctx, name, func(ctx context.Context) func() {
+		return func() {}
+	})
+	return func() {
+		stopRun()
+		if err := store.DeleteJobLease(l.db, name); err != nil {
+			log.Warn().Err(err).Str("lease", name).Msg("failed to delete replica lease")
+		}
+	}
+}
+
+// Holds reports whether this replica currently runs the job called name.
+func (l *Leases) Holds(name string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held[name]
+}
+
+// Run keeps trying to acquire the lease called name and calls start while this replica holds
+// it. start must launch the job and return its stop function; the job's ctx is cancelled
+// when the lease is lost. The returned stop function halts the loop, stops the job and
+// releases the lease.
+func (l *Leases) Run(ctx context.Context, name string, start func(ctx context.Context) (stop func())) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		var jobStop func()
+		var heldUntil time.Time
+		stopJob := func(reason string) {
+			if jobStop == nil {
+				return
+			}
+			jobStop()
+			jobStop = nil
+			l.setHeld(name, false)
+			log.Info().Str("lease", name).Str("holder", l.holder).Str("reason", reason).Msg("stopped leased job")
+		}
+
+		ticker := time.NewTicker(l.ttl / leaseRenewDivisor)
+		defer ticker.Stop()
+		for {
+			now := time.Now()
+			held, err := store.AcquireJobLease(l.db.WithContext(ctx), name, l.holder, l.ttl, now)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Error().Err(err).Str("lease", name).Msg("failed to acquire or renew job lease")
+				}
+				// Keep running on what we last secured; once it lapses another replica may take over
+				held = jobStop != nil && now.Before(heldUntil)
+			} else if held {
+				heldUntil = now.Add(l.ttl)
+			}
+			switch {
+			case held && jobStop == nil:
+				log.Info().Str("lease", name).Str("holder", l.holder).Msg("acquired job lease; starting job")
+				jobCtx, cancelJob := context.WithCancel(ctx)
+				stopStarted := start(jobCtx)
+				jobStop = func() {
+					stopStarted()
+					cancelJob()
+				}
+				l.setHeld(name, true)
+			case !held && jobStop != nil:
+				stopJob("lease lost")
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				wasHeld := jobStop != nil
+				stopJob("shutdown")
+				if wasHeld {
+					// Hand over immediately instead of making other replicas wait for expiry
+					if err := store.ReleaseJobLease(l.db, name, l.holder); err != nil {
+						log.Warn().Err(err).Str("lease", name).Msg("failed to release job lease")
+					}
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (l *Leases) setHeld(name string, held bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held[name] = held
+}
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting: %s", msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLeases_OnlyOneReplicaRunsJobAndFailsOver(t *testing.T) {
+	// A file database shared by both "replicas"; :memory: would give each connection its own DB
+	db, err := store.Open(filepath.Join(t.TempDir(), "leases.db"))
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	var running atomic.Int32
+	start := func(ctx context.Context) func() {
+		running.Add(1)
+		return func() { running.Add(-1) }
+	}
+
+	ttl := 150 * time.Millisecond
+	a := NewLeases(db, "replica-a", ttl)
+	b := NewLeases(db, "replica-b", ttl)
+
+	stopA := a.Run(context.Background(), "status_poller", start)
+	waitFor(t, func() bool { return a.Holds("status_poller") }, "replica a to acquire the lease")
+	stopB := b.Run(context.Background(), "status_poller", start)
+
+	time.Sleep(2 * ttl)
+	if b.Holds("status_poller") || running.Load() != 1 {
+		t.Fatalf("expected only replica a to run the job, running=%d", running.Load())
+	}
+
+	stopA()
+	waitFor(t, func() bool { return b.Holds("status_poller") }, "replica b to take over")
+	if running.Load() != 1 {
+		t.Fatalf("expected exactly one running job after failover, got %d", running.Load())
+	}
+
+	stopB()
+	if running.Load() != 0 {
+		t.Fatalf("expected job stopped on shutdown, got %d running", running.Load())
+	}
+}
+
+func TestLeases_ReplicaLeasesAreDeleted(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	now := time.Now()
+	if _, err := store.AcquireJobLease(db, replicaLeasePrefix+"crashed", "crashed", time.Second, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+	if _, err := store.AcquireJobLease(db, LeaseStatusPoller, "crashed", time.Second, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("acquire failed: %v", err)
+	}
+
+	l := NewLeases(db, "replica-a", time.Minute)
+	stop := l.Heartbeat(context.Background())
+	waitFor(t, func() bool { return l.Holds(replicaLeasePrefix + "replica-a") }, "replica lease to be acquired")
+
+	stopSweeper := StartInterruptedJobSweeper(context.Background(), db, time.Hour)
+	waitFor(t, func() bool {
+		leases, _ := store.ListJobLeases(db)
+		return len(leases) == 2
+	}, "expired replica lease to be deleted")
+	stopSweeper()
+
+	stop()
+	leases, err := store.ListJobLeases(db)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(leases) != 1 || leases[0].Name != LeaseStatusPoller {
+		t.Fatalf("expected only the status poller lease to remain, got %+v", leases)
+	}
+}
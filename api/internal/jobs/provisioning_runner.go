@@ -0,0 +1,188 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// DefaultProvisioningJobTimeout bounds how long a single provisioning job may run.
+const DefaultProvisioningJobTimeout = 30 * time.Minute
+
+// JobPublisher allows emitting provisioning job updates to downstream consumers (e.g., websockets).
+type JobPublisher interface {
+	PublishJobUpdated(job store.ProvisioningJob)
+}
+
+// ProvisioningStep is one unit of work in a provisioning job. Run's output is stored on the
+// step, even when it fails, and may be nil. Steps share state through closures, so later steps can use earlier results.
+type ProvisioningStep struct {
+	Name string
+	Run  func(ctx context.Context) (interface{}, error)
+}
+
+// ProvisioningRunner executes provisioning jobs in the background, persisting each step's
+// progress and publishing every change.
+type ProvisioningRunner struct {
+	ctx       context.Context
+	db        *gorm.DB
+	publisher JobPublisher
+	holder    string
+	timeout   time.Duration
+	wg        sync.WaitGroup
+}
+
+// NewProvisioningRunner creates a runner whose jobs are cancelled when ctx is done. holder is
+// this replica's lease holder ID, recorded on its jobs so other replicas can tell whether they
+// are still running. A nil publisher disables update notifications.
+func NewProvisioningRunner(ctx context.Context, db *gorm.DB, publisher JobPublisher, holder string) *ProvisioningRunner {
+	return &ProvisioningRunner{ctx: ctx, db: db, publisher: publisher, holder: holder, timeout: DefaultProvisioningJobTimeout}
+}
+
+// Holder returns the lease holder ID recorded on the jobs this runner executes.
+func (r *ProvisioningRunner) Holder() string {
+	return r.holder
+}
+
+// NewProvisioningJob builds a pending job for userID with one pending step per name.
+// The caller persists it before handing it to the runner.
+func NewProvisioningJob(userID string, request interface{}, stepNames []string) (store.ProvisioningJob, error) {
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return store.ProvisioningJob{}, fmt.Errorf("marshal job request: %w", err)
+	}
+	now := time.Now()
+	job := store.ProvisioningJob{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Status:      store.JobStatusPending,
+		RequestJSON: requestJSON,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	steps := make([]store.JobStep, 0, len(stepNames))
+	for _, name := range stepNames {
+		steps = append(steps, store.JobStep{Name: name, Status: store.JobStatusPending})
+	}
+	if err := job.SetSteps(steps); err != nil {
+		return store.ProvisioningJob{}, err
+	}
+	return job, nil
+}
+
+// Start runs job in the background. steps must match the job's step names in order.
+func (r *ProvisioningRunner) Start(job store.ProvisioningJob, steps []ProvisioningStep) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.Run(job, steps)
+	}()
+}
+
+// Wait blocks until every started job has finished.
+func (r *ProvisioningRunner) Wait() {
+	r.wg.Wait()
+}
+
+// Run executes steps in order and returns the finished job. The first failing step fails the
+// job and the remaining steps are skipped.
+func (r *ProvisioningRunner) Run(job store.ProvisioningJob, steps []ProvisioningStep) store.ProvisioningJob {
+	ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+	defer cancel()
+
+	records, err := job.Steps()
+	if err != nil || len(records) != len(steps) {
+		return r.finish(job, records, fmt.Errorf("job steps do not match runner steps"))
+	}
+
+	now := time.Now()
+	job.Status = store.JobStatusRunning
+	job.StartedAt = &now
+	r.save(&job, records)
+
+	for i, step := range steps {
+		started := time.Now()
+		records[i].Status = store.JobStatusRunning
+		records[i].StartedAt = &started
+		job.CurrentStep = step.Name
+		r.save(&job, records)
+
+		output, err := runStep(ctx, step)
+		finished := time.Now()
+		records[i].FinishedAt = &finished
+		if output != nil {
+			if data, mErr := json.Marshal(output); mErr == nil {
+				records[i].Output = data
+			} else {
+				log.Warn().Err(mErr).Str("job_id", job.ID).Str("step", step.Name).Msg("failed to encode step output")
+			}
+		}
+		if err != nil {
+			records[i].Status = store.JobStatusFailed
+			records[i].Error = err.Error()
+			for j := i + 1; j < len(records); j++ {
+				records[j].Status = store.JobStatusSkipped
+			}
+			return r.finish(job, records, fmt.Errorf("%s: %w", step.Name, err))
+		}
+		records[i].Status = store.JobStatusSucceeded
+	}
+	return r.finish(job, records, nil)
+}
+
+// runStep runs a step, converting a panic into an error so one bad job can't crash the server.
+func runStep(ctx context.Context, step ProvisioningStep) (output interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return step.Run(ctx)
+}
+
+func (r *ProvisioningRunner) finish(job store.ProvisioningJob, records []store.JobStep, err error) store.ProvisioningJob {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.CurrentStep = ""
+	if err != nil {
+		msg := err.Error()
+		job.Status = store.JobStatusFailed
+		job.Error = &msg
+		log.Error().Err(err).Str("job_id", job.ID).Str("user_id", job.UserID).Msg("provisioning job failed")
+	} else {
+		job.Status = store.JobStatusSucceeded
+		log.Info().Str("job_id", job.ID).Str("user_id", job.UserID).Msg("provisioning job succeeded")
+	}
+	r.save(&job, records)
+	return job
+}
+
+// save persists the job with its steps and publishes the new state. Persistence errors are
+// logged rather than returned so a database hiccup doesn't abort provisioning midway.
+func (r *ProvisioningRunner) save(job *store.ProvisioningJob, records []store.JobStep) {
+	if err := job.SetSteps(records); err != nil {
+		log.Error().Err(err).Str("job_id", job.ID).Msg("failed to encode job steps")
+	}
+	job.UpdatedAt = time.Now()
+	// Only update a job that is still open, so one already failed as interrupted stays failed
+	res := r.db.Model(job).
+		Where("status IN ?", []store.JobStatus{store.JobStatusPending, store.JobStatusRunning}).
+		Select("*").Omit("User").
+		Updates(job)
+	if res.Error != nil {
+		log.Error().Err(res.Error).Str("job_id", job.ID).Msg("failed to persist provisioning job")
+	} else if res.RowsAffected == 0 {
+		log.Warn().Str("job_id", job.ID).Msg("provisioning job already finished elsewhere; dropping update")
+		return
+	}
+	if r.publisher != nil {
+		r.publisher.PublishJobUpdated(*job)
+	}
+}
@@ -0,0 +1,172 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+type recordingJobPublisher struct {
+	updates []store.ProvisioningJob
+}
+
+func (p *recordingJobPublisher) PublishJobUpdated(job store.ProvisioningJob) {
+	p.updates = append(p.updates, job)
+}
+
+func newRunnerTestJob(t *testing.T, stepNames []string) (*ProvisioningRunner, *recordingJobPublisher, store.ProvisioningJob) {
+	t.Helper()
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if err := db.Create(&store.User{ID: "u1", ClerkUserID: "clerk_u1", Email: "u1@example.com", IsActive: true}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	job, err := NewProvisioningJob("u1", map[string]string{"name": "demo"}, stepNames)
+	if err != nil {
+		t.Fatalf("new job failed: %v", err)
+	}
+	job.Holder = "replica-a"
+	if err := db.Create(&job).Error; err != nil {
+		t.Fatalf("create job failed: %v", err)
+	}
+	pub := &recordingJobPublisher{}
+	return NewProvisioningRunner(context.Background(), db, pub, "replica-a"), pub, job
+}
+
+func TestProvisioningRunner_Succeeds(t *testing.T) {
+	runner, pub, job := newRunnerTestJob(t, []string{"project", "environment"})
+
+	var projectID string
+	finished := runner.Run(job, []ProvisioningStep{
+		{Name: "project", Run: func(ctx context.Context) (interface{}, error) {
+			projectID = "proj-1"
+			return map[string]string{"projectId": projectID}, nil
+		}},
+		{Name: "environment", Run: func(ctx context.Context) (interface{}, error) {
+			if projectID != "proj-1" {
+				return nil, errors.New("project step output not visible")
+			}
+			return nil, nil
+		}},
+	})
+
+	if finished.Status != store.JobStatusSucceeded || finished.Error != nil {
+		t.Fatalf("expected succeeded job, got status=%s error=%v", finished.Status, finished.Error)
+	}
+
+	var stored store.ProvisioningJob
+	if err := runner.db.First(&stored, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("load job failed: %v", err)
+	}
+	steps, err := stored.Steps()
+	if err != nil {
+		t.Fatalf("decode steps failed: %v", err)
+	}
+	if stored.Status != store.JobStatusSucceeded || stored.StartedAt == nil || stored.FinishedAt == nil {
+		t.Fatalf("expected persisted succeeded job with timestamps, got %+v", stored)
+	}
+	for _, s := range steps {
+		if s.Status != store.JobStatusSucceeded {
+			t.Fatalf("expected step %s succeeded, got %s", s.Name, s.Status)
+		}
+	}
+	if string(steps[0].Output) != `{"projectId":"proj-1"}` {
+		t.Fatalf("unexpected project output: %s", steps[0].Output)
+	}
+
+	// running + 2 steps started + finished
+	if len(pub.updates) != 4 {
+		t.Fatalf("expected 4 published updates, got %d", len(pub.updates))
+	}
+	if last := pub.updates[len(pub.updates)-1]; last.Status != store.JobStatusSucceeded {
+		t.Fatalf("expected last update to be terminal, got %s", last.Status)
+	}
+}
+
+func TestProvisioningRunner_FailureSkipsRemainingSteps(t *testing.T) {
+	runner, _, job := newRunnerTestJob(t, []string{"project", "environment", "services"})
+
+	ranServices := false
+	finished := runner.Run(job, []ProvisioningStep{
+		{Name: "project", Run: func(ctx context.Context) (interface{}, error) { return nil, nil }},
+		{Name: "environment", Run: func(ctx context.Context) (interface{}, error) { return nil, errors.New("railway down") }},
+		{Name: "services", Run: func(ctx context.Context) (interface{}, error) { ranServices = true; return nil, nil }},
+	})
+
+	if ranServices {
+		t.Fatalf("expected services step not to run")
+	}
+	if finished.Status != store.JobStatusFailed || finished.Error == nil || *finished.Error != "environment: railway down" {
+		t.Fatalf("expected failed job with step error, got status=%s error=%v", finished.Status, finished.Error)
+	}
+	steps, _ := finished.Steps()
+	want := []store.JobStatus{store.JobStatusSucceeded, store.JobStatusFailed, store.JobStatusSkipped}
+	for i, s := range steps {
+		if s.Status != want[i] {
+			t.Fatalf("step %s: expected %s, got %s", s.Name, want[i], s.Status)
+		}
+	}
+}
+
+func TestProvisioningRunner_RecoversPanickingStep(t *testing.T) {
+	runner, _, job := newRunnerTestJob(t, []string{"project"})
+
+	finished := runner.Run(job, []ProvisioningStep{
+		{Name: "project", Run: func(ctx context.Context) (interface{}, error) { panic("boom") }},
+	})
+
+	if finished.Status != store.JobStatusFailed {
+		t.Fatalf("expected failed job, got %s", finished.Status)
+	}
+}
+
+func TestFailInterruptedJobs_SkipsJobsOfLiveReplicas(t *testing.T) {
+	runner, pub, job := newRunnerTestJob(t, []string{"project"})
+	now := job.CreatedAt
+	if _, err := store.AcquireJobLease(runner.db, replicaLeasePrefix+"replica-a", "replica-a", time.Minute, now); err != nil {
+		t.Fatalf("acquire lease failed: %v", err)
+	}
+
+	n, err := store.FailInterruptedJobs(runner.db, now)
+	if err != nil {
+		t.Fatalf("fail interrupted failed: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected the live replica's job to be left alone, failed %d", n)
+	}
+
+	// Once the replica's lease lapses its job can never finish
+	n, err = store.FailInterruptedJobs(runner.db, now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("fail interrupted failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 interrupted job, got %d", n)
+	}
+	var stored store.ProvisioningJob
+	if err := runner.db.First(&stored, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("load job failed: %v", err)
+	}
+	if stored.Status != store.JobStatusFailed || stored.Error == nil {
+		t.Fatalf("expected failed job with reason, got %+v", stored)
+	}
+
+	// A runner that was only slow must not flip the job back
+	runner.Run(job, []ProvisioningStep{
+		{Name: "project", Run: func(ctx context.Context) (interface{}, error) { return nil, nil }},
+	})
+	if err := runner.db.First(&stored, "id = ?", job.ID).Error; err != nil {
+		t.Fatalf("load job failed: %v", err)
+	}
+	if stored.Status != store.JobStatusFailed {
+		t.Fatalf("expected job to stay failed, got %s", stored.Status)
+	}
+	if len(pub.updates) != 0 {
+		t.Fatalf("expected no updates published for a finished job, got %d", len(pub.updates))
+	}
+}
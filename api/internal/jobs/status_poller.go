@@ -2,6 +2,7 @@ package jobs
 
 import (
 	"context"
+	"errors"
 	"math"
 	"math/rand"
 	"time"
@@ -10,12 +11,14 @@ import (
 	"github.com/stwalsh4118/mirageapi/internal/railway"
 	"github.com/stwalsh4118/mirageapi/internal/status"
 	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
 	"gorm.io/gorm"
 )
 
 // EnvironmentPublisher allows emitting updates to downstream consumers (e.g., websockets).
 type EnvironmentPublisher interface {
 	PublishEnvironmentUpdated(environmentID string, newStatus string)
+	PublishServiceUpdated(environmentID string, serviceID string, newStatus string)
 }
 
 // LogPublisher is a minimal publisher that logs updates.
@@ -25,14 +28,28 @@ func (LogPublisher) PublishEnvironmentUpdated(environmentID string, newStatus st
 	log.Info().Str("env_id", environmentID).Str("status", newStatus).Msg("environment status updated")
 }
 
-// StartStatusPoller starts a background loop that periodically polls Railway for environment statuses
-// and reconciles them with the local database. It returns a stop function to halt the loop.
+func (LogPublisher) PublishServiceUpdated(environmentID string, serviceID string, newStatus string) {
+	log.Info().Str("env_id", environmentID).Str("service_id", serviceID).Str("status", newStatus).Msg("service status updated")
+}
+
+// statusSource is the subset of the Railway client the poller reads statuses from.
+type statusSource interface {
+	GetEnvironmentStatus(ctx context.Context, environmentID string) (string, error)
+	GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error)
+}
+
+// StartStatusPoller starts a background loop that periodically polls Railway for environment and
+// service deployment statuses and reconciles them with the local database. Environments are polled
+// with their owner's Railway client (Vault-backed when vaultClient is set); owners whose client or
+// requests fail are backed off exponentially up to maxBackoff. It returns a stop function to halt the loop.
 func StartStatusPoller(
 	ctx context.Context,
 	db *gorm.DB,
 	rw *railway.Client,
+	vaultClient *vault.Client,
 	interval time.Duration,
 	jitterFraction float64,
+	maxBackoff time.Duration,
 	publisher EnvironmentPublisher,
 ) (stop func()) {
 	// Validate dependencies first
@@ -51,16 +68,25 @@ func StartStatusPoller(
 	}
 	jitter := clampJitter(jitterFraction)
 
+	p := &statusPoller{
+		db: db,
+		clientFor: func(ctx context.Context, userID string) (statusSource, error) {
+			return railway.GetRailwayClientForUser(ctx, userID, vaultClient, rw)
+		},
+		publisher: publisher,
+		backoff:   newUserBackoff(interval, maxBackoff),
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	go func() {
-		log.Info().Dur("interval", interval).Float64("jitter_fraction", jitter).Msg("status poller started")
+		log.Info().Dur("interval", interval).Float64("jitter_fraction", jitter).Dur("max_backoff", p.backoff.max).Msg("status poller started")
 		defer log.Info().Msg("status poller stopped")
 		for {
 			// Sleep for interval with jitter
 			d := addJitter(interval, jitter)
 			select {
 			case <-time.After(d):
-				if err := pollOnce(ctx, db, rw, publisher); err != nil {
+				if err := p.pollOnce(ctx, time.Now()); err != nil {
 					log.Error().Err(err).Msg("status poller iteration failed")
 				}
 			case <-ctx.Done():
@@ -81,17 +107,66 @@ func clampJitter(fraction float64) float64 {
 	return fraction
 }
 
-func pollOnce(ctx context.Context, db *gorm.DB, rw *railway.Client, publisher EnvironmentPublisher) error {
+// statusPoller reconciles statuses for every user's environments using that user's Railway client.
+type statusPoller struct {
+	db        *gorm.DB
+	clientFor func(ctx context.Context, userID string) (statusSource, error)
+	publisher EnvironmentPublisher
+	backoff   *userBackoff
+}
+
+func (p *statusPoller) pollOnce(ctx context.Context, now time.Time) error {
 	var envs []store.Environment
-	if err := db.WithContext(ctx).Where("railway_environment_id <> ''").Find(&envs).Error; err != nil {
+	if err := p.db.WithContext(ctx).Where("railway_environment_id <> ''").Order("user_id").Find(&envs).Error; err != nil {
 		return err
 	}
+
+	byUser := make(map[string][]store.Environment)
+	var users []string
+	for _, e := range envs {
+		if _, ok := byUser[e.UserID]; !ok {
+			users = append(users, e.UserID)
+		}
+		byUser[e.UserID] = append(byUser[e.UserID], e)
+	}
+
+	for _, userID := range users {
+		if !p.backoff.ready(userID, now) {
+			continue
+		}
+		rw, err := p.clientFor(ctx, userID)
+		if err != nil {
+			delay := p.backoff.fail(userID, now)
+			ev := log.Warn()
+			if errors.Is(err, railway.ErrNoRailwayToken) {
+				ev = log.Debug() // Expected for users who haven't connected Railway yet
+			}
+			ev.Err(err).Str("user_id", userID).Dur("retry_in", delay).Msg("skipping status poll: no railway client for user")
+			continue
+		}
+		if err := pollEnvironments(ctx, p.db, rw, byUser[userID], p.publisher); err != nil {
+			delay := p.backoff.fail(userID, now)
+			log.Warn().Err(err).Str("user_id", userID).Dur("retry_in", delay).Msg("status poll failed for user; backing off")
+			continue
+		}
+		p.backoff.succeed(userID)
+	}
+	return nil
+}
+
+// pollEnvironments reconciles one user's environments and their services. It returns an error
+// only when every Railway request failed, which usually means the user's token is unusable.
+func pollEnvironments(ctx context.Context, db *gorm.DB, rw statusSource, envs []store.Environment, publisher EnvironmentPublisher) error {
+	var lastErr error
+	succeeded := 0
 	for _, e := range envs {
 		status, err := rw.GetEnvironmentStatus(ctx, e.RailwayEnvironmentID)
 		if err != nil {
 			log.Error().Err(err).Str("env_id", e.ID).Msg("failed to fetch remote status")
+			lastErr = err
 			continue
 		}
+		succeeded++
 		changed, newStatus := reconcileEnvironmentStatus(e.Status, status)
 		if !changed {
 			continue
@@ -102,9 +177,83 @@ func pollOnce(ctx context.Context, db *gorm.DB, rw *railway.Client, publisher En
 		}
 		publisher.PublishEnvironmentUpdated(e.ID, newStatus)
 	}
+	pollServiceStatuses(ctx, db, rw, envs, publisher)
+	if succeeded == 0 && lastErr != nil {
+		return lastErr
+	}
 	return nil
 }
 
+// pollServiceStatuses reconciles each service's status with its latest Railway deployment.
+// Project details carry every environment's deployments, so each project is fetched once.
+func pollServiceStatuses(ctx context.Context, db *gorm.DB, rw statusSource, envs []store.Environment, publisher EnvironmentPublisher) {
+	byProject := make(map[string][]store.Environment)
+	for _, e := range envs {
+		if e.RailwayProjectID == "" {
+			continue
+		}
+		byProject[e.RailwayProjectID] = append(byProject[e.RailwayProjectID], e)
+	}
+
+	for projectID, projectEnvs := range byProject {
+		details, err := rw.GetProjectWithDetailsByID(ctx, projectID)
+		if err != nil {
+			log.Error().Err(err).Str("project_id", projectID).Msg("failed to fetch project deployments")
+			continue
+		}
+		deployments := latestDeploymentStatuses(details)
+
+		for _, e := range projectEnvs {
+			var services []store.Service
+			if err := db.WithContext(ctx).Where("environment_id = ? AND railway_service_id <> ''", e.ID).Find(&services).Error; err != nil {
+				log.Error().Err(err).Str("env_id", e.ID).Msg("failed to load services for status poll")
+				continue
+			}
+			for _, svc := range services {
+				remote, ok := deployments[e.RailwayEnvironmentID][svc.RailwayServiceID]
+				if !ok {
+					continue // Not deployed yet
+				}
+				changed, newStatus := reconcileServiceStatus(svc.Status, remote)
+				if !changed {
+					continue
+				}
+				if err := db.WithContext(ctx).Model(&svc).Update("status", newStatus).Error; err != nil {
+					log.Error().Err(err).Str("service_id", svc.ID).Str("status", newStatus).Msg("failed to update service status")
+					continue
+				}
+				publisher.PublishServiceUpdated(e.ID, svc.ID, newStatus)
+			}
+		}
+	}
+}
+
+// latestDeploymentStatuses indexes latest deployment statuses by Railway environment ID,
+// then Railway service ID. Services without a deployment are omitted.
+func latestDeploymentStatuses(details railway.ProjectDetails) map[string]map[string]string {
+	out := make(map[string]map[string]string, len(details.Environments))
+	for _, env := range details.Environments {
+		statuses := make(map[string]string, len(env.Services))
+		for _, si := range env.Services {
+			if si.LatestDeployment != nil && si.LatestDeployment.Status != nil {
+				statuses[si.ServiceID] = *si.LatestDeployment.Status
+			}
+		}
+		out[env.ID] = statuses
+	}
+	return out
+}
+
+// reconcileServiceStatus compares a service's local status with its latest Railway deployment
+// status and returns whether a change should be applied and the normalized target status.
+func reconcileServiceStatus(local string, deploymentStatus string) (bool, string) {
+	n := status.NormalizeDeploymentToUI(deploymentStatus)
+	if n == status.NormalizeLocalToUI(local) {
+		return false, local
+	}
+	return true, n
+}
+
 // reconcileEnvironmentStatus compares local and remote status strings and returns whether
 // a change should be applied and the normalized target status.
 func reconcileEnvironmentStatus(local string, remote string) (bool, string) {
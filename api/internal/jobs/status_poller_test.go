@@ -1,6 +1,14 @@
 package jobs
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
 
 func TestReconcileEnvironmentStatus_NoChangeWhenSameAfterNormalization(t *testing.T) {
 	changed, target := reconcileEnvironmentStatus("active", "running")
@@ -15,3 +23,181 @@ func TestReconcileEnvironmentStatus_ChangeWhenDifferent(t *testing.T) {
 		t.Fatalf("expected change to active, got changed=%v target=%q", changed, target)
 	}
 }
+
+type fakeStatusSource struct {
+	envStatus string
+	envErr    error
+	details   railway.ProjectDetails
+	polled    []string
+}
+
+func (f *fakeStatusSource) GetEnvironmentStatus(ctx context.Context, environmentID string) (string, error) {
+	f.polled = append(f.polled, environmentID)
+	return f.envStatus, f.envErr
+}
+
+func (f *fakeStatusSource) GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error) {
+	return f.details, nil
+}
+
+type recordingEnvironmentPublisher struct {
+	envUpdates     []string
+	serviceUpdates []string
+}
+
+func (p *recordingEnvironmentPublisher) PublishEnvironmentUpdated(environmentID string, newStatus string) {
+	p.envUpdates = append(p.envUpdates, environmentID+"="+newStatus)
+}
+
+func (p *recordingEnvironmentPublisher) PublishServiceUpdated(environmentID string, serviceID string, newStatus string) {
+	p.serviceUpdates = append(p.serviceUpdates, serviceID+"="+newStatus)
+}
+
+func TestReconcileServiceStatus(t *testing.T) {
+	if changed, _ := reconcileServiceStatus("provisioning", "DEPLOYING"); changed {
+		t.Fatalf("expected no change while still deploying")
+	}
+	if changed, target := reconcileServiceStatus("active", "CRASHED"); !changed || target != "error" {
+		t.Fatalf("expected change to error, got changed=%v target=%q", changed, target)
+	}
+}
+
+func TestPollEnvironments_UpdatesServiceStatusFromLatestDeployment(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env := store.Environment{ID: "env-1", UserID: "u1", Name: "dev", Type: store.EnvironmentTypeDev, Status: "active", RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-env"}
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	services := []store.Service{
+		{ID: "svc-api", UserID: "u1", EnvironmentID: "env-1", Name: "api", Status: "active", RailwayServiceID: "rw-api"},
+		{ID: "svc-web", UserID: "u1", EnvironmentID: "env-1", Name: "web", Status: "active", RailwayServiceID: "rw-web"},
+		{ID: "svc-new", UserID: "u1", EnvironmentID: "env-1", Name: "new", Status: "provisioning", RailwayServiceID: "rw-new"},
+	}
+	for i := range services {
+		if err := db.Create(&services[i]).Error; err != nil {
+			t.Fatalf("create service failed: %v", err)
+		}
+	}
+
+	crashed, success := "CRASHED", "SUCCESS"
+	rw := &fakeStatusSource{
+		envStatus: "active",
+		details: railway.ProjectDetails{Environments: []railway.ProjectEnvironment{{
+			ID: "rw-env",
+			Services: []railway.ServiceInstance{
+				{ServiceID: "rw-api", LatestDeployment: &railway.LatestDeployment{Status: &crashed}},
+				{ServiceID: "rw-web", LatestDeployment: &railway.LatestDeployment{Status: &success}},
+				{ServiceID: "rw-new"}, // No deployment yet
+			},
+		}}},
+	}
+	pub := &recordingEnvironmentPublisher{}
+
+	if err := pollEnvironments(context.Background(), db, rw, []store.Environment{env}, pub); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+
+	if len(pub.envUpdates) != 0 {
+		t.Fatalf("expected no environment updates, got %v", pub.envUpdates)
+	}
+	if len(pub.serviceUpdates) != 1 || pub.serviceUpdates[0] != "svc-api=error" {
+		t.Fatalf("expected only api to change to error, got %v", pub.serviceUpdates)
+	}
+	var api, fresh store.Service
+	db.First(&api, "id = ?", "svc-api")
+	db.First(&fresh, "id = ?", "svc-new")
+	if api.Status != "error" || fresh.Status != "provisioning" {
+		t.Fatalf("unexpected persisted statuses: api=%q new=%q", api.Status, fresh.Status)
+	}
+}
+
+func TestStatusPoller_UsesOwnerClientsAndBacksOffFailingUsers(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	envs := []store.Environment{
+		{ID: "env-a", UserID: "alice", Name: "a", Type: store.EnvironmentTypeDev, Status: "creating", RailwayEnvironmentID: "rw-a"},
+		{ID: "env-b", UserID: "bob", Name: "b", Type: store.EnvironmentTypeDev, Status: "creating", RailwayEnvironmentID: "rw-b"},
+	}
+	for i := range envs {
+		if err := db.Create(&envs[i]).Error; err != nil {
+			t.Fatalf("create env failed: %v", err)
+		}
+	}
+
+	alice := &fakeStatusSource{envStatus: "ready"}
+	lookups := map[string]int{}
+	pub := &recordingEnvironmentPublisher{}
+	p := &statusPoller{
+		db: db,
+		clientFor: func(ctx context.Context, userID string) (statusSource, error) {
+			lookups[userID]++
+			if userID == "alice" {
+				return alice, nil
+			}
+			return nil, railway.ErrNoRailwayToken
+		},
+		publisher: pub,
+		backoff:   newUserBackoff(time.Minute, 10*time.Minute),
+	}
+
+	now := time.Now()
+	if err := p.pollOnce(context.Background(), now); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if len(alice.polled) != 1 || alice.polled[0] != "rw-a" {
+		t.Fatalf("expected alice's client to poll only her environment, got %v", alice.polled)
+	}
+	if len(pub.envUpdates) != 1 || pub.envUpdates[0] != "env-a=active" {
+		t.Fatalf("expected env-a to become active, got %v", pub.envUpdates)
+	}
+
+	// Bob is skipped until his backoff elapses; Alice keeps being polled
+	if err := p.pollOnce(context.Background(), now.Add(30*time.Second)); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if lookups["bob"] != 1 || lookups["alice"] != 2 {
+		t.Fatalf("unexpected client lookups during backoff: %v", lookups)
+	}
+	if err := p.pollOnce(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatalf("poll failed: %v", err)
+	}
+	if lookups["bob"] != 2 {
+		t.Fatalf("expected bob retried after backoff, got %d lookups", lookups["bob"])
+	}
+}
+
+func TestPollEnvironments_FailsWhenEveryRequestFails(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	rw := &fakeStatusSource{envErr: errors.New("unauthorized")}
+	envs := []store.Environment{{ID: "env-1", RailwayEnvironmentID: "rw-1"}}
+
+	if err := pollEnvironments(context.Background(), db, rw, envs, LogPublisher{}); err == nil {
+		t.Fatalf("expected error when no request succeeds")
+	}
+}
+
+func TestUserBackoff_DoublesUpToMax(t *testing.T) {
+	b := newUserBackoff(time.Minute, 3*time.Minute)
+	now := time.Now()
+	want := []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for i, w := range want {
+		if got := b.fail("u", now); got != w {
+			t.Fatalf("failure %d: expected %s, got %s", i+1, w, got)
+		}
+	}
+	if b.ready("u", now.Add(2*time.Minute)) {
+		t.Fatalf("expected user to be backing off")
+	}
+	b.succeed("u")
+	if !b.ready("u", now) {
+		t.Fatalf("expected success to clear backoff")
+	}
+}
@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"github.com/stwalsh4118/mirageapi/internal/vault"
+	"gorm.io/gorm"
+)
+
+// StartTTLReaper starts a background loop that destroys environments whose ExpiresAt has passed.
+// Each environment is torn down in Railway, volumes included, using its owner's Vault-backed
+// client before the local records are removed. It returns a stop function to halt the loop.
+func StartTTLReaper(
+	ctx context.Context,
+	db *gorm.DB,
+	rw *railway.Client,
+	vaultClient *vault.Client,
+	interval time.Duration,
+) (stop func()) {
+	if db == nil || rw == nil {
+		log.Error().Msg("ttl reaper not started: nil dependency (db or railway client)")
+		return func() {}
+	}
+	if interval <= 0 {
+		log.Warn().Dur("provided_interval", interval).Msg("invalid reaper interval; using minimum 1s")
+		interval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		log.Info().Dur("interval", interval).Msg("ttl reaper started")
+		defer log.Info().Msg("ttl reaper stopped")
+		for {
+			select {
+			case <-time.After(interval):
+				if err := reapOnce(ctx, db, rw, vaultClient, time.Now()); err != nil {
+					log.Error().Err(err).Msg("ttl reaper iteration failed")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return cancel
+}
+
+func reapOnce(ctx context.Context, db *gorm.DB, rw *railway.Client, vaultClient *vault.Client, now time.Time) error {
+	expired, err := findExpiredEnvironments(ctx, db, now)
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		env := &expired[i]
+		rwClient, err := railway.GetRailwayClientForUser(ctx, env.UserID, vaultClient, rw)
+		if err != nil {
+			log.Warn().Err(err).Str("env_id", env.ID).Str("user_id", env.UserID).Msg("skipping expired environment: no railway client for owner")
+			continue
+		}
+
+		log.Info().
+			Str("env_id", env.ID).
+			Str("railway_env_id", env.RailwayEnvironmentID).
+			Str("user_id", env.UserID).
+			Msg("destroying expired environment")
+
+		if env.Status != status.StatusDestroying {
+			if err := db.WithContext(ctx).Model(env).Update("status", status.StatusDestroying).Error; err != nil {
+				log.Error().Err(err).Str("env_id", env.ID).Msg("failed to mark expired environment as destroying")
+			}
+		}
+
+		if err := DestroyEnvironment(ctx, db, rwClient, vaultClient, env); err != nil {
+			// Leave the records in place so the next iteration retries
+			log.Error().Err(err).Str("env_id", env.ID).Msg("failed to destroy expired environment")
+			continue
+		}
+		log.Info().Str("env_id", env.ID).Msg("reaped expired environment")
+	}
+	return nil
+}
+
+// findExpiredEnvironments returns environments whose expiry is at or before now.
+func findExpiredEnvironments(ctx context.Context, db *gorm.DB, now time.Time) ([]store.Environment, error) {
+	var expired []store.Environment
+	if err := db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at <= ?", now).Find(&expired).Error; err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
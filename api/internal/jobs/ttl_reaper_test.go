@@ -0,0 +1,42 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func TestFindExpiredEnvironments(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if err := db.Create(&store.User{ID: "u1", ClerkUserID: "clerk_u1", Email: "u1@example.com", IsActive: true}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+
+	now := time.Now()
+	created := now.Add(-2 * time.Minute)
+	short := int64(60)
+	long := int64(86400)
+	envs := []store.Environment{
+		{ID: "expired", UserID: "u1", Name: "expired", Type: store.EnvironmentTypeEphemeral, TTLSeconds: &short, ExpiresAt: store.ComputeExpiresAt(created, &short), CreatedAt: created},
+		{ID: "alive", UserID: "u1", Name: "alive", Type: store.EnvironmentTypeEphemeral, TTLSeconds: &long, ExpiresAt: store.ComputeExpiresAt(created, &long), CreatedAt: created},
+		{ID: "forever", UserID: "u1", Name: "forever", Type: store.EnvironmentTypeDev, CreatedAt: now.Add(-48 * time.Hour)},
+	}
+	for i := range envs {
+		if err := db.Create(&envs[i]).Error; err != nil {
+			t.Fatalf("create env failed: %v", err)
+		}
+	}
+
+	expired, err := findExpiredEnvironments(context.Background(), db, now)
+	if err != nil {
+		t.Fatalf("find expired failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ID != "expired" {
+		t.Fatalf("expected only 'expired' environment, got %+v", expired)
+	}
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+)
+
+// userBackoff tracks consecutive failures per user so a background loop can skip users whose
+// Railway access is broken, doubling the wait from base up to max after each failure.
+type userBackoff struct {
+	mu    sync.Mutex
+	base  time.Duration
+	max   time.Duration
+	state map[string]backoffState
+}
+
+type backoffState struct {
+	failures int
+	next     time.Time
+}
+
+func newUserBackoff(base, max time.Duration) *userBackoff {
+	if base <= 0 {
+		base = time.Second
+	}
+	if max < base {
+		max = base
+	}
+	return &userBackoff{base: base, max: max, state: make(map[string]backoffState)}
+}
+
+// ready reports whether userID may be attempted at now.
+func (b *userBackoff) ready(userID string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.state[userID]
+	return !ok || !now.Before(st.next)
+}
+
+// fail records a failure for userID and returns how long it will be skipped.
+func (b *userBackoff) fail(userID string, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.state[userID]
+	st.failures++
+	delay := b.base
+	for i := 1; i < st.failures && delay < b.max; i++ {
+		delay *= 2
+	}
+	if delay > b.max {
+		delay = b.max
+	}
+	st.next = now.Add(delay)
+	b.state[userID] = st
+	return delay
+}
+
+// succeed clears any backoff for userID.
+func (b *userBackoff) succeed(userID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.state, userID)
+}
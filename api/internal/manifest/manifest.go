@@ -0,0 +1,314 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersion is the manifest schema version written on export and required on apply.
+	APIVersion = "mirage/v1"
+	// KindEnvironment is the only supported manifest kind.
+	KindEnvironment = "Environment"
+
+	// FormatYAML and FormatJSON are the supported encodings.
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// variableNamePattern matches valid environment variable names.
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Manifest is a declarative, versionable description of an environment.
+//
+// Example:
+//
+//	apiVersion: mirage/v1
+//	kind: Environment
+//	name: feature-login
+//	projectId: 2f1c...
+//	ttlSeconds: 86400
+//	secretsFrom: 9a4e...
+//	variables:
+//	  LOG_LEVEL: debug
+//	  DATABASE_PASSWORD:
+//	    envSecretRef: DATABASE_PASSWORD
+//	services:
+//	  - name: api
+//	    repo: acme/api
+//	    branch: feature/login
+//	    dockerfilePath: services/api/Dockerfile
+//	    variables:
+//	      STRIPE_KEY:
+//	        secretRef: stripe-key
+//	  - name: cache
+//	    image: redis:7
+//	  - name: db
+//	    database: postgres
+type Manifest struct {
+	APIVersion  string              `yaml:"apiVersion" json:"apiVersion"`
+	Kind        string              `yaml:"kind" json:"kind"`
+	Name        string              `yaml:"name" json:"name"`
+	Type        string              `yaml:"type,omitempty" json:"type,omitempty"`
+	ProjectID   string              `yaml:"projectId,omitempty" json:"projectId,omitempty"`
+	TTLSeconds  *int64              `yaml:"ttlSeconds,omitempty" json:"ttlSeconds,omitempty"`
+	SecretsFrom string              `yaml:"secretsFrom,omitempty" json:"secretsFrom,omitempty"` // Railway environment ID whose environment secrets envSecretRef reads
+	Variables   map[string]Variable `yaml:"variables,omitempty" json:"variables,omitempty"`     // Shared by every service
+	Services    []Service           `yaml:"services" json:"services"`
+}
+
+// Service describes a single service. Exactly one of Repo, Image or Database must be set.
+type Service struct {
+	Name           string              `yaml:"name" json:"name"`
+	Repo           string              `yaml:"repo,omitempty" json:"repo,omitempty"`
+	Branch         string              `yaml:"branch,omitempty" json:"branch,omitempty"`
+	DockerfilePath string              `yaml:"dockerfilePath,omitempty" json:"dockerfilePath,omitempty"`
+	Image          string              `yaml:"image,omitempty" json:"image,omitempty"`       // Full reference, e.g. ghcr.io/acme/api:1.2
+	Database       string              `yaml:"database,omitempty" json:"database,omitempty"` // Database engine, e.g. postgres
+	Variables      map[string]Variable `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// Variable is either a literal value or a reference to a secret stored in Vault: SecretRef names
+// one of the user's secrets, EnvSecretRef an environment secret of the SecretsFrom environment.
+// In documents a literal is written as a plain string and a reference as {secretRef: key} or
+// {envSecretRef: key}.
+type Variable struct {
+	Value        string `yaml:"value,omitempty" json:"value,omitempty"`
+	SecretRef    string `yaml:"secretRef,omitempty" json:"secretRef,omitempty"`
+	EnvSecretRef string `yaml:"envSecretRef,omitempty" json:"envSecretRef,omitempty"`
+}
+
+// variableObject is the mapping form of Variable, used to avoid recursing into the custom codecs.
+type variableObject struct {
+	Value        string `yaml:"value,omitempty" json:"value,omitempty"`
+	SecretRef    string `yaml:"secretRef,omitempty" json:"secretRef,omitempty"`
+	EnvSecretRef string `yaml:"envSecretRef,omitempty" json:"envSecretRef,omitempty"`
+}
+
+// isLiteral reports whether v is a plain value rather than a reference.
+func (v Variable) isLiteral() bool {
+	return v.SecretRef == "" && v.EnvSecretRef == ""
+}
+
+// UnmarshalYAML accepts either a scalar value or a {value|secretRef|envSecretRef} mapping.
+func (v *Variable) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		v.Value = node.Value
+		return nil
+	}
+	var obj variableObject
+	if err := node.Decode(&obj); err != nil {
+		return err
+	}
+	*v = Variable(obj)
+	return nil
+}
+
+// MarshalYAML writes literals as plain strings and references as mappings.
+func (v Variable) MarshalYAML() (interface{}, error) {
+	if v.isLiteral() {
+		return v.Value, nil
+	}
+	return variableObject(v), nil
+}
+
+// UnmarshalJSON accepts either a string value or a {value|secretRef|envSecretRef} object.
+func (v *Variable) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v.Value = s
+		return nil
+	}
+	var obj variableObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*v = Variable(obj)
+	return nil
+}
+
+// MarshalJSON writes literals as strings and references as objects.
+func (v Variable) MarshalJSON() ([]byte, error) {
+	if v.isLiteral() {
+		return json.Marshal(v.Value)
+	}
+	return json.Marshal(variableObject(v))
+}
+
+// Parse decodes a YAML or JSON manifest. Unknown fields are rejected so typos don't
+// silently drop configuration. The result is not validated; call Validate.
+func Parse(data []byte) (Manifest, error) {
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return Manifest{}, fmt.Errorf("parse manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Encode serializes a manifest in the given format (FormatYAML or FormatJSON).
+func Encode(m Manifest, format string) ([]byte, error) {
+	switch format {
+	case FormatYAML, "":
+		return yaml.Marshal(m)
+	case FormatJSON:
+		return json.MarshalIndent(m, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported manifest format %q", format)
+	}
+}
+
+// Validate checks the manifest for structural errors and returns all of them joined.
+func (m Manifest) Validate() error {
+	var errs []error
+	if m.APIVersion != APIVersion {
+		errs = append(errs, fmt.Errorf("apiVersion must be %q", APIVersion))
+	}
+	if m.Kind != KindEnvironment {
+		errs = append(errs, fmt.Errorf("kind must be %q", KindEnvironment))
+	}
+	if strings.TrimSpace(m.Name) == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+	switch store.EnvironmentType(m.Type) {
+	case "", store.EnvironmentTypeDev, store.EnvironmentTypeStaging, store.EnvironmentTypeProd, store.EnvironmentTypeEphemeral:
+	default:
+		errs = append(errs, fmt.Errorf("type %q is not a known environment type", m.Type))
+	}
+	if m.TTLSeconds != nil && *m.TTLSeconds <= 0 {
+		errs = append(errs, errors.New("ttlSeconds must be positive"))
+	}
+	errs = append(errs, validateVariables("variables", m.Variables)...)
+
+	seen := make(map[string]bool, len(m.Services))
+	for i, s := range m.Services {
+		field := fmt.Sprintf("services[%d]", i)
+		if strings.TrimSpace(s.Name) == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", field))
+		} else if seen[s.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate service name %q", field, s.Name))
+		}
+		seen[s.Name] = true
+
+		hasRepo, hasImage, hasDatabase := s.Repo != "", s.Image != "", s.Database != ""
+		switch {
+		case hasRepo && hasImage, hasDatabase && (hasRepo || hasImage):
+			errs = append(errs, fmt.Errorf("%s: repo, image and database are mutually exclusive", field))
+		case !hasRepo && !hasImage && !hasDatabase:
+			errs = append(errs, fmt.Errorf("%s: one of repo, image or database is required", field))
+		case hasRepo && s.Branch == "":
+			errs = append(errs, fmt.Errorf("%s: branch is required with repo", field))
+		}
+		if s.DockerfilePath != "" && !hasRepo {
+			errs = append(errs, fmt.Errorf("%s: dockerfilePath requires repo", field))
+		}
+		if strings.Contains(s.Image, "@") {
+			errs = append(errs, fmt.Errorf("%s: image digests are not supported, use a tag", field))
+		}
+		errs = append(errs, validateVariables(field+".variables", s.Variables)...)
+	}
+	if m.SecretsFrom == "" && m.usesEnvSecretRefs() {
+		errs = append(errs, errors.New("secretsFrom is required with envSecretRef"))
+	}
+	return errors.Join(errs...)
+}
+
+// SecretRefs returns the distinct secret keys referenced anywhere in the manifest.
+func (m Manifest) SecretRefs() []string {
+	seen := make(map[string]bool)
+	var refs []string
+	collect := func(vars map[string]Variable) {
+		for _, v := range vars {
+			if v.SecretRef != "" && !seen[v.SecretRef] {
+				seen[v.SecretRef] = true
+				refs = append(refs, v.SecretRef)
+			}
+		}
+	}
+	collect(m.Variables)
+	for _, s := range m.Services {
+		collect(s.Variables)
+	}
+	return refs
+}
+
+// usesEnvSecretRefs reports whether any variable references an environment secret.
+func (m Manifest) usesEnvSecretRefs() bool {
+	uses := func(vars map[string]Variable) bool {
+		for _, v := range vars {
+			if v.EnvSecretRef != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if uses(m.Variables) {
+		return true
+	}
+	for _, s := range m.Services {
+		if uses(s.Variables) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns vars as plain values, looking secret references up in secrets and environment
+// secret references up in envSecrets.
+func Resolve(vars map[string]Variable, secrets, envSecrets map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(vars))
+	for name, v := range vars {
+		switch {
+		case v.SecretRef != "":
+			value, ok := secrets[v.SecretRef]
+			if !ok {
+				return nil, fmt.Errorf("variable %s: secret %q not found", name, v.SecretRef)
+			}
+			resolved[name] = value
+		case v.EnvSecretRef != "":
+			value, ok := envSecrets[v.EnvSecretRef]
+			if !ok {
+				return nil, fmt.Errorf("variable %s: environment secret %q not found", name, v.EnvSecretRef)
+			}
+			resolved[name] = value
+		default:
+			resolved[name] = v.Value
+		}
+	}
+	return resolved, nil
+}
+
+// SplitImage splits an image reference into name and tag. The tag is empty when the
+// reference has none; a registry port (host:5000/app) is not mistaken for a tag.
+func SplitImage(ref string) (name, tag string) {
+	slash := strings.LastIndex(ref, "/")
+	colon := strings.LastIndex(ref, ":")
+	if colon > slash {
+		return ref[:colon], ref[colon+1:]
+	}
+	return ref, ""
+}
+
+func validateVariables(field string, vars map[string]Variable) []error {
+	var errs []error
+	for name, v := range vars {
+		if !variableNamePattern.MatchString(name) {
+			errs = append(errs, fmt.Errorf("%s: %q is not a valid variable name", field, name))
+		}
+		if v.Value != "" && v.SecretRef != "" {
+			errs = append(errs, fmt.Errorf("%s.%s: value and secretRef are mutually exclusive", field, name))
+		}
+		if v.EnvSecretRef != "" && (v.Value != "" || v.SecretRef != "") {
+			errs = append(errs, fmt.Errorf("%s.%s: envSecretRef cannot be combined with value or secretRef", field, name))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,159 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleYAML = `
+apiVersion: mirage/v1
+kind: Environment
+name: feature-login
+projectId: proj-1
+ttlSeconds: 3600
+variables:
+  LOG_LEVEL: debug
+services:
+  - name: api
+    repo: acme/api
+    branch: feature/login
+    dockerfilePath: services/api/Dockerfile
+    variables:
+      PORT: "8080"
+      STRIPE_KEY:
+        secretRef: stripe-key
+  - name: cache
+    image: redis:7
+`
+
+func TestParse_YAMLWithSecretRefs(t *testing.T) {
+	m, err := Parse([]byte(sampleYAML))
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+
+	assert.Equal(t, "feature-login", m.Name)
+	require.NotNil(t, m.TTLSeconds)
+	assert.Equal(t, int64(3600), *m.TTLSeconds)
+	assert.Equal(t, Variable{Value: "debug"}, m.Variables["LOG_LEVEL"])
+	require.Len(t, m.Services, 2)
+	assert.Equal(t, Variable{Value: "8080"}, m.Services[0].Variables["PORT"])
+	assert.Equal(t, Variable{SecretRef: "stripe-key"}, m.Services[0].Variables["STRIPE_KEY"])
+	assert.Equal(t, []string{"stripe-key"}, m.SecretRefs())
+}
+
+func TestParse_JSON(t *testing.T) {
+	m, err := Parse([]byte(`{"apiVersion":"mirage/v1","kind":"Environment","name":"x","services":[{"name":"web","image":"nginx","variables":{"A":"1","B":{"secretRef":"b"}}}]}`))
+	require.NoError(t, err)
+	require.NoError(t, m.Validate())
+	assert.Equal(t, Variable{Value: "1"}, m.Services[0].Variables["A"])
+	assert.Equal(t, Variable{SecretRef: "b"}, m.Services[0].Variables["B"])
+}
+
+func TestParse_RejectsUnknownFields(t *testing.T) {
+	_, err := Parse([]byte("apiVersion: mirage/v1\nkind: Environment\nname: x\nservcies: []\n"))
+	assert.Error(t, err)
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	m, err := Parse([]byte(sampleYAML))
+	require.NoError(t, err)
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		data, err := Encode(m, format)
+		require.NoError(t, err, format)
+		back, err := Parse(data)
+		require.NoError(t, err, format)
+		assert.Equal(t, m, back, format)
+	}
+
+	_, err = Encode(m, "toml")
+	assert.Error(t, err)
+}
+
+func TestValidate_ReportsAllErrors(t *testing.T) {
+	ttl := int64(0)
+	m := Manifest{
+		APIVersion: "v0",
+		Kind:       KindEnvironment,
+		TTLSeconds: &ttl,
+		Services: []Service{
+			{Name: "a", Repo: "acme/a"},
+			{Name: "a", Repo: "acme/a", Branch: "main", Image: "nginx"},
+			{Name: "b", Image: "nginx@sha256:abc", DockerfilePath: "Dockerfile"},
+			{Name: "c", Image: "nginx", Variables: map[string]Variable{"bad-name": {Value: "x", SecretRef: "y"}}},
+		},
+	}
+
+	err := m.Validate()
+	require.Error(t, err)
+	for _, want := range []string{
+		"apiVersion",
+		"name is required",
+		"ttlSeconds must be positive",
+		"services[0]: branch is required",
+		"duplicate service name",
+		"mutually exclusive",
+		"dockerfilePath requires repo",
+		"digests are not supported",
+		"not a valid variable name",
+		"value and secretRef",
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestValidate_TypeAndEnvSecretRefs(t *testing.T) {
+	m := Manifest{
+		APIVersion: APIVersion,
+		Kind:       KindEnvironment,
+		Name:       "dev",
+		Type:       "sandbox",
+		Variables:  map[string]Variable{"DB_PASSWORD": {EnvSecretRef: "DB_PASSWORD"}},
+		Services:   []Service{{Name: "web", Image: "nginx"}},
+	}
+	err := m.Validate()
+	assert.ErrorContains(t, err, `type "sandbox" is not a known environment type`)
+	assert.ErrorContains(t, err, "secretsFrom is required")
+
+	m.Type, m.SecretsFrom = "staging", "rw-env-1"
+	assert.NoError(t, m.Validate())
+}
+
+func TestValidate_DatabaseService(t *testing.T) {
+	m := Manifest{APIVersion: APIVersion, Kind: KindEnvironment, Name: "dev", Services: []Service{{Name: "db", Database: "postgres"}}}
+	require.NoError(t, m.Validate())
+
+	m.Services[0].Image = "postgres:16"
+	assert.ErrorContains(t, m.Validate(), "mutually exclusive")
+}
+
+func TestResolve(t *testing.T) {
+	vars := map[string]Variable{"A": {Value: "1"}, "B": {SecretRef: "b"}}
+
+	vars["C"] = Variable{EnvSecretRef: "c"}
+
+	resolved, err := Resolve(vars, map[string]string{"b": "secret"}, map[string]string{"c": "env-secret"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"A": "1", "B": "secret", "C": "env-secret"}, resolved)
+
+	_, err = Resolve(vars, nil, map[string]string{"c": "env-secret"})
+	assert.Error(t, err)
+	_, err = Resolve(vars, map[string]string{"b": "secret"}, map[string]string{"b": "wrong store"})
+	assert.ErrorContains(t, err, `environment secret "c"`)
+}
+
+func TestSplitImage(t *testing.T) {
+	cases := map[string][2]string{
+		"redis":                         {"redis", ""},
+		"redis:7":                       {"redis", "7"},
+		"ghcr.io/acme/api:1.2":          {"ghcr.io/acme/api", "1.2"},
+		"localhost:5000/app":            {"localhost:5000/app", ""},
+		"registry.local:5000/app:1.0.0": {"registry.local:5000/app", "1.0.0"},
+	}
+	for ref, want := range cases {
+		name, tag := SplitImage(ref)
+		assert.Equal(t, want, [2]string{name, tag}, ref)
+	}
+}
@@ -0,0 +1,89 @@
+package railway
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"sort"
+)
+
+// Embedded GraphQL mutations
+var (
+	//go:embed queries/mutations/deployment-redeploy.graphql
+	gqlDeploymentRedeploy string
+
+	//go:embed queries/mutations/deployment-restart.graphql
+	gqlDeploymentRestart string
+
+	//go:embed queries/mutations/deployment-rollback.graphql
+	gqlDeploymentRollback string
+)
+
+// MaxDeploymentHistory is the most deployments ListServiceDeployments fetches at once.
+const MaxDeploymentHistory = 100
+
+// ListServiceDeploymentsInput selects a service's deployments in one environment.
+type ListServiceDeploymentsInput struct {
+	ServiceID     string
+	EnvironmentID string // Railway services span environments; only this one's deployments are returned
+	Limit         int    // Number of recent deployments to fetch, capped at MaxDeploymentHistory
+}
+
+// ListServiceDeployments returns a service's recent deployments in an environment, newest first.
+// Limit applies before filtering by environment, so fewer deployments may be returned when the
+// service is also deployed in other environments.
+func (c *Client) ListServiceDeployments(ctx context.Context, in ListServiceDeploymentsInput) ([]Deployment, error) {
+	limit := in.Limit
+	if limit <= 0 || limit > MaxDeploymentHistory {
+		limit = MaxDeploymentHistory
+	}
+	all, err := c.serviceDeployments(ctx, in.ServiceID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deployments := make([]Deployment, 0, len(all))
+	for _, d := range all {
+		if in.EnvironmentID == "" || d.EnvironmentID == in.EnvironmentID {
+			deployments = append(deployments, d)
+		}
+	}
+	// createdAt is RFC3339 in UTC, so string order is chronological
+	sort.SliceStable(deployments, func(i, j int) bool {
+		return deployments[i].CreatedAt > deployments[j].CreatedAt
+	})
+	return deployments, nil
+}
+
+// RedeployDeployment rebuilds and redeploys a deployment, returning the new deployment.
+func (c *Client) RedeployDeployment(ctx context.Context, deploymentID string) (Deployment, error) {
+	var resp struct {
+		DeploymentRedeploy Deployment `json:"deploymentRedeploy"`
+	}
+	if err := c.execute(ctx, gqlDeploymentRedeploy, map[string]any{"id": deploymentID}, &resp); err != nil {
+		return Deployment{}, fmt.Errorf("redeploy deployment: %w", err)
+	}
+	return resp.DeploymentRedeploy, nil
+}
+
+// RestartDeployment restarts the running containers of a deployment without rebuilding it.
+func (c *Client) RestartDeployment(ctx context.Context, deploymentID string) error {
+	var resp struct {
+		DeploymentRestart bool `json:"deploymentRestart"`
+	}
+	if err := c.execute(ctx, gqlDeploymentRestart, map[string]any{"id": deploymentID}, &resp); err != nil {
+		return fmt.Errorf("restart deployment: %w", err)
+	}
+	return nil
+}
+
+// RollbackDeployment makes a previous deployment the active one again.
+func (c *Client) RollbackDeployment(ctx context.Context, deploymentID string) error {
+	var resp struct {
+		DeploymentRollback bool `json:"deploymentRollback"`
+	}
+	if err := c.execute(ctx, gqlDeploymentRollback, map[string]any{"id": deploymentID}, &resp); err != nil {
+		return fmt.Errorf("rollback deployment: %w", err)
+	}
+	return nil
+}
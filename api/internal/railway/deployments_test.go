@@ -0,0 +1,59 @@
+package railway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListServiceDeployments_FiltersByEnvironmentNewestFirst(t *testing.T) {
+	var vars map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		vars = body.Variables
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"service":{"id":"svc","deployments":{"edges":[
+			{"node":{"id":"d1","status":"REMOVED","createdAt":"2026-01-01T00:00:00Z","environmentId":"env-a","canRollback":true}},
+			{"node":{"id":"d2","status":"SUCCESS","createdAt":"2026-01-02T00:00:00Z","environmentId":"env-b"}},
+			{"node":{"id":"d3","status":"SUCCESS","createdAt":"2026-01-03T00:00:00Z","environmentId":"env-a","canRedeploy":true}}
+		]}}}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	got, err := c.ListServiceDeployments(context.Background(), ListServiceDeploymentsInput{ServiceID: "svc", EnvironmentID: "env-a", Limit: 500})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vars["serviceId"] != "svc" || vars["last"] != float64(MaxDeploymentHistory) {
+		t.Fatalf("unexpected variables %v", vars)
+	}
+	if len(got) != 2 || got[0].ID != "d3" || got[1].ID != "d1" {
+		t.Fatalf("expected env-a deployments newest first, got %+v", got)
+	}
+	if !got[0].CanRedeploy || !got[1].CanRollback {
+		t.Fatalf("expected capability flags to be decoded, got %+v", got)
+	}
+}
+
+func TestRedeployDeployment_ReturnsNewDeployment(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"deploymentRedeploy":{"id":"d4","status":"QUEUED","environmentId":"env-a"}}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	d, err := c.RedeployDeployment(context.Background(), "d3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.ID != "d4" || d.Status != "QUEUED" {
+		t.Fatalf("unexpected deployment %+v", d)
+	}
+}
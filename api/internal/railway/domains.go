@@ -0,0 +1,132 @@
+package railway
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+)
+
+// Embedded GraphQL operations
+var (
+	//go:embed queries/mutations/service-domain-create.graphql
+	gqlServiceDomainCreate string
+
+	//go:embed queries/mutations/custom-domain-create.graphql
+	gqlCustomDomainCreate string
+
+	//go:embed queries/queries/service-domains.graphql
+	gqlServiceDomains string
+)
+
+// Domain is a hostname routed to a service, either generated by Railway or custom.
+type Domain struct {
+	ID         string `json:"id"`
+	Domain     string `json:"domain"`
+	TargetPort *int   `json:"targetPort,omitempty"` // Nil when Railway routes to the service's detected port
+}
+
+// DNSRecord is a record the domain owner must create for a custom domain to resolve.
+type DNSRecord struct {
+	Hostlabel     string `json:"hostlabel"`
+	RecordType    string `json:"recordType"`
+	RequiredValue string `json:"requiredValue"`
+	Status        string `json:"status"`
+}
+
+// CustomDomain is a user-owned domain attached to a service.
+type CustomDomain struct {
+	Domain
+	DNSRecords []DNSRecord `json:"dnsRecords"`
+}
+
+// ServiceDomains lists every domain of a service in one environment.
+type ServiceDomains struct {
+	ServiceDomains []Domain `json:"serviceDomains"` // Railway-generated *.up.railway.app domains
+	CustomDomains  []Domain `json:"customDomains"`
+}
+
+// CreateServiceDomainInput selects the service instance to generate a domain for.
+type CreateServiceDomainInput struct {
+	EnvironmentID string
+	ServiceID     string
+	TargetPort    *int // Optional port to route to
+}
+
+// CreateCustomDomainInput attaches a custom domain to a service instance.
+type CreateCustomDomainInput struct {
+	ProjectID     string
+	EnvironmentID string
+	ServiceID     string
+	Domain        string
+	TargetPort    *int // Optional port to route to
+}
+
+// ListServiceDomainsInput selects the service instance whose domains are listed.
+type ListServiceDomainsInput struct {
+	ProjectID     string
+	EnvironmentID string
+	ServiceID     string
+}
+
+// CreateServiceDomain generates a Railway domain for a service in an environment.
+func (c *Client) CreateServiceDomain(ctx context.Context, in CreateServiceDomainInput) (Domain, error) {
+	input := map[string]any{
+		"environmentId": in.EnvironmentID,
+		"serviceId":     in.ServiceID,
+	}
+	if in.TargetPort != nil {
+		input["targetPort"] = *in.TargetPort
+	}
+	var resp struct {
+		ServiceDomainCreate Domain `json:"serviceDomainCreate"`
+	}
+	if err := c.execute(ctx, gqlServiceDomainCreate, map[string]any{"input": input}, &resp); err != nil {
+		return Domain{}, fmt.Errorf("create service domain: %w", err)
+	}
+	return resp.ServiceDomainCreate, nil
+}
+
+// CreateCustomDomain attaches a custom domain to a service in an environment. The returned DNS
+// records must be created at the domain's DNS provider before it resolves.
+func (c *Client) CreateCustomDomain(ctx context.Context, in CreateCustomDomainInput) (CustomDomain, error) {
+	input := map[string]any{
+		"projectId":     in.ProjectID,
+		"environmentId": in.EnvironmentID,
+		"serviceId":     in.ServiceID,
+		"domain":        in.Domain,
+	}
+	if in.TargetPort != nil {
+		input["targetPort"] = *in.TargetPort
+	}
+	var resp struct {
+		CustomDomainCreate struct {
+			Domain
+			Status struct {
+				DNSRecords []DNSRecord `json:"dnsRecords"`
+			} `json:"status"`
+		} `json:"customDomainCreate"`
+	}
+	if err := c.execute(ctx, gqlCustomDomainCreate, map[string]any{"input": input}, &resp); err != nil {
+		return CustomDomain{}, fmt.Errorf("create custom domain: %w", err)
+	}
+	return CustomDomain{
+		Domain:     resp.CustomDomainCreate.Domain,
+		DNSRecords: resp.CustomDomainCreate.Status.DNSRecords,
+	}, nil
+}
+
+// ListServiceDomains returns the generated and custom domains of a service in an environment.
+func (c *Client) ListServiceDomains(ctx context.Context, in ListServiceDomainsInput) (ServiceDomains, error) {
+	vars := map[string]any{
+		"projectId":     in.ProjectID,
+		"environmentId": in.EnvironmentID,
+		"serviceId":     in.ServiceID,
+	}
+	var resp struct {
+		Domains ServiceDomains `json:"domains"`
+	}
+	if err := c.execute(ctx, gqlServiceDomains, vars, &resp); err != nil {
+		return ServiceDomains{}, fmt.Errorf("list service domains: %w", err)
+	}
+	return resp.Domains, nil
+}
@@ -0,0 +1,56 @@
+package railway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateServiceDomain_SendsTargetPort(t *testing.T) {
+	var vars map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		vars = body.Variables
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"serviceDomainCreate":{"id":"dom-1","domain":"api-production.up.railway.app","targetPort":8080}}}`))
+	}))
+	defer ts.Close()
+
+	port := 8080
+	c := NewClient(ts.URL, "", ts.Client())
+	d, err := c.CreateServiceDomain(context.Background(), CreateServiceDomainInput{EnvironmentID: "env", ServiceID: "svc", TargetPort: &port})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	input, _ := vars["input"].(map[string]any)
+	if input["environmentId"] != "env" || input["serviceId"] != "svc" || input["targetPort"] != float64(8080) {
+		t.Fatalf("unexpected input %v", vars)
+	}
+	if d.Domain != "api-production.up.railway.app" || d.TargetPort == nil || *d.TargetPort != 8080 {
+		t.Fatalf("unexpected domain %+v", d)
+	}
+}
+
+func TestCreateCustomDomain_ReturnsDNSRecords(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"customDomainCreate":{"id":"cd-1","domain":"api.example.com","status":{"dnsRecords":[
+			{"hostlabel":"api","recordType":"DNS_RECORD_TYPE_CNAME","requiredValue":"abc.up.railway.app","status":"DNS_RECORD_STATUS_REQUIRES_UPDATE"}
+		]}}}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	d, err := c.CreateCustomDomain(context.Background(), CreateCustomDomainInput{ProjectID: "p", EnvironmentID: "env", ServiceID: "svc", Domain: "api.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.ID != "cd-1" || d.Domain.Domain != "api.example.com" || len(d.DNSRecords) != 1 || d.DNSRecords[0].RequiredValue != "abc.up.railway.app" {
+		t.Fatalf("unexpected custom domain %+v", d)
+	}
+}
@@ -1,6 +1,9 @@
 package railway
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 // Error types for Railway client operations
 var (
@@ -13,3 +16,10 @@ var (
 	// ErrVaultRequired is returned when Vault is required but not provided
 	ErrVaultRequired = errors.New("vault not configured, cannot get user railway client")
 )
+
+// IsNotFound reports whether err is Railway saying the requested resource doesn't exist, e.g.
+// an environment that was already deleted. Railway reports this as a GraphQL error message
+// rather than a status code.
+func IsNotFound(err error) bool {
+	return err != nil && strings.Contains(strings.ToLower(err.Error()), "not found")
+}
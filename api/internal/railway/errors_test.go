@@ -0,0 +1,19 @@
+package railway
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsNotFound(t *testing.T) {
+	if !IsNotFound(errors.New("graphql: Environment not found")) {
+		t.Fatalf("expected a not found message to match")
+	}
+	if !IsNotFound(fmt.Errorf("destroy: %w", errors.New("graphql: Not Found"))) {
+		t.Fatalf("expected a wrapped not found error to match")
+	}
+	if IsNotFound(errors.New("graphql: Not Authorized")) || IsNotFound(nil) {
+		t.Fatalf("expected other errors not to match")
+	}
+}
@@ -233,12 +233,29 @@ type Deployment struct {
 	Status        string `json:"status"`
 	CreatedAt     string `json:"createdAt"`
 	EnvironmentID string `json:"environmentId"`
+	CanRedeploy   bool   `json:"canRedeploy"`
+	CanRollback   bool   `json:"canRollback"`
 }
 
 // GetLatestDeploymentID fetches the most recent deployment ID for a service
 func (c *Client) GetLatestDeploymentID(ctx context.Context, serviceID string) (string, error) {
+	deployments, err := c.serviceDeployments(ctx, serviceID, 1)
+	if err != nil {
+		return "", err
+	}
+
+	if len(deployments) == 0 {
+		return "", fmt.Errorf("no deployments found for service %s", serviceID)
+	}
+
+	return deployments[0].ID, nil
+}
+
+// serviceDeployments fetches the last n deployments of a service across all its environments.
+func (c *Client) serviceDeployments(ctx context.Context, serviceID string, n int) ([]Deployment, error) {
 	vars := map[string]any{
 		"serviceId": serviceID,
+		"last":      n,
 	}
 
 	var out struct {
@@ -255,12 +272,12 @@ func (c *Client) GetLatestDeploymentID(ctx context.Context, serviceID string) (s
 	}
 
 	if err := c.execute(ctx, serviceDeploymentsQuery, vars, &out); err != nil {
-		return "", fmt.Errorf("query service deployments: %w", err)
+		return nil, fmt.Errorf("query service deployments: %w", err)
 	}
 
-	if len(out.Service.Deployments.Edges) == 0 {
-		return "", fmt.Errorf("no deployments found for service %s", serviceID)
+	deployments := make([]Deployment, 0, len(out.Service.Deployments.Edges))
+	for _, e := range out.Service.Deployments.Edges {
+		deployments = append(deployments, e.Node)
 	}
-
-	return out.Service.Deployments.Edges[0].Node.ID, nil
+	return deployments, nil
 }
@@ -8,10 +8,19 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// Embedded GraphQL query
+// Embedded GraphQL operations
 var (
 	//go:embed queries/queries/variables-get.graphql
 	gqlGetVariables string
+
+	//go:embed queries/mutations/variable-upsert.graphql
+	gqlVariableUpsert string
+
+	//go:embed queries/mutations/variable-delete.graphql
+	gqlVariableDelete string
+
+	//go:embed queries/mutations/variable-collection-upsert.graphql
+	gqlVariableCollectionUpsert string
 )
 
 // GetEnvironmentVariablesInput contains parameters for fetching variables
@@ -19,6 +28,7 @@ type GetEnvironmentVariablesInput struct {
 	ProjectID     string
 	EnvironmentID string
 	ServiceID     *string // nil for environment-level, set for service-level
+	Unrendered    bool    // Return references such as ${{db.DATABASE_URL}} as written instead of resolved
 }
 
 // GetEnvironmentVariablesResult contains the fetched variables as a flat map
@@ -38,6 +48,7 @@ func (c *Client) GetEnvironmentVariables(
 	vars := map[string]any{
 		"projectId":     in.ProjectID,
 		"environmentId": in.EnvironmentID,
+		"unrendered":    in.Unrendered,
 	}
 
 	// Add serviceId if requesting service-level variables
@@ -174,6 +185,100 @@ func (c *Client) GetAllEnvironmentAndServiceVariables(
 	}, nil
 }
 
+// UpsertVariableInput sets one variable on an environment or service
+type UpsertVariableInput struct {
+	ProjectID     string
+	EnvironmentID string
+	ServiceID     *string // nil for environment-level, set for service-level
+	Name          string
+	Value         string
+	SkipDeploys   bool // Don't let Railway redeploy affected services
+}
+
+// DeleteVariableInput removes one variable from an environment or service
+type DeleteVariableInput struct {
+	ProjectID     string
+	EnvironmentID string
+	ServiceID     *string // nil for environment-level, set for service-level
+	Name          string
+}
+
+// UpsertVariableCollectionInput sets several variables on an environment or service at once
+type UpsertVariableCollectionInput struct {
+	ProjectID     string
+	EnvironmentID string
+	ServiceID     *string           // nil for environment-level, set for service-level
+	Variables     map[string]string // Variable name -> value
+	SkipDeploys   bool              // Don't let Railway redeploy affected services
+}
+
+// UpsertVariable creates or updates a single variable.
+func (c *Client) UpsertVariable(ctx context.Context, in UpsertVariableInput) error {
+	input := variableScope(in.ProjectID, in.EnvironmentID, in.ServiceID)
+	input["name"] = in.Name
+	input["value"] = in.Value
+	input["skipDeploys"] = in.SkipDeploys
+
+	var resp struct {
+		VariableUpsert bool `json:"variableUpsert"`
+	}
+	if err := c.execute(ctx, gqlVariableUpsert, map[string]any{"input": input}, &resp); err != nil {
+		return fmt.Errorf("upsert variable %s: %w", in.Name, err)
+	}
+	return nil
+}
+
+// DeleteVariable removes a single variable.
+func (c *Client) DeleteVariable(ctx context.Context, in DeleteVariableInput) error {
+	input := variableScope(in.ProjectID, in.EnvironmentID, in.ServiceID)
+	input["name"] = in.Name
+
+	var resp struct {
+		VariableDelete bool `json:"variableDelete"`
+	}
+	if err := c.execute(ctx, gqlVariableDelete, map[string]any{"input": input}, &resp); err != nil {
+		return fmt.Errorf("delete variable %s: %w", in.Name, err)
+	}
+	return nil
+}
+
+// UpsertVariableCollection creates or updates several variables in one mutation. Variables not
+// in the collection are left untouched.
+func (c *Client) UpsertVariableCollection(ctx context.Context, in UpsertVariableCollectionInput) error {
+	input := variableScope(in.ProjectID, in.EnvironmentID, in.ServiceID)
+	input["variables"] = in.Variables
+	input["skipDeploys"] = in.SkipDeploys
+
+	var resp struct {
+		VariableCollectionUpsert bool `json:"variableCollectionUpsert"`
+	}
+	if err := c.execute(ctx, gqlVariableCollectionUpsert, map[string]any{"input": input}, &resp); err != nil {
+		return fmt.Errorf("upsert variable collection: %w", err)
+	}
+
+	// Log count only, never log actual variable values for security
+	log.Info().
+		Str("project_id", in.ProjectID).
+		Str("environment_id", in.EnvironmentID).
+		Str("service_id", stringPtrToLogValue(in.ServiceID)).
+		Int("variable_count", len(in.Variables)).
+		Msg("upserted variables in Railway")
+	return nil
+}
+
+// variableScope builds the project/environment/service part of a variable mutation input.
+// serviceId is omitted for environment-level (shared) variables.
+func variableScope(projectID, environmentID string, serviceID *string) map[string]any {
+	input := map[string]any{
+		"projectId":     projectID,
+		"environmentId": environmentID,
+	}
+	if serviceID != nil {
+		input["serviceId"] = *serviceID
+	}
+	return input
+}
+
 // stringPtrToLogValue converts a string pointer to a log-safe value
 func stringPtrToLogValue(s *string) string {
 	if s == nil {
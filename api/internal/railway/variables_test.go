@@ -2,6 +2,9 @@ package railway
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -293,3 +296,81 @@ func TestGetEnvironmentVariables_ContextCancellation(t *testing.T) {
 	// In a real integration test, this would return context.Canceled error
 	t.Log("context cancellation would be tested in integration tests")
 }
+
+func TestUpsertVariableCollection_SendsScopeAndVariables(t *testing.T) {
+	var input map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		input, _ = body.Variables["input"].(map[string]any)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"variableCollectionUpsert":true}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	err := c.UpsertVariableCollection(context.Background(), UpsertVariableCollectionInput{
+		ProjectID:     "proj",
+		EnvironmentID: "env",
+		ServiceID:     strPtr("svc"),
+		Variables:     map[string]string{"LOG_LEVEL": "debug"},
+		SkipDeploys:   true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars, _ := input["variables"].(map[string]any)
+	if input["projectId"] != "proj" || input["environmentId"] != "env" || input["serviceId"] != "svc" || input["skipDeploys"] != true || vars["LOG_LEVEL"] != "debug" {
+		t.Fatalf("unexpected input %v", input)
+	}
+}
+
+func TestDeleteVariable_OmitsServiceForEnvironmentScope(t *testing.T) {
+	var input map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		input, _ = body.Variables["input"].(map[string]any)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"variableDelete":true}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	if err := c.DeleteVariable(context.Background(), DeleteVariableInput{ProjectID: "proj", EnvironmentID: "env", Name: "OLD"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := input["serviceId"]; ok || input["name"] != "OLD" {
+		t.Fatalf("unexpected input %v", input)
+	}
+}
+
+func TestGetEnvironmentVariables_SendsUnrendered(t *testing.T) {
+	var vars map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		vars = body.Variables
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"variables":{"DATABASE_URL":"${{db.DATABASE_URL}}"}}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	res, err := c.GetEnvironmentVariables(context.Background(), GetEnvironmentVariablesInput{ProjectID: "proj", EnvironmentID: "env", Unrendered: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vars["unrendered"] != true {
+		t.Fatalf("expected unrendered to be sent, got %v", vars)
+	}
+	if res.Variables["DATABASE_URL"] != "${{db.DATABASE_URL}}" {
+		t.Fatalf("unexpected variables %v", res.Variables)
+	}
+}
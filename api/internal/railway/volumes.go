@@ -0,0 +1,119 @@
+package railway
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+)
+
+// Embedded GraphQL operations
+var (
+	//go:embed queries/mutations/volume-create.graphql
+	gqlVolumeCreate string
+
+	//go:embed queries/mutations/volume-instance-update.graphql
+	gqlVolumeInstanceUpdate string
+
+	//go:embed queries/mutations/volume-delete.graphql
+	gqlVolumeDelete string
+)
+
+// Volume is a persistent disk mounted into a service in one environment.
+type Volume struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	SizeMB    int    `json:"sizeMB"` // Capacity Railway allotted, 0 when not reported
+}
+
+// CreateVolumeInput creates a volume in an environment, mounted into ServiceID when set.
+type CreateVolumeInput struct {
+	ProjectID     string
+	EnvironmentID string
+	ServiceID     string // Optional; an unattached volume can be attached later
+	MountPath     string
+}
+
+// AttachVolumeInput mounts an existing volume into a service in an environment.
+type AttachVolumeInput struct {
+	VolumeID      string
+	EnvironmentID string
+	ServiceID     string
+	MountPath     string
+}
+
+// DeleteVolumeInput selects the volume to delete.
+type DeleteVolumeInput struct {
+	VolumeID string
+}
+
+// CreateVolume creates a volume and returns it with the size Railway allotted in the environment.
+func (c *Client) CreateVolume(ctx context.Context, in CreateVolumeInput) (Volume, error) {
+	input := map[string]any{
+		"projectId":     in.ProjectID,
+		"environmentId": in.EnvironmentID,
+		"mountPath":     in.MountPath,
+	}
+	if in.ServiceID != "" {
+		input["serviceId"] = in.ServiceID
+	}
+	var resp struct {
+		VolumeCreate struct {
+			ID              string `json:"id"`
+			Name            string `json:"name"`
+			VolumeInstances struct {
+				Edges []struct {
+					Node struct {
+						MountPath     string `json:"mountPath"`
+						SizeMB        int    `json:"sizeMB"`
+						EnvironmentID string `json:"environmentId"`
+					} `json:"node"`
+				} `json:"edges"`
+			} `json:"volumeInstances"`
+		} `json:"volumeCreate"`
+	}
+	if err := c.execute(ctx, gqlVolumeCreate, map[string]any{"input": input}, &resp); err != nil {
+		return Volume{}, fmt.Errorf("create volume: %w", err)
+	}
+
+	v := Volume{ID: resp.VolumeCreate.ID, Name: resp.VolumeCreate.Name, MountPath: in.MountPath}
+	for _, e := range resp.VolumeCreate.VolumeInstances.Edges {
+		if e.Node.EnvironmentID == in.EnvironmentID {
+			v.MountPath = e.Node.MountPath
+			v.SizeMB = e.Node.SizeMB
+			break
+		}
+	}
+	return v, nil
+}
+
+// AttachVolume mounts a volume into a service, replacing the volume's previous mount in that
+// environment.
+func (c *Client) AttachVolume(ctx context.Context, in AttachVolumeInput) error {
+	vars := map[string]any{
+		"volumeId":      in.VolumeID,
+		"environmentId": in.EnvironmentID,
+		"input": map[string]any{
+			"serviceId": in.ServiceID,
+			"mountPath": in.MountPath,
+		},
+	}
+	var resp struct {
+		VolumeInstanceUpdate bool `json:"volumeInstanceUpdate"`
+	}
+	if err := c.execute(ctx, gqlVolumeInstanceUpdate, vars, &resp); err != nil {
+		return fmt.Errorf("attach volume %s: %w", in.VolumeID, err)
+	}
+	return nil
+}
+
+// DeleteVolume deletes a volume and the data on it.
+func (c *Client) DeleteVolume(ctx context.Context, in DeleteVolumeInput) error {
+	var resp struct {
+		VolumeDelete bool `json:"volumeDelete"`
+	}
+	if err := c.execute(ctx, gqlVolumeDelete, map[string]any{"volumeId": in.VolumeID}, &resp); err != nil {
+		return fmt.Errorf("delete volume %s: %w", in.VolumeID, err)
+	}
+	return nil
+}
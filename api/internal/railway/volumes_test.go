@@ -0,0 +1,71 @@
+package railway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateVolume_ReturnsEnvironmentInstance(t *testing.T) {
+	var vars map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		vars = body.Variables
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"volumeCreate":{"id":"vol-1","name":"brave-volume","volumeInstances":{"edges":[
+			{"node":{"id":"vi-0","mountPath":"/data","sizeMB":500,"environmentId":"other"}},
+			{"node":{"id":"vi-1","mountPath":"/data","sizeMB":5000,"environmentId":"env"}}
+		]}}}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	v, err := c.CreateVolume(context.Background(), CreateVolumeInput{ProjectID: "p", EnvironmentID: "env", ServiceID: "svc", MountPath: "/data"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	input, _ := vars["input"].(map[string]any)
+	if input["projectId"] != "p" || input["environmentId"] != "env" || input["serviceId"] != "svc" || input["mountPath"] != "/data" {
+		t.Fatalf("unexpected input %v", vars)
+	}
+	if v.ID != "vol-1" || v.MountPath != "/data" || v.SizeMB != 5000 {
+		t.Fatalf("unexpected volume %+v", v)
+	}
+}
+
+func TestAttachAndDeleteVolume_SendVolumeID(t *testing.T) {
+	var calls []map[string]any
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]any `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls = append(calls, body.Variables)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"volumeInstanceUpdate":true,"volumeDelete":true}}`))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ts.URL, "", ts.Client())
+	if err := c.AttachVolume(context.Background(), AttachVolumeInput{VolumeID: "vol-1", EnvironmentID: "env", ServiceID: "svc", MountPath: "/data"}); err != nil {
+		t.Fatalf("attach: %v", err)
+	}
+	if err := c.DeleteVolume(context.Background(), DeleteVolumeInput{VolumeID: "vol-1"}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	input, _ := calls[0]["input"].(map[string]any)
+	if calls[0]["volumeId"] != "vol-1" || calls[0]["environmentId"] != "env" || input["serviceId"] != "svc" || input["mountPath"] != "/data" {
+		t.Fatalf("unexpected attach variables %v", calls[0])
+	}
+	if calls[1]["volumeId"] != "vol-1" {
+		t.Fatalf("unexpected delete variables %v", calls[1])
+	}
+}
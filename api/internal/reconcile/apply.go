@@ -0,0 +1,155 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+	"gorm.io/gorm"
+)
+
+// fieldColumns maps compared fields to their store.Service columns.
+var fieldColumns = map[string]string{
+	FieldName:         "name",
+	FieldSourceRepo:   "source_repo",
+	FieldSourceBranch: "source_branch",
+	FieldDockerImage:  "docker_image",
+}
+
+// ApplyResult counts the corrections made to the Mirage database.
+type ApplyResult struct {
+	EnvironmentDeleted bool  `json:"environmentDeleted"`
+	ServicesDeleted    int64 `json:"servicesDeleted"`
+	VolumesDeleted     int64 `json:"volumesDeleted"`
+	ServicesCreated    int   `json:"servicesCreated"`
+	ServicesUpdated    int   `json:"servicesUpdated"`
+	// Railway IDs of the volumes whose records went with missing services. Railway may still
+	// hold them, so callers can clean them up or report them.
+	DeletedVolumeIDs []string `json:"deletedVolumeIds,omitempty"`
+}
+
+// Apply corrects the Mirage records of env so they match Railway as described by report:
+// missing services are deleted with their volumes, extra services are adopted and changed fields take Railway's
+// value. An environment that no longer exists in Railway is deleted with its services and
+// metadata. Only the database is touched; callers own any Vault cleanup.
+func Apply(ctx context.Context, db *gorm.DB, env *store.Environment, report Report, now time.Time) (ApplyResult, error) {
+	var res ApplyResult
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if report.EnvironmentMissing {
+			cleanup, err := store.DeleteEnvironmentRecords(tx, env)
+			if err != nil {
+				return err
+			}
+			res.EnvironmentDeleted = true
+			res.ServicesDeleted = cleanup.ServicesDeleted
+			res.VolumesDeleted = cleanup.VolumesDeleted
+			return nil
+		}
+
+		for _, d := range report.Services {
+			switch d.Kind {
+			case DriftMissing:
+				owned := tx.Model(&store.Service{}).Select("id").Where("id = ? AND environment_id = ?", d.ServiceID, env.ID)
+				var volumes []store.Volume
+				if err := tx.Select("railway_volume_id").Where("service_id IN (?)", owned).Find(&volumes).Error; err != nil {
+					return err
+				}
+				for _, v := range volumes {
+					if v.RailwayVolumeID != "" {
+						res.DeletedVolumeIDs = append(res.DeletedVolumeIDs, v.RailwayVolumeID)
+					}
+				}
+				result := tx.Where("service_id IN (?)", owned).Delete(&store.Volume{})
+				if result.Error != nil {
+					return result.Error
+				}
+				res.VolumesDeleted += result.RowsAffected
+				result = tx.Where("id = ? AND environment_id = ?", d.ServiceID, env.ID).Delete(&store.Service{})
+				if result.Error != nil {
+					return result.Error
+				}
+				res.ServicesDeleted += result.RowsAffected
+			case DriftExtra:
+				svc := adoptedService(env, d, now)
+				if err := tx.Create(&svc).Error; err != nil {
+					return err
+				}
+				res.ServicesCreated++
+			case DriftChanged:
+				updates := map[string]interface{}{"updated_at": now}
+				sourceChanged := false
+				for _, change := range d.Changes {
+					if column, ok := fieldColumns[change.Field]; ok {
+						updates[column] = change.Remote
+					}
+					if change.Field == FieldSourceRepo || change.Field == FieldDockerImage {
+						sourceChanged = true
+					}
+				}
+				// Railway reports no source for some services; only a source that actually
+				// changed says how the service is deployed now
+				if d.Remote != nil && sourceChanged && (d.Remote.SourceRepo != "" || d.Remote.DockerImage != "") {
+					var local store.Service
+					err := tx.Select("deployment_type").Where("id = ? AND environment_id = ?", d.ServiceID, env.ID).First(&local).Error
+					if err != nil && err != gorm.ErrRecordNotFound {
+						return err
+					}
+					updates["deployment_type"] = changedDeploymentType(local.DeploymentType, *d.Remote)
+				}
+				err := tx.Model(&store.Service{}).
+					Where("id = ? AND environment_id = ?", d.ServiceID, env.ID).
+					Updates(updates).Error
+				if err != nil {
+					return err
+				}
+				res.ServicesUpdated++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return ApplyResult{}, err
+	}
+	return res, nil
+}
+
+// adoptedService builds the record for a service that exists only in Railway.
+func adoptedService(env *store.Environment, d ServiceDrift, now time.Time) store.Service {
+	svc := store.Service{
+		ID:               uuid.New().String(),
+		UserID:           env.UserID,
+		EnvironmentID:    env.ID,
+		Name:             d.Name,
+		RailwayServiceID: d.RailwayServiceID,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	if d.Remote != nil {
+		svc.Status = d.Remote.Status
+		svc.DeploymentType = deploymentType(*d.Remote)
+		svc.SourceRepo = d.Remote.SourceRepo
+		svc.SourceBranch = d.Remote.SourceBranch
+		svc.DockerImage = d.Remote.DockerImage
+	}
+	return svc
+}
+
+// changedDeploymentType returns the deployment type of a changed service. Database services are
+// deployed from their template image, so they stay databases unless Railway now builds them
+// from a repository.
+func changedDeploymentType(local store.DeploymentType, rs RemoteService) store.DeploymentType {
+	remote := deploymentType(rs)
+	if local == store.DeploymentTypeDatabase && remote == store.DeploymentTypeDockerImage {
+		return local
+	}
+	return remote
+}
+
+// deploymentType infers how Railway deploys a service from its source.
+func deploymentType(rs RemoteService) store.DeploymentType {
+	if rs.DockerImage != "" {
+		return store.DeploymentTypeDockerImage
+	}
+	return store.DeploymentTypeSourceRepo
+}
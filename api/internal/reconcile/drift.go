@@ -0,0 +1,206 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/status"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+// DriftKind classifies how a service differs between Mirage and Railway.
+type DriftKind string
+
+const (
+	// DriftMissing means Mirage tracks a service that no longer exists in Railway.
+	DriftMissing DriftKind = "missing"
+	// DriftExtra means Railway has a service that Mirage does not track.
+	DriftExtra DriftKind = "extra"
+	// DriftChanged means the service exists in both but its source, branch or image differ.
+	DriftChanged DriftKind = "changed"
+)
+
+// Compared service fields; names match the store.Service JSON fields.
+const (
+	FieldName         = "name"
+	FieldSourceRepo   = "sourceRepo"
+	FieldSourceBranch = "sourceBranch"
+	FieldDockerImage  = "dockerImage"
+)
+
+// deploymentMetaBranch is the key Railway uses for the deployed branch in deployment meta
+const deploymentMetaBranch = "branch"
+
+// ProjectSource is the Railway read needed to detect drift.
+type ProjectSource interface {
+	GetProjectWithDetailsByID(ctx context.Context, id string) (railway.ProjectDetails, error)
+}
+
+// FieldChange is a single field whose Railway value differs from the Mirage record.
+type FieldChange struct {
+	Field  string `json:"field"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+}
+
+// RemoteService is what Railway reports for a service, used to create or correct records.
+type RemoteService struct {
+	Name         string `json:"name"`
+	SourceRepo   string `json:"sourceRepo,omitempty"`
+	SourceBranch string `json:"sourceBranch,omitempty"`
+	DockerImage  string `json:"dockerImage,omitempty"`
+	Status       string `json:"status"`
+}
+
+// ServiceDrift describes one service that differs between Mirage and Railway.
+type ServiceDrift struct {
+	Kind             DriftKind      `json:"kind"`
+	ServiceID        string         `json:"serviceId,omitempty"` // Mirage service ID, empty for extra services
+	RailwayServiceID string         `json:"railwayServiceId"`
+	Name             string         `json:"name"`
+	Changes          []FieldChange  `json:"changes,omitempty"` // Only for changed services
+	Remote           *RemoteService `json:"remote,omitempty"`  // Nil for missing services
+}
+
+// Report is the result of comparing one environment with Railway.
+type Report struct {
+	EnvironmentID        string `json:"environmentId"`
+	RailwayEnvironmentID string `json:"railwayEnvironmentId"`
+	// EnvironmentMissing is set when the environment itself no longer exists in Railway;
+	// every tracked service is then reported missing.
+	EnvironmentMissing bool           `json:"environmentMissing"`
+	Services           []ServiceDrift `json:"services"`
+	CheckedAt          time.Time      `json:"checkedAt"`
+}
+
+// HasDrift reports whether the environment differs from Railway at all.
+func (r Report) HasDrift() bool {
+	return r.EnvironmentMissing || len(r.Services) > 0
+}
+
+// Check fetches the environment's project from Railway and compares it with the local records.
+func Check(ctx context.Context, rw ProjectSource, env store.Environment, services []store.Service, now time.Time) (Report, error) {
+	details, err := rw.GetProjectWithDetailsByID(ctx, env.RailwayProjectID)
+	if err != nil {
+		return Report{}, err
+	}
+	return Detect(env, services, details, now), nil
+}
+
+// Detect compares the local records of env with Railway's view of its project. Services are
+// matched by Railway service ID; instances Railway has marked deleted count as gone.
+func Detect(env store.Environment, services []store.Service, details railway.ProjectDetails, now time.Time) Report {
+	report := Report{
+		EnvironmentID:        env.ID,
+		RailwayEnvironmentID: env.RailwayEnvironmentID,
+		Services:             []ServiceDrift{},
+		CheckedAt:            now,
+	}
+
+	var remoteEnv *railway.ProjectEnvironment
+	for i := range details.Environments {
+		if details.Environments[i].ID == env.RailwayEnvironmentID {
+			remoteEnv = &details.Environments[i]
+			break
+		}
+	}
+	if remoteEnv == nil {
+		report.EnvironmentMissing = true
+	}
+
+	remote := map[string]railway.ServiceInstance{}
+	var remoteOrder []string
+	if remoteEnv != nil {
+		for _, inst := range remoteEnv.Services {
+			if inst.DeletedAt != nil {
+				continue
+			}
+			remote[inst.ServiceID] = inst
+			remoteOrder = append(remoteOrder, inst.ServiceID)
+		}
+	}
+
+	tracked := map[string]bool{}
+	for _, svc := range services {
+		tracked[svc.RailwayServiceID] = true
+		inst, ok := remote[svc.RailwayServiceID]
+		if !ok {
+			report.Services = append(report.Services, ServiceDrift{
+				Kind:             DriftMissing,
+				ServiceID:        svc.ID,
+				RailwayServiceID: svc.RailwayServiceID,
+				Name:             svc.Name,
+			})
+			continue
+		}
+		rs := remoteService(inst)
+		if changes := compareService(svc, inst, rs); len(changes) > 0 {
+			report.Services = append(report.Services, ServiceDrift{
+				Kind:             DriftChanged,
+				ServiceID:        svc.ID,
+				RailwayServiceID: svc.RailwayServiceID,
+				Name:             svc.Name,
+				Changes:          changes,
+				Remote:           &rs,
+			})
+		}
+	}
+
+	for _, id := range remoteOrder {
+		if tracked[id] {
+			continue
+		}
+		rs := remoteService(remote[id])
+		report.Services = append(report.Services, ServiceDrift{
+			Kind:             DriftExtra,
+			RailwayServiceID: id,
+			Name:             rs.Name,
+			Remote:           &rs,
+		})
+	}
+	return report
+}
+
+// remoteService extracts the compared fields from a Railway service instance.
+func remoteService(inst railway.ServiceInstance) RemoteService {
+	rs := RemoteService{Name: inst.ServiceName, Status: status.StatusUnknown}
+	if inst.Source != nil {
+		if inst.Source.Repo != nil {
+			rs.SourceRepo = *inst.Source.Repo
+		}
+		if inst.Source.Image != nil {
+			rs.DockerImage = *inst.Source.Image
+		}
+	}
+	if d := inst.LatestDeployment; d != nil {
+		if branch, ok := d.Meta[deploymentMetaBranch].(string); ok {
+			rs.SourceBranch = branch
+		}
+		if d.Status != nil {
+			rs.Status = status.NormalizeDeploymentToUI(*d.Status)
+		}
+	}
+	return rs
+}
+
+// compareService lists the fields where Railway disagrees with svc. Source fields are only
+// compared when Railway reports a source, and the branch only when the latest deployment
+// records one, so a missing value in the response is not mistaken for a change.
+func compareService(svc store.Service, inst railway.ServiceInstance, rs RemoteService) []FieldChange {
+	var changes []FieldChange
+	add := func(field, local, remote string) {
+		if local != remote {
+			changes = append(changes, FieldChange{Field: field, Local: local, Remote: remote})
+		}
+	}
+	add(FieldName, svc.Name, rs.Name)
+	if inst.Source != nil {
+		add(FieldSourceRepo, svc.SourceRepo, rs.SourceRepo)
+		add(FieldDockerImage, svc.DockerImage, rs.DockerImage)
+	}
+	if rs.SourceBranch != "" {
+		add(FieldSourceBranch, svc.SourceBranch, rs.SourceBranch)
+	}
+	return changes
+}
@@ -0,0 +1,237 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stwalsh4118/mirageapi/internal/railway"
+	"github.com/stwalsh4118/mirageapi/internal/store"
+)
+
+func strPtr(s string) *string { return &s }
+
+func driftFixture() (store.Environment, []store.Service, railway.ProjectDetails) {
+	env := store.Environment{ID: "env-1", UserID: "u1", Name: "dev", Type: store.EnvironmentTypeDev, RailwayProjectID: "rw-proj", RailwayEnvironmentID: "rw-env"}
+	services := []store.Service{
+		{ID: "svc-api", UserID: "u1", EnvironmentID: "env-1", Name: "api", RailwayServiceID: "rw-api", SourceRepo: "org/api", SourceBranch: "main"},
+		{ID: "svc-web", UserID: "u1", EnvironmentID: "env-1", Name: "web", RailwayServiceID: "rw-web", DeploymentType: store.DeploymentTypeDockerImage, DockerImage: "nginx:1.25"},
+		{ID: "svc-gone", UserID: "u1", EnvironmentID: "env-1", Name: "worker", RailwayServiceID: "rw-worker"},
+		{ID: "svc-same", UserID: "u1", EnvironmentID: "env-1", Name: "cron", RailwayServiceID: "rw-cron", SourceRepo: "org/cron"},
+	}
+	success := "SUCCESS"
+	details := railway.ProjectDetails{ID: "rw-proj", Environments: []railway.ProjectEnvironment{
+		{ID: "rw-other"},
+		{ID: "rw-env", Services: []railway.ServiceInstance{
+			{ServiceID: "rw-api", ServiceName: "api", Source: &railway.ServiceSource{Repo: strPtr("org/api")},
+				LatestDeployment: &railway.LatestDeployment{Meta: map[string]any{"branch": "hotfix"}}},
+			{ServiceID: "rw-web", ServiceName: "web", Source: &railway.ServiceSource{Image: strPtr("nginx:1.27")}},
+			{ServiceID: "rw-cron", ServiceName: "cron", Source: &railway.ServiceSource{Repo: strPtr("org/cron")}},
+			{ServiceID: "rw-worker", ServiceName: "worker", DeletedAt: strPtr("2026-01-01T00:00:00Z")},
+			{ServiceID: "rw-redis", ServiceName: "redis", Source: &railway.ServiceSource{Image: strPtr("redis:7")},
+				LatestDeployment: &railway.LatestDeployment{Status: &success}},
+		}},
+	}}
+	return env, services, details
+}
+
+func TestDetect_ReportsMissingExtraAndChanged(t *testing.T) {
+	env, services, details := driftFixture()
+
+	report := Detect(env, services, details, time.Now())
+
+	if report.EnvironmentMissing {
+		t.Fatalf("expected environment to be found")
+	}
+	byID := map[string]ServiceDrift{}
+	for _, d := range report.Services {
+		byID[d.RailwayServiceID] = d
+	}
+	if len(byID) != 4 {
+		t.Fatalf("expected 4 drifted services, got %+v", report.Services)
+	}
+	if d := byID["rw-api"]; d.Kind != DriftChanged || len(d.Changes) != 1 || d.Changes[0] != (FieldChange{Field: FieldSourceBranch, Local: "main", Remote: "hotfix"}) {
+		t.Fatalf("expected api branch change, got %+v", d)
+	}
+	if d := byID["rw-web"]; d.Kind != DriftChanged || len(d.Changes) != 1 || d.Changes[0].Field != FieldDockerImage || d.Changes[0].Remote != "nginx:1.27" {
+		t.Fatalf("expected web image change, got %+v", d)
+	}
+	if d := byID["rw-worker"]; d.Kind != DriftMissing || d.ServiceID != "svc-gone" {
+		t.Fatalf("expected deleted worker to be missing, got %+v", d)
+	}
+	if d := byID["rw-redis"]; d.Kind != DriftExtra || d.Remote == nil || d.Remote.DockerImage != "redis:7" || d.Remote.Status != "active" {
+		t.Fatalf("expected untracked redis to be extra, got %+v", d)
+	}
+	if _, ok := byID["rw-cron"]; ok {
+		t.Fatalf("expected unchanged cron not to be reported")
+	}
+}
+
+func TestDetect_EnvironmentMissing(t *testing.T) {
+	env, services, details := driftFixture()
+	details.Environments = details.Environments[:1]
+
+	report := Detect(env, services, details, time.Now())
+
+	if !report.EnvironmentMissing || !report.HasDrift() {
+		t.Fatalf("expected missing environment, got %+v", report)
+	}
+	if len(report.Services) != len(services) {
+		t.Fatalf("expected every tracked service missing, got %+v", report.Services)
+	}
+	for _, d := range report.Services {
+		if d.Kind != DriftMissing {
+			t.Fatalf("expected only missing services, got %+v", d)
+		}
+	}
+}
+
+func TestApply_CorrectsServices(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env, services, details := driftFixture()
+	services[2].Volumes = []store.Volume{{ID: "vol-gone", UserID: "u1", RailwayVolumeID: "rw-vol-gone", MountPath: "/data"}}
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	for i := range services {
+		if err := db.Create(&services[i]).Error; err != nil {
+			t.Fatalf("create service failed: %v", err)
+		}
+	}
+	now := time.Now()
+
+	report := Detect(env, services, details, now)
+	res, err := Apply(context.Background(), db, &env, report, now)
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if res.ServicesDeleted != 1 || res.VolumesDeleted != 1 || res.ServicesCreated != 1 || res.ServicesUpdated != 2 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(res.DeletedVolumeIDs) != 1 || res.DeletedVolumeIDs[0] != "rw-vol-gone" {
+		t.Fatalf("expected the deleted volume to be reported, got %v", res.DeletedVolumeIDs)
+	}
+	var volumes int64
+	if err := db.Model(&store.Volume{}).Count(&volumes).Error; err != nil || volumes != 0 {
+		t.Fatalf("expected the missing service's volume to be deleted, %d left (err %v)", volumes, err)
+	}
+
+	var after []store.Service
+	if err := db.Where("environment_id = ?", env.ID).Find(&after).Error; err != nil {
+		t.Fatalf("list services failed: %v", err)
+	}
+	if again := Detect(env, after, details, now); again.HasDrift() {
+		t.Fatalf("expected no drift after apply, got %+v", again.Services)
+	}
+	var redis store.Service
+	if err := db.Where("railway_service_id = ?", "rw-redis").First(&redis).Error; err != nil {
+		t.Fatalf("expected redis to be adopted: %v", err)
+	}
+	if redis.UserID != "u1" || redis.DeploymentType != store.DeploymentTypeDockerImage || redis.Status != "active" {
+		t.Fatalf("unexpected adopted service %+v", redis)
+	}
+}
+
+func TestApply_DeletesMissingEnvironment(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env, services, details := driftFixture()
+	details.Environments = nil
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	if err := db.Create(&services[0]).Error; err != nil {
+		t.Fatalf("create service failed: %v", err)
+	}
+
+	res, err := Apply(context.Background(), db, &env, Detect(env, services[:1], details, time.Now()), time.Now())
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if !res.EnvironmentDeleted || res.ServicesDeleted != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	var count int64
+	db.Model(&store.Environment{}).Where("id = ?", env.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected environment to be deleted")
+	}
+}
+
+func TestApply_KeepsDatabaseDeploymentType(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env, _, _ := driftFixture()
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	services := []store.Service{
+		{ID: "svc-db", UserID: "u1", EnvironmentID: env.ID, Name: "db", RailwayServiceID: "rw-db", DeploymentType: store.DeploymentTypeDatabase, DatabaseEngine: "postgres", DockerImage: "postgres:16"},
+		{ID: "svc-moved", UserID: "u1", EnvironmentID: env.ID, Name: "cache", RailwayServiceID: "rw-cache", DeploymentType: store.DeploymentTypeDatabase, DatabaseEngine: "redis", DockerImage: "redis:7"},
+	}
+	if err := db.Create(&services).Error; err != nil {
+		t.Fatalf("create services failed: %v", err)
+	}
+	details := railway.ProjectDetails{ID: "rw-proj", Environments: []railway.ProjectEnvironment{
+		{ID: "rw-env", Services: []railway.ServiceInstance{
+			{ServiceID: "rw-db", ServiceName: "db", Source: &railway.ServiceSource{Image: strPtr("postgres:17")}},
+			{ServiceID: "rw-cache", ServiceName: "cache", Source: &railway.ServiceSource{Repo: strPtr("org/cache")}},
+		}},
+	}}
+
+	if _, err := Apply(context.Background(), db, &env, Detect(env, services, details, time.Now()), time.Now()); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	var dbSvc, moved store.Service
+	db.First(&dbSvc, "id = ?", "svc-db")
+	db.First(&moved, "id = ?", "svc-moved")
+	if dbSvc.DeploymentType != store.DeploymentTypeDatabase || dbSvc.DockerImage != "postgres:17" {
+		t.Fatalf("expected database service to keep its type with the new image, got %+v", dbSvc)
+	}
+	if moved.DeploymentType != store.DeploymentTypeSourceRepo {
+		t.Fatalf("expected service now built from a repository to change type, got %s", moved.DeploymentType)
+	}
+}
+
+func TestApply_KeepsDeploymentTypeWithoutSourceChange(t *testing.T) {
+	db, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	env, _, _ := driftFixture()
+	if err := db.Create(&env).Error; err != nil {
+		t.Fatalf("create env failed: %v", err)
+	}
+	services := []store.Service{
+		{ID: "svc-web", UserID: "u1", EnvironmentID: env.ID, Name: "web", RailwayServiceID: "rw-web", DeploymentType: store.DeploymentTypeDockerImage, DockerImage: "nginx:1.25"},
+	}
+	if err := db.Create(&services).Error; err != nil {
+		t.Fatalf("create services failed: %v", err)
+	}
+	// Renamed in Railway, which reports no source for it
+	details := railway.ProjectDetails{ID: "rw-proj", Environments: []railway.ProjectEnvironment{
+		{ID: "rw-env", Services: []railway.ServiceInstance{
+			{ServiceID: "rw-web", ServiceName: "frontend"},
+		}},
+	}}
+
+	report := Detect(env, services, details, time.Now())
+	if len(report.Services) != 1 || report.Services[0].Kind != DriftChanged {
+		t.Fatalf("expected web to be changed, got %+v", report.Services)
+	}
+	if _, err := Apply(context.Background(), db, &env, report, time.Now()); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	var web store.Service
+	db.First(&web, "id = ?", "svc-web")
+	if web.Name != "frontend" || web.DeploymentType != store.DeploymentTypeDockerImage {
+		t.Fatalf("expected renamed service to keep its deployment type, got %+v", web)
+	}
+}
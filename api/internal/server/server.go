@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -10,9 +11,12 @@ import (
 	"github.com/stwalsh4118/mirageapi/internal/auth"
 	"github.com/stwalsh4118/mirageapi/internal/config"
 	"github.com/stwalsh4118/mirageapi/internal/controller"
+	"github.com/stwalsh4118/mirageapi/internal/events"
+	"github.com/stwalsh4118/mirageapi/internal/jobs"
 	"github.com/stwalsh4118/mirageapi/internal/logging"
 	"github.com/stwalsh4118/mirageapi/internal/railway"
 	"github.com/stwalsh4118/mirageapi/internal/scanner"
+	"github.com/stwalsh4118/mirageapi/internal/store"
 	"github.com/stwalsh4118/mirageapi/internal/vault"
 	"github.com/stwalsh4118/mirageapi/internal/webhooks"
 	"gorm.io/gorm"
@@ -49,6 +53,9 @@ func NewHTTPServer(cfg config.AppConfig, deps ...any) *gin.Engine {
 	var db *gorm.DB
 	var rw *railway.Client
 	var vaultClient *vault.Client
+	var hub *events.Hub
+	var runner *jobs.ProvisioningRunner
+	var leases *jobs.Leases
 	for _, d := range deps {
 		switch v := d.(type) {
 		case *gorm.DB:
@@ -57,8 +64,24 @@ func NewHTTPServer(cfg config.AppConfig, deps ...any) *gin.Engine {
 			rw = v
 		case *vault.Client:
 			vaultClient = v
+		case *events.Hub:
+			hub = v
+		case *jobs.ProvisioningRunner:
+			runner = v
+		case *jobs.Leases:
+			leases = v
 		}
 	}
+	if hub == nil {
+		hub = events.NewHub()
+	}
+	if runner == nil && db != nil {
+		holder := jobs.NewLeaseHolderID()
+		if leases != nil {
+			holder = leases.Holder()
+		}
+		runner = jobs.NewProvisioningRunner(context.Background(), db, hub, holder)
+	}
 
 	// Log Vault availability for debugging
 	if vaultClient != nil {
@@ -80,12 +103,42 @@ func NewHTTPServer(cfg config.AppConfig, deps ...any) *gin.Engine {
 
 	// Public health endpoint
 	v1.GET("/healthz", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
+		body := gin.H{
 			"status": "ok",
 			"env":    cfg.Environment,
-		})
+		}
+		if vaultClient != nil {
+			body["vaultCache"] = vaultClient.GetCacheStats()
+		}
+		c.JSON(http.StatusOK, body)
 	})
 
+	// Public lease status: which replica runs each background job
+	if db != nil && leases != nil {
+		v1.GET("/healthz/leases", func(c *gin.Context) {
+			rows, err := store.ListJobLeases(db)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to list job leases")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list job leases"})
+				return
+			}
+			now := time.Now()
+			items := make([]gin.H, 0, len(rows))
+			for _, l := range rows {
+				items = append(items, gin.H{
+					"name":       l.Name,
+					"holder":     l.Holder,
+					"active":     l.Holder != "" && l.ExpiresAt.After(now),
+					"self":       leases.Holds(l.Name),
+					"expiresAt":  l.ExpiresAt,
+					"acquiredAt": l.AcquiredAt,
+					"renewedAt":  l.RenewedAt,
+				})
+			}
+			c.JSON(http.StatusOK, gin.H{"instance": leases.Holder(), "leases": items})
+		})
+	}
+
 	// Apply authentication middleware to all other v1 routes
 	if db != nil {
 		// Create authenticated route group for regular HTTP routes
@@ -93,10 +146,13 @@ func NewHTTPServer(cfg config.AppConfig, deps ...any) *gin.Engine {
 		authed.Use(auth.RequireAuth(db))
 		{
 			if rw != nil {
-				ec := &controller.EnvironmentController{DB: db, Railway: rw, Vault: vaultClient}
+				idempotencyRetention := time.Duration(cfg.IdempotencyRetentionSeconds) * time.Second
+				ec := &controller.EnvironmentController{DB: db, Railway: rw, Vault: vaultClient, IdempotencyRetention: idempotencyRetention, Concurrency: cfg.ProvisionConcurrency}
 				ec.RegisterRoutes(authed)
-				sc := &controller.ServicesController{Railway: rw, DB: db, Vault: vaultClient}
+				sc := &controller.ServicesController{Railway: rw, DB: db, Vault: vaultClient, IdempotencyRetention: idempotencyRetention, Concurrency: cfg.ProvisionConcurrency}
 				sc.RegisterRoutes(authed)
+				jc := &controller.JobsController{DB: db, Railway: rw, Vault: vaultClient, Hub: hub, Runner: runner, IdempotencyRetention: idempotencyRetention, Concurrency: cfg.ProvisionConcurrency}
+				jc.RegisterRoutes(authed)
 
 				// Register non-WebSocket log routes with regular auth
 				lc := &controller.LogsController{DB: db, Railway: rw, AllowedOrigins: cfg.AllowedOrigins}
@@ -133,6 +189,14 @@ func NewHTTPServer(cfg config.AppConfig, deps ...any) *gin.Engine {
 			v1.GET("/services/:id/logs/stream", lc.StreamServiceLogs)
 			v1.GET("/environments/:id/logs/stream", lc.StreamEnvironmentLogs)
 		}
+
+		// Provisioning job progress stream - auth via first message, as for log streams
+		jc := &controller.JobsController{DB: db, Hub: hub, AllowedOrigins: cfg.AllowedOrigins}
+		v1.GET("/jobs/:id/stream", jc.StreamJob)
+
+		// Environment status stream fed by the status poller - auth via first message
+		esc := &controller.EnvironmentStatusController{DB: db, Hub: hub, AllowedOrigins: cfg.AllowedOrigins}
+		v1.GET("/environments/status/stream", esc.StreamEnvironmentStatus)
 	}
 
 	return r
@@ -1,5 +1,7 @@
 package status
 
+import "strings"
+
 // Canonical UI status constants used across the system.
 const (
 	StatusActive     = "active"
@@ -48,3 +50,20 @@ func NormalizeLocalToUI(s string) string {
 		return StatusUnknown
 	}
 }
+
+// NormalizeDeploymentToUI maps a Railway deployment status (e.g. "SUCCESS", "CRASHED")
+// into the canonical UI statuses. Any unrecognized value maps to "unknown".
+func NormalizeDeploymentToUI(s string) string {
+	switch strings.ToUpper(s) {
+	case "SUCCESS", "SLEEPING":
+		return StatusActive
+	case "BUILDING", "DEPLOYING", "INITIALIZING", "QUEUED", "WAITING":
+		return StatusCreating
+	case "REMOVING":
+		return StatusDestroying
+	case "FAILED", "CRASHED":
+		return StatusError
+	default:
+		return StatusUnknown
+	}
+}
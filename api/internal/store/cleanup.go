@@ -0,0 +1,44 @@
+package store
+
+import "gorm.io/gorm"
+
+// EnvironmentCleanupResult reports how many dependent rows were removed alongside an environment.
+type EnvironmentCleanupResult struct {
+	ServicesDeleted int64
+	VolumesDeleted  int64
+	MetadataDeleted int64
+}
+
+// DeleteEnvironmentRecords removes an environment together with its services, their volumes and
+// metadata.
+// Callers that need the cleanup to be atomic should pass a transaction handle.
+func DeleteEnvironmentRecords(tx *gorm.DB, env *Environment) (EnvironmentCleanupResult, error) {
+	var res EnvironmentCleanupResult
+
+	// Delete the volumes of this environment's services
+	result := tx.Where("service_id IN (?)", tx.Model(&Service{}).Select("id").Where("environment_id = ?", env.ID)).Delete(&Volume{})
+	if result.Error != nil {
+		return res, result.Error
+	}
+	res.VolumesDeleted = result.RowsAffected
+
+	// Delete all services for this environment
+	result = tx.Where("environment_id = ?", env.ID).Delete(&Service{})
+	if result.Error != nil {
+		return res, result.Error
+	}
+	res.ServicesDeleted = result.RowsAffected
+
+	// Delete metadata for this environment (may not exist for old environments)
+	result = tx.Where("environment_id = ?", env.ID).Delete(&EnvironmentMetadata{})
+	if result.Error != nil {
+		return res, result.Error
+	}
+	res.MetadataDeleted = result.RowsAffected
+
+	// Delete the environment itself
+	if err := tx.Delete(env).Error; err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,40 @@
+package store
+
+import "testing"
+
+func TestDeleteEnvironmentRecords_RemovesServiceVolumes(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	env := Environment{ID: "env-1", UserID: "user-1", Name: "dev", Type: EnvironmentTypeDev}
+	other := Environment{ID: "env-2", UserID: "user-1", Name: "prod", Type: EnvironmentTypeProd}
+	for _, e := range []*Environment{&env, &other} {
+		if err := db.Create(e).Error; err != nil {
+			t.Fatalf("create environment: %v", err)
+		}
+	}
+	services := []Service{
+		{ID: "svc-1", UserID: "user-1", EnvironmentID: env.ID, Name: "db", Volumes: []Volume{{ID: "vol-1", UserID: "user-1", MountPath: "/data"}}},
+		{ID: "svc-2", UserID: "user-1", EnvironmentID: other.ID, Name: "db", Volumes: []Volume{{ID: "vol-2", UserID: "user-1", MountPath: "/data"}}},
+	}
+	if err := db.Create(&services).Error; err != nil {
+		t.Fatalf("create services: %v", err)
+	}
+
+	res, err := DeleteEnvironmentRecords(db, &env)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if res.ServicesDeleted != 1 || res.VolumesDeleted != 1 {
+		t.Fatalf("unexpected cleanup result %+v", res)
+	}
+	var remaining []Volume
+	if err := db.Find(&remaining).Error; err != nil {
+		t.Fatalf("list volumes: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != "vol-2" {
+		t.Fatalf("expected only the other environment's volume to remain, got %+v", remaining)
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// HubEvent is an event published on one API replica, recorded so every other replica can
+// deliver it to its own WebSocket subscribers. Rows are short-lived and pruned after a
+// retention window.
+type HubEvent struct {
+	ID        uint64         `gorm:"primaryKey;autoIncrement"`
+	Origin    string         `gorm:"not null;type:text"` // Lease holder ID of the publishing replica
+	Topic     string         `gorm:"not null;type:text"`
+	Type      string         `gorm:"not null;type:text"`
+	DataJSON  datatypes.JSON `gorm:"type:jsonb"`
+	CreatedAt time.Time      `gorm:"index;not null"`
+}
+
+// ListHubEventsSince returns events recorded at or after since by replicas other than origin,
+// oldest first.
+func ListHubEventsSince(db *gorm.DB, origin string, since time.Time) ([]HubEvent, error) {
+	var events []HubEvent
+	err := db.Where("origin <> ? AND created_at >= ?", origin, since.UTC()).
+		Order("created_at ASC, id ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// PruneHubEvents deletes events recorded before cutoff.
+func PruneHubEvents(db *gorm.DB, cutoff time.Time) (int64, error) {
+	res := db.Where("created_at < ?", cutoff.UTC()).Delete(&HubEvent{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyStatus tracks whether the request holding an idempotency key has finished.
+type IdempotencyStatus string
+
+const (
+	IdempotencyStatusInProgress IdempotencyStatus = "in_progress"
+	IdempotencyStatusCompleted  IdempotencyStatus = "completed"
+)
+
+// IdempotencyRecord remembers the response to a provisioning request so a retry with the
+// same RequestID replays it instead of creating duplicate Railway resources.
+// Keys are unique per user and scope (the endpoint), so the same ID can't collide across endpoints.
+type IdempotencyRecord struct {
+	ID           string            `gorm:"primaryKey;type:text"`
+	UserID       string            `gorm:"uniqueIndex:idx_idempotency_key;not null;type:text"`
+	Scope        string            `gorm:"uniqueIndex:idx_idempotency_key;not null;type:text"`
+	RequestID    string            `gorm:"uniqueIndex:idx_idempotency_key;not null;type:text"`
+	RequestHash  string            `gorm:"type:text"` // Digest of the request payload, to detect a reused RequestID
+	Status       IdempotencyStatus `gorm:"not null;type:text"`
+	StatusCode   int
+	ResponseBody []byte
+	ContentType  string    `gorm:"type:text"`
+	ExpiresAt    time.Time `gorm:"index;not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// ClaimIdempotencyKey reserves (userID, scope, requestID) for the caller, recording requestHash
+// as the payload it was claimed for. When the key is already held, the existing record is
+// returned with claimed=false so the caller can replay a completed response or reject an
+// in-flight duplicate or a different payload. An in-progress claim for the same payload older
+// than staleAfter is assumed abandoned, e.g. by a crashed server, and taken over instead.
+// Expired records are purged first.
+func ClaimIdempotencyKey(db *gorm.DB, userID, scope, requestID, requestHash string, retention, staleAfter time.Duration) (IdempotencyRecord, bool, error) {
+	now := time.Now()
+	if _, err := PurgeExpiredIdempotencyRecords(db, now); err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+
+	record := IdempotencyRecord{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Scope:       scope,
+		RequestID:   requestID,
+		RequestHash: requestHash,
+		Status:      IdempotencyStatusInProgress,
+		ExpiresAt:   now.Add(retention),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	// The unique index arbitrates concurrent claims: exactly one insert wins
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if res.Error != nil {
+		return IdempotencyRecord{}, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	// Take over a stale claim. The conditional update lets only one concurrent caller win, and
+	// the new ID fences off the old holder: its complete or release no longer matches a row.
+	res = db.Model(&IdempotencyRecord{}).
+		Where("user_id = ? AND scope = ? AND request_id = ? AND request_hash IN ? AND status = ? AND updated_at < ?",
+			userID, scope, requestID, []string{requestHash, ""}, IdempotencyStatusInProgress, now.Add(-staleAfter)).
+		Updates(map[string]interface{}{
+			"id":           record.ID,
+			"request_hash": requestHash,
+			"expires_at":   record.ExpiresAt,
+			"created_at":   now,
+			"updated_at":   now,
+		})
+	if res.Error != nil {
+		return IdempotencyRecord{}, false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing IdempotencyRecord
+	err := db.Where("user_id = ? AND scope = ? AND request_id = ?", userID, scope, requestID).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Released between our insert and read; let the caller retry as a fresh request
+		return IdempotencyRecord{}, false, err
+	}
+	return existing, false, err
+}
+
+// CompleteIdempotencyKey stores the response for a claimed key so duplicates can replay it.
+func CompleteIdempotencyKey(db *gorm.DB, id string, statusCode int, contentType string, body []byte) error {
+	return db.Model(&IdempotencyRecord{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        IdempotencyStatusCompleted,
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+		"updated_at":    time.Now(),
+	}).Error
+}
+
+// ReleaseIdempotencyKey drops a claim so the request can be retried, e.g. after a failure.
+func ReleaseIdempotencyKey(db *gorm.DB, id string) error {
+	return db.Where("id = ?", id).Delete(&IdempotencyRecord{}).Error
+}
+
+// PurgeExpiredIdempotencyRecords deletes records whose retention window ended before now.
+func PurgeExpiredIdempotencyRecords(db *gorm.DB, now time.Time) (int64, error) {
+	res := db.Where("expires_at < ?", now).Delete(&IdempotencyRecord{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimIdempotencyKey_DuplicateReturnsExisting(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	first, claimed, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, time.Hour)
+	if err != nil || !claimed {
+		t.Fatalf("expected first claim to succeed, claimed=%v err=%v", claimed, err)
+	}
+
+	dup, claimed, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("duplicate claim failed: %v", err)
+	}
+	if claimed || dup.ID != first.ID || dup.Status != IdempotencyStatusInProgress {
+		t.Fatalf("expected in-progress record %s, got claimed=%v record=%+v", first.ID, claimed, dup)
+	}
+
+	// The same request ID is independent across users and scopes
+	if _, claimed, _ := ClaimIdempotencyKey(db, "user-2", "scope", "req-1", "", time.Hour, time.Hour); !claimed {
+		t.Fatalf("expected claim for another user to succeed")
+	}
+	if _, claimed, _ := ClaimIdempotencyKey(db, "user-1", "other", "req-1", "", time.Hour, time.Hour); !claimed {
+		t.Fatalf("expected claim for another scope to succeed")
+	}
+
+	if err := CompleteIdempotencyKey(db, first.ID, 201, "application/json", []byte(`{"ok":true}`)); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	done, _, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("claim after completion failed: %v", err)
+	}
+	if done.Status != IdempotencyStatusCompleted || done.StatusCode != 201 || string(done.ResponseBody) != `{"ok":true}` {
+		t.Fatalf("unexpected completed record: %+v", done)
+	}
+}
+
+func TestClaimIdempotencyKey_ReleasedAndExpiredKeysCanBeReclaimed(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	record, _, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if err := ReleaseIdempotencyKey(db, record.ID); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if _, claimed, _ := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, time.Hour); !claimed {
+		t.Fatalf("expected released key to be reclaimable")
+	}
+
+	if _, _, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-2", "", -time.Minute, time.Hour); err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if _, claimed, _ := ClaimIdempotencyKey(db, "user-1", "scope", "req-2", "", time.Hour, time.Hour); !claimed {
+		t.Fatalf("expected expired key to be purged and reclaimed")
+	}
+}
+
+func TestClaimIdempotencyKey_TakesOverStaleInProgressClaim(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	abandoned, _, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if err := db.Model(&IdempotencyRecord{}).Where("id = ?", abandoned.ID).Update("updated_at", time.Now().Add(-20*time.Minute)).Error; err != nil {
+		t.Fatalf("backdate failed: %v", err)
+	}
+
+	retry, claimed, err := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, 15*time.Minute)
+	if err != nil || !claimed {
+		t.Fatalf("expected stale claim to be taken over, claimed=%v err=%v", claimed, err)
+	}
+	if retry.ID == abandoned.ID {
+		t.Fatalf("expected the takeover to get a new record ID")
+	}
+	if _, claimed, _ := ClaimIdempotencyKey(db, "user-1", "scope", "req-1", "", time.Hour, 15*time.Minute); claimed {
+		t.Fatalf("expected the fresh takeover to hold the key")
+	}
+
+	// The abandoned holder finishing late must not overwrite the new claim
+	if err := CompleteIdempotencyKey(db, abandoned.ID, 201, "application/json", []byte(`{"stale":true}`)); err != nil {
+		t.Fatalf("complete failed: %v", err)
+	}
+	var current IdempotencyRecord
+	if err := db.Where("request_id = ?", "req-1").First(&current).Error; err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if current.ID != retry.ID || current.Status != IdempotencyStatusInProgress {
+		t.Fatalf("unexpected record after late completion: %+v", current)
+	}
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JobLease grants one API replica the right to run a named background job (e.g. the status
+// poller) until ExpiresAt. The holder renews it periodically; if the holder dies, the lease
+// expires and another replica takes it over.
+type JobLease struct {
+	Name       string    `gorm:"primaryKey;type:text" json:"name"`
+	Holder     string    `gorm:"type:text" json:"holder"` // Replica ID, empty when released
+	ExpiresAt  time.Time `gorm:"index;not null" json:"expiresAt"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	RenewedAt  time.Time `json:"renewedAt"`
+}
+
+// AcquireJobLease acquires or renews the lease called name for holder and reports whether
+// holder now owns it. Ownership changes only through conditional UPDATEs, so concurrent
+// replicas can't both win: Postgres serializes them on the lease row's lock, SQLite on its
+// database write lock.
+func AcquireJobLease(db *gorm.DB, name, holder string, ttl time.Duration, now time.Time) (bool, error) {
+	// Make sure the row exists; an expired, unheld lease is free for anyone to take
+	seed := JobLease{Name: name, ExpiresAt: time.Unix(0, 0).UTC()}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+		return false, err
+	}
+
+	// Compare in UTC: SQLite stores timestamps as text, so mixed offsets would misorder
+	now = now.UTC()
+	expiresAt := now.Add(ttl)
+
+	// Renew if we already hold it
+	res := db.Model(&JobLease{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Updates(map[string]interface{}{"expires_at": expiresAt, "renewed_at": now})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// Otherwise take it over only if the current lease has lapsed
+	res = db.Model(&JobLease{}).
+		Where("name = ? AND expires_at < ?", name, now).
+		Updates(map[string]interface{}{"holder": holder, "expires_at": expiresAt, "acquired_at": now, "renewed_at": now})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+// ReleaseJobLease gives up the lease if holder owns it, so another replica can take over
+// without waiting for it to expire.
+func ReleaseJobLease(db *gorm.DB, name, holder string) error {
+	return db.Model(&JobLease{}).
+		Where("name = ? AND holder = ?", name, holder).
+		Updates(map[string]interface{}{"holder": "", "expires_at": time.Unix(0, 0).UTC()}).Error
+}
+
+// DeleteJobLease removes the lease row called name, for leases that are never reused once
+// released, such as a replica's liveness lease.
+func DeleteJobLease(db *gorm.DB, name string) error {
+	return db.Where("name = ?", name).Delete(&JobLease{}).Error
+}
+
+// DeleteExpiredJobLeases removes leases whose name starts with prefix and that expired before
+// now, e.g. the liveness leases of replicas that died without deleting theirs.
+func DeleteExpiredJobLeases(db *gorm.DB, prefix string, now time.Time) (int64, error) {
+	res := db.Where("name LIKE ? AND expires_at < ?", prefix+"%", now.UTC()).Delete(&JobLease{})
+	return res.RowsAffected, res.Error
+}
+
+// ListJobLeases returns every lease ordered by name.
+func ListJobLeases(db *gorm.DB) ([]JobLease, error) {
+	var leases []JobLease
+	err := db.Order("name").Find(&leases).Error
+	return leases, err
+}
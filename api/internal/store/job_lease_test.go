@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcquireJobLease_ExclusiveUntilExpiry(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	now := time.Now()
+	ttl := 30 * time.Second
+
+	if held, err := AcquireJobLease(db, "poller", "a", ttl, now); err != nil || !held {
+		t.Fatalf("expected a to acquire free lease, held=%v err=%v", held, err)
+	}
+	if held, _ := AcquireJobLease(db, "poller", "b", ttl, now.Add(time.Second)); held {
+		t.Fatalf("expected b to be refused while a holds the lease")
+	}
+	if held, _ := AcquireJobLease(db, "poller", "a", ttl, now.Add(20*time.Second)); !held {
+		t.Fatalf("expected a to renew its lease")
+	}
+	// Renewal pushed expiry to now+50s, so b still can't take over at now+40s
+	if held, _ := AcquireJobLease(db, "poller", "b", ttl, now.Add(40*time.Second)); held {
+		t.Fatalf("expected renewed lease to still be held")
+	}
+	if held, _ := AcquireJobLease(db, "reaper", "b", ttl, now); !held {
+		t.Fatalf("expected leases to be independent by name")
+	}
+
+	// a stops renewing: b takes over once the lease lapses
+	if held, _ := AcquireJobLease(db, "poller", "b", ttl, now.Add(51*time.Second)); !held {
+		t.Fatalf("expected b to take over expired lease")
+	}
+	if held, _ := AcquireJobLease(db, "poller", "a", ttl, now.Add(52*time.Second)); held {
+		t.Fatalf("expected a to have lost the lease")
+	}
+
+	leases, err := ListJobLeases(db)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(leases) != 2 || leases[0].Name != "poller" || leases[0].Holder != "b" {
+		t.Fatalf("unexpected leases %+v", leases)
+	}
+}
+
+func TestReleaseJobLease_FreesForOthers(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	now := time.Now()
+
+	if held, _ := AcquireJobLease(db, "poller", "a", time.Minute, now); !held {
+		t.Fatalf("expected a to acquire")
+	}
+	// Only the holder can release
+	if err := ReleaseJobLease(db, "poller", "b"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if held, _ := AcquireJobLease(db, "poller", "b", time.Minute, now); held {
+		t.Fatalf("expected release by non-holder to be a no-op")
+	}
+
+	if err := ReleaseJobLease(db, "poller", "a"); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if held, _ := AcquireJobLease(db, "poller", "b", time.Minute, now); !held {
+		t.Fatalf("expected b to acquire released lease immediately")
+	}
+}
@@ -78,6 +78,7 @@ type Environment struct {
 	RailwayProjectID     string          `gorm:"type:text" json:"railwayProjectId"` // Railway project ID (needed for provision outputs)
 	RailwayEnvironmentID string          `gorm:"type:text" json:"railwayEnvironmentId"`
 	TTLSeconds           *int64          `gorm:"type:integer" json:"ttlSeconds,omitempty"`
+	ExpiresAt            *time.Time      `gorm:"index" json:"expiresAt,omitempty"` // CreatedAt + TTLSeconds, nil when the environment never expires
 	ParentEnvironmentID  *string         `gorm:"type:text" json:"parentEnvironmentId,omitempty"`
 	CreatedAt            time.Time       `gorm:"index" json:"createdAt"`
 	UpdatedAt            time.Time       `json:"updatedAt"`
@@ -91,6 +92,7 @@ type DeploymentType string
 const (
 	DeploymentTypeSourceRepo  DeploymentType = "source_repo"
 	DeploymentTypeDockerImage DeploymentType = "docker_image"
+	DeploymentTypeDatabase    DeploymentType = "database" // Railway database template (image plus generated credentials)
 )
 
 type Service struct {
@@ -125,15 +127,32 @@ type Service struct {
 	ImageDigest     string `gorm:"type:text" json:"imageDigest"`
 	ImageAuthStored bool   `gorm:"default:false" json:"imageAuthStored"` // Indicates if Railway has stored auth credentials
 
+	// Database template (for database deployment; image fields hold the template image)
+	DatabaseEngine string `gorm:"type:text" json:"databaseEngine,omitempty"` // postgres, redis, mysql or mongo
+
 	// Runtime configuration
 	ExposedPortsJSON string  `gorm:"type:text" json:"exposedPortsJson"`          // JSON array of port numbers
 	HealthCheckPath  *string `gorm:"type:text" json:"healthCheckPath,omitempty"` // Health check endpoint path
 	StartCommand     *string `gorm:"type:text" json:"startCommand,omitempty"`    // Custom start command
 
+	Volumes []Volume `gorm:"foreignKey:ServiceID" json:"volumes,omitempty"`
+
 	User        *User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Environment *Environment `gorm:"foreignKey:EnvironmentID" json:"environment,omitempty"`
 }
 
+// Volume is a persistent Railway volume mounted into a service.
+type Volume struct {
+	ID              string    `gorm:"primaryKey;type:text" json:"id"`
+	UserID          string    `gorm:"index;not null;type:text" json:"userId"`
+	ServiceID       string    `gorm:"index;not null;type:text" json:"serviceId"` // Foreign key to Service
+	RailwayVolumeID string    `gorm:"type:text" json:"railwayVolumeId"`
+	MountPath       string    `gorm:"type:text;not null" json:"mountPath"`
+	SizeMB          int       `json:"sizeMB"` // Capacity Railway allotted, 0 when not reported
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
 // EnvironmentMetadata stores complete wizard state and provision outputs
 // to enable environment cloning, branch-based deployments, and template creation.
 type EnvironmentMetadata struct {
@@ -152,8 +171,10 @@ type EnvironmentMetadata struct {
 	ClonedFromEnvID *string `gorm:"type:text"` // ID of environment this was cloned from
 
 	// Wizard state and provision outputs (stored as JSON for flexibility)
-	WizardInputsJSON     datatypes.JSON `gorm:"type:jsonb"` // Complete wizard state (all inputs from all steps)
-	ProvisionOutputsJSON datatypes.JSON `gorm:"type:jsonb"` // Provision outputs (Railway project/environment/service IDs)
+	WizardInputsJSON      datatypes.JSON `gorm:"type:jsonb"` // Complete wizard state (all inputs from all steps)
+	ProvisionOutputsJSON  datatypes.JSON `gorm:"type:jsonb"` // Provision outputs (Railway project/environment/service IDs)
+	ServiceLinksJSON      datatypes.JSON `gorm:"type:jsonb"` // Cross-service variable references (service, variable, target, value)
+	TemplateVariablesJSON datatypes.JSON `gorm:"type:jsonb"` // Variables captured when saved as a template (shared and per service, secrets excluded)
 
 	User        *User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	Environment *Environment `gorm:"foreignKey:EnvironmentID" json:"environment,omitempty"`
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// JobStatus is the lifecycle state of a provisioning job or one of its steps.
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusSkipped   JobStatus = "skipped" // Step not run because an earlier step failed
+)
+
+// IsTerminal reports whether no further updates will follow this status.
+func (s JobStatus) IsTerminal() bool {
+	return s == JobStatusSucceeded || s == JobStatusFailed
+}
+
+// JobStep records the progress of one step of a provisioning job.
+type JobStep struct {
+	Name       string          `json:"name"`
+	Status     JobStatus       `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	Output     json.RawMessage `json:"output,omitempty"` // Step result, e.g. created Railway IDs
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// ProvisioningJob tracks a long-running provisioning request (project → environment → services)
+// executed in the background so clients can poll or stream its progress.
+type ProvisioningJob struct {
+	ID          string         `gorm:"primaryKey;type:text"`
+	UserID      string         `gorm:"index;not null;type:text"` // Foreign key to User
+	Status      JobStatus      `gorm:"index;not null;type:text"`
+	CurrentStep string         `gorm:"type:text"`
+	Holder      string         `gorm:"index;type:text"` // Lease holder ID of the replica running the job
+	Error       *string        `gorm:"type:text"`
+	RequestJSON datatypes.JSON `gorm:"type:jsonb"` // Original request payload
+	StepsJSON   datatypes.JSON `gorm:"type:jsonb"` // []JobStep in execution order
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// Steps decodes the job's steps.
+func (j *ProvisioningJob) Steps() ([]JobStep, error) {
+	var steps []JobStep
+	if len(j.StepsJSON) == 0 {
+		return steps, nil
+	}
+	err := json.Unmarshal(j.StepsJSON, &steps)
+	return steps, err
+}
+
+// SetSteps encodes steps onto the job.
+func (j *ProvisioningJob) SetSteps(steps []JobStep) error {
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	j.StepsJSON = data
+	return nil
+}
+
+// FailInterruptedJobs marks jobs left pending or running by a replica that is gone as failed.
+// Jobs run in the process that queued them, so once their holder stops renewing its leases
+// nothing will ever finish them. Jobs of replicas still holding a live lease are left alone.
+func FailInterruptedJobs(db *gorm.DB, now time.Time) (int64, error) {
+	const reason = "interrupted by server restart"
+	live := db.Model(&JobLease{}).Select("holder").Where("holder <> '' AND expires_at >= ?", now.UTC())
+	res := db.Model(&ProvisioningJob{}).
+		Where("status IN ?", []JobStatus{JobStatusPending, JobStatusRunning}).
+		Where("holder = '' OR holder NOT IN (?)", live).
+		Updates(map[string]interface{}{
+			"status":      JobStatusFailed,
+			"error":       reason,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return res.RowsAffected, res.Error
+}
@@ -17,7 +17,7 @@ func Open(sqlitePath string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := db.AutoMigrate(&User{}, &Environment{}, &Service{}, &EnvironmentMetadata{}); err != nil {
+	if err := migrate(db); err != nil {
 		return nil, err
 	}
 	return db, nil
@@ -37,7 +37,7 @@ func OpenFromURL(databaseURL string) (*gorm.DB, error) {
 		if err != nil {
 			return nil, err
 		}
-		if err := db.AutoMigrate(&User{}, &Environment{}, &Service{}, &EnvironmentMetadata{}); err != nil {
+		if err := migrate(db); err != nil {
 			return nil, err
 		}
 		return db, nil
@@ -45,3 +45,11 @@ func OpenFromURL(databaseURL string) (*gorm.DB, error) {
 	// Otherwise treat as sqlite DSN or path
 	return Open(trimmed)
 }
+
+// migrate runs AutoMigrate for all models and backfills derived columns.
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&User{}, &Environment{}, &Service{}, &Volume{}, &EnvironmentMetadata{}, &IdempotencyRecord{}, &ProvisioningJob{}, &JobLease{}, &HubEvent{}); err != nil {
+		return err
+	}
+	return backfillEnvironmentExpiry(db)
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ComputeExpiresAt returns the moment an environment created at createdAt expires,
+// or nil when no TTL is set.
+func ComputeExpiresAt(createdAt time.Time, ttlSeconds *int64) *time.Time {
+	if ttlSeconds == nil || *ttlSeconds <= 0 {
+		return nil
+	}
+	at := createdAt.Add(time.Duration(*ttlSeconds) * time.Second)
+	return &at
+}
+
+// SetExpiry points the environment's expiry at the given time and keeps TTLSeconds
+// consistent with it (TTLSeconds is always measured from CreatedAt). A nil time clears both.
+func (e *Environment) SetExpiry(at *time.Time) {
+	if at == nil {
+		e.TTLSeconds = nil
+		e.ExpiresAt = nil
+		return
+	}
+	ttl := int64(at.Sub(e.CreatedAt).Seconds())
+	expiresAt := *at
+	e.TTLSeconds = &ttl
+	e.ExpiresAt = &expiresAt
+}
+
+// backfillEnvironmentExpiry populates ExpiresAt for environments created before the column existed.
+func backfillEnvironmentExpiry(db *gorm.DB) error {
+	var envs []Environment
+	if err := db.Where("ttl_seconds IS NOT NULL AND ttl_seconds > 0 AND expires_at IS NULL").Find(&envs).Error; err != nil {
+		return err
+	}
+	for _, e := range envs {
+		expiresAt := ComputeExpiresAt(e.CreatedAt, e.TTLSeconds)
+		if err := db.Model(&Environment{}).Where("id = ?", e.ID).Update("expires_at", expiresAt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package vault
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// secretCache is a TTL-bounded, in-memory cache of secret values keyed by secret path.
+// Secret paths are scoped to a user (users/{userID}/...), so entries are per user.
+// The cache is per replica: a secret rotated or deleted through one replica is invalidated
+// there only, and other replicas keep serving the old value until their entry expires.
+//
+// Expired entries are kept for up to staleTTL so they can be served while the circuit
+// breaker is open; outside that window an expired entry behaves like a miss.
+// A nil *secretCache is valid and caches nothing.
+type secretCache struct {
+	mu       sync.RWMutex
+	entries  map[string]cacheEntry
+	ttl      time.Duration
+	staleTTL time.Duration
+	now      func() time.Time
+	hits     atomic.Int64
+	misses   atomic.Int64
+}
+
+// cacheEntry is a cached secret value and the time it stops being fresh
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// newSecretCache creates a cache whose entries stay fresh for ttl.
+// Returns nil (caching disabled) when ttl is not positive.
+func newSecretCache(ttl time.Duration) *secretCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &secretCache{
+		entries:  make(map[string]cacheEntry),
+		ttl:      ttl,
+		staleTTL: DefaultCacheStaleTTL,
+		now:      time.Now,
+	}
+}
+
+// get returns a fresh cached value for path and records a hit or miss.
+func (sc *secretCache) get(path string) (string, bool) {
+	if sc == nil {
+		return "", false
+	}
+
+	sc.mu.RLock()
+	entry, ok := sc.entries[path]
+	sc.mu.RUnlock()
+
+	if ok && sc.now().Before(entry.expiresAt) {
+		sc.hits.Add(1)
+		return entry.value, true
+	}
+	sc.misses.Add(1)
+	return "", false
+}
+
+// getStale returns a cached value for path even if it has expired, as long as it is
+// still within the stale window. Used as a fallback when Vault can't be reached.
+func (sc *secretCache) getStale(path string) (string, bool) {
+	if sc == nil {
+		return "", false
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	entry, ok := sc.entries[path]
+	if !ok {
+		return "", false
+	}
+	if sc.now().After(entry.expiresAt.Add(sc.staleTTL)) {
+		delete(sc.entries, path)
+		return "", false
+	}
+	return entry.value, true
+}
+
+// set stores a value for path, replacing any existing entry.
+func (sc *secretCache) set(path, value string) {
+	if sc == nil {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.entries[path] = cacheEntry{value: value, expiresAt: sc.now().Add(sc.ttl)}
+}
+
+// invalidate removes the entry for path.
+func (sc *secretCache) invalidate(path string) {
+	if sc == nil {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	delete(sc.entries, path)
+}
+
+// invalidateTree removes the entry for root and every entry nested under it,
+// e.g. all of a user's secrets when the user is deleted.
+func (sc *secretCache) invalidateTree(root string) {
+	if sc == nil {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	root = strings.TrimSuffix(root, "/")
+	for path := range sc.entries {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			delete(sc.entries, path)
+		}
+	}
+}
+
+// stats returns the current cache metrics.
+func (sc *secretCache) stats() CacheStats {
+	if sc == nil {
+		return CacheStats{}
+	}
+
+	sc.mu.RLock()
+	size := len(sc.entries)
+	sc.mu.RUnlock()
+
+	hits := sc.hits.Load()
+	misses := sc.misses.Load()
+
+	stats := CacheStats{Size: size, Hits: hits, Misses: misses}
+	if total := hits + misses; total > 0 {
+		stats.HitRate = float64(hits) / float64(total)
+	}
+	return stats
+}
+
+// GetCacheStats returns statistics about the secret cache.
+// Returns empty stats if caching is not enabled.
+func (c *Client) GetCacheStats() CacheStats {
+	return c.cache.stats()
+}
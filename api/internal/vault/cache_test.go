@@ -0,0 +1,151 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretCache_HitsMissesAndExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newSecretCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	_, ok := cache.get("users/u1/railway")
+	assert.False(t, ok)
+
+	cache.set("users/u1/railway", "token-1")
+	value, ok := cache.get("users/u1/railway")
+	require.True(t, ok)
+	assert.Equal(t, "token-1", value)
+
+	// Expired entries are misses but still available as stale values
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("users/u1/railway")
+	assert.False(t, ok)
+	value, ok = cache.getStale("users/u1/railway")
+	require.True(t, ok)
+	assert.Equal(t, "token-1", value)
+
+	// Past the stale window the entry is dropped
+	now = now.Add(DefaultCacheStaleTTL)
+	_, ok = cache.getStale("users/u1/railway")
+	assert.False(t, ok)
+
+	stats := cache.stats()
+	assert.Equal(t, 0, stats.Size)
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+	assert.InDelta(t, 1.0/3.0, stats.HitRate, 0.0001)
+}
+
+func TestSecretCache_InvalidateTree(t *testing.T) {
+	cache := newSecretCache(time.Minute)
+	cache.set("users/u1/railway", "a")
+	cache.set("users/u1/github", "b")
+	cache.set("users/u10/railway", "c")
+
+	cache.invalidateTree("users/u1")
+
+	_, ok := cache.get("users/u1/railway")
+	assert.False(t, ok)
+	_, ok = cache.get("users/u1/github")
+	assert.False(t, ok)
+	_, ok = cache.get("users/u10/railway")
+	assert.True(t, ok)
+}
+
+func TestSecretCache_DisabledIsNoop(t *testing.T) {
+	cache := newSecretCache(0)
+	assert.Nil(t, cache)
+
+	cache.set("users/u1/railway", "a")
+	_, ok := cache.get("users/u1/railway")
+	assert.False(t, ok)
+	assert.Equal(t, CacheStats{}, cache.stats())
+}
+
+// newTestTokenServer serves a single Railway token secret and counts reads.
+// When failing is set every request returns a 500.
+func newTestTokenServer(t *testing.T, reads *atomic.Int64, failing *atomic.Bool) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":["vault is sealed"]}`))
+			return
+		}
+		if r.Method == http.MethodGet {
+			reads.Add(1)
+			_, _ = w.Write([]byte(`{"data":{"data":{"token":"railway-token"}}}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+
+	return &Client{
+		address:        srv.URL,
+		httpClient:     srv.Client(),
+		mountPath:      DefaultMountPath,
+		circuitBreaker: NewCircuitBreaker(),
+		cache:          newSecretCache(time.Minute),
+	}
+}
+
+func TestGetRailwayToken_ReadsThroughCache(t *testing.T) {
+	var reads atomic.Int64
+	var failing atomic.Bool
+	c := newTestTokenServer(t, &reads, &failing)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		token, err := c.GetRailwayToken(ctx, "u1")
+		require.NoError(t, err)
+		assert.Equal(t, "railway-token", token)
+	}
+	assert.Equal(t, int64(1), reads.Load())
+
+	// Storing a token invalidates the cached value
+	require.NoError(t, c.StoreRailwayToken(ctx, "u1", "railway-token"))
+	_, err := c.GetRailwayToken(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), reads.Load())
+
+	stats := c.GetCacheStats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+}
+
+func TestGetRailwayToken_ServesStaleWhileCircuitOpen(t *testing.T) {
+	var reads atomic.Int64
+	var failing atomic.Bool
+	c := newTestTokenServer(t, &reads, &failing)
+	ctx := context.Background()
+
+	_, err := c.GetRailwayToken(ctx, "u1")
+	require.NoError(t, err)
+
+	// Expire the entry, then trip the breaker
+	c.cache.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	failing.Store(true)
+	for i := 0; i < DefaultFailureThreshold; i++ {
+		_, err = c.GetRailwayToken(ctx, "u1")
+		require.Error(t, err)
+	}
+	require.Equal(t, CircuitOpen, c.GetCircuitState())
+
+	token, err := c.GetRailwayToken(ctx, "u1")
+	require.NoError(t, err)
+	assert.Equal(t, "railway-token", token)
+
+	// Nothing cached for another user, so the open circuit surfaces
+	_, err = c.GetRailwayToken(ctx, "u2")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+}
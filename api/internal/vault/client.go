@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +29,7 @@ type Client struct {
 	healthStop     chan struct{}
 	healthStopOnce sync.Once
 	shutdownOnce   sync.Once
+	cache          *secretCache
 }
 
 // NewClient creates a new Vault HTTP client with the provided configuration
@@ -61,6 +63,7 @@ func NewClient(cfg Config) (*Client, error) {
 		renewalStop:    make(chan struct{}),
 		circuitBreaker: NewCircuitBreaker(),
 		healthStop:     make(chan struct{}),
+		cache:          newSecretCache(cfg.CacheTTL),
 	}
 
 	// Authenticate with Vault
@@ -80,6 +83,7 @@ func NewClient(cfg Config) (*Client, error) {
 		Str("address", cfg.Address).
 		Str("mount_path", cfg.MountPath).
 		Bool("skip_verify", cfg.SkipVerify).
+		Dur("cache_ttl", cfg.CacheTTL).
 		Msg("Vault HTTP client initialized")
 
 	return client, nil
@@ -511,6 +515,9 @@ func (c *Client) DeletePathRecursive(ctx context.Context, path string) error {
 		return fmt.Errorf("failed to delete path %s: %w", path, err)
 	}
 
+	// Drop cached secrets under the deleted path so they aren't served afterwards
+	c.cache.invalidateTree(strings.TrimPrefix(path, fmt.Sprintf("/v1/%s/metadata/", c.mountPath)))
+
 	log.Debug().Str("path", path).Msg("deleted path from vault")
 	return nil
 }
@@ -1,6 +1,9 @@
 package vault
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // Config holds configuration for the Vault client
 type Config struct {
@@ -18,6 +21,8 @@ type Config struct {
 	SkipVerify bool
 	// MountPath is the KV v2 secrets engine mount path (default: "mirage")
 	MountPath string
+	// CacheTTL is how long token lookups are cached in memory (0 disables caching)
+	CacheTTL time.Duration
 }
 
 // Validate checks if the configuration is valid
@@ -0,0 +1,484 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Custom metadata keys used for generic secrets. KV v2 custom_metadata is stored per secret
+// rather than per version, so tags and other metadata can change without creating a new version.
+const (
+	customMetaCreatedBy     = "created_by"
+	customMetaCreatedAt     = "created_at"
+	customMetaSecretType    = "secret_type"
+	customMetaTags          = "tags"
+	customMetaLastValidated = "last_validated"
+
+	// tagSeparator joins tags into a single custom_metadata value (custom_metadata only holds strings)
+	tagSeparator = ","
+)
+
+// customSecretKeyPattern restricts generic secret keys to a single, URL-safe path segment.
+var customSecretKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+// kvMetadataResponse is the response from the KV v2 metadata endpoint
+type kvMetadataResponse struct {
+	Data struct {
+		CurrentVersion int                       `json:"current_version"`
+		CustomMetadata map[string]string         `json:"custom_metadata"`
+		Versions       map[string]kvVersionState `json:"versions"`
+	} `json:"data"`
+}
+
+// kvVersionState describes a single version in the KV v2 metadata response
+type kvVersionState struct {
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time"`
+	Destroyed    bool   `json:"destroyed"`
+}
+
+// ValidateSecretKey checks that a key can be used to name a generic secret.
+func ValidateSecretKey(key string) error {
+	if !customSecretKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be 1-128 characters of letters, digits, '.', '_' or '-'", ErrInvalidSecret, key)
+	}
+	return nil
+}
+
+// StoreSecret stores a generic secret with metadata, creating a new version if it already exists.
+// CreatedBy and CreatedAt are preserved from the first version.
+func (c *Client) StoreSecret(ctx context.Context, userID, key, value string, metadata SecretMetadata) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if err := ValidateSecretKey(key); err != nil {
+		return err
+	}
+	if value == "" {
+		return ErrInvalidSecret
+	}
+
+	secretPath := BuildCustomSecretPath(userID, key)
+
+	// Preserve creation metadata across versions
+	custom := map[string]string{
+		customMetaCreatedBy: userID,
+		customMetaCreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	existing, err := c.readSecretMetadata(ctx, secretPath)
+	if err == nil {
+		if v := existing.Data.CustomMetadata[customMetaCreatedBy]; v != "" {
+			custom[customMetaCreatedBy] = v
+		}
+		if v := existing.Data.CustomMetadata[customMetaCreatedAt]; v != "" {
+			custom[customMetaCreatedAt] = v
+		}
+		// A rotation that doesn't mention tags or type keeps them
+		for _, k := range []string{customMetaTags, customMetaSecretType} {
+			if v := existing.Data.CustomMetadata[k]; v != "" {
+				custom[k] = v
+			}
+		}
+	} else if err != ErrSecretNotFound {
+		return fmt.Errorf("failed to read secret metadata: %w", err)
+	}
+	applySecretMetadata(custom, metadata)
+
+	if err := c.writeSecretValue(ctx, secretPath, value); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	if err := c.writeCustomMetadata(ctx, secretPath, custom); err != nil {
+		return fmt.Errorf("failed to store secret metadata: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("key", key).
+		Str("secret_path", secretPath).
+		Msg("stored secret")
+
+	return nil
+}
+
+// GetSecret retrieves the current version of a secret with its metadata.
+// Returns ErrSecretNotFound if the secret doesn't exist.
+func (c *Client) GetSecret(ctx context.Context, userID, key string) (Secret, error) {
+	return c.getSecretAtVersion(ctx, userID, key, 0)
+}
+
+// GetSecretValue retrieves only the current value of a secret.
+// Returns ErrSecretNotFound if the secret doesn't exist.
+func (c *Client) GetSecretValue(ctx context.Context, userID, key string) (string, error) {
+	if userID == "" {
+		return "", fmt.Errorf("user ID is required")
+	}
+	if err := ValidateSecretKey(key); err != nil {
+		return "", err
+	}
+
+	value, _, err := c.readSecretValue(ctx, BuildCustomSecretPath(userID, key), 0)
+	if err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// DeleteSecret soft-deletes the current version of a secret. Earlier versions are kept
+// for audit purposes and can be restored with RollbackSecret.
+// Returns ErrSecretNotFound if the secret doesn't exist.
+func (c *Client) DeleteSecret(ctx context.Context, userID, key string) error {
+	if _, err := c.GetSecretValue(ctx, userID, key); err != nil {
+		return err
+	}
+
+	secretPath := BuildCustomSecretPath(userID, key)
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+	if err := c.makeRequest(ctx, "DELETE", kvPath, nil, nil); err != nil {
+		if isNotFoundError(err) {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("key", key).
+		Str("secret_path", secretPath).
+		Msg("deleted secret")
+
+	return nil
+}
+
+// ListSecrets returns metadata for all of a user's secrets, sorted by key.
+// Secrets whose current version is deleted are omitted.
+func (c *Client) ListSecrets(ctx context.Context, userID string) ([]SecretMetadata, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	listPath := fmt.Sprintf("/v1/%s/metadata/%s", c.mountPath, BuildUserSecretPath(userID, PathCustom))
+
+	var response struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	if err := c.makeRequest(ctx, "LIST", listPath, nil, &response); err != nil {
+		// Vault returns 404 when nothing has been stored under the prefix yet
+		if isNotFoundError(err) {
+			return []SecretMetadata{}, nil
+		}
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	secrets := make([]SecretMetadata, 0, len(response.Data.Keys))
+	for _, key := range response.Data.Keys {
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		meta, err := c.GetSecretMetadata(ctx, userID, key)
+		if err != nil {
+			if err == ErrSecretNotFound {
+				continue
+			}
+			return nil, err
+		}
+		secrets = append(secrets, meta)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Key < secrets[j].Key })
+
+	return secrets, nil
+}
+
+// GetSecretVersion retrieves a specific version of a secret.
+// Returns ErrVersionNotFound if the version doesn't exist or was deleted.
+func (c *Client) GetSecretVersion(ctx context.Context, userID, key string, version int) (Secret, error) {
+	if version <= 0 {
+		return Secret{}, ErrVersionNotFound
+	}
+	secret, err := c.getSecretAtVersion(ctx, userID, key, version)
+	if err == ErrSecretNotFound {
+		return Secret{}, ErrVersionNotFound
+	}
+	return secret, err
+}
+
+// ListSecretVersions returns all version numbers for a secret, newest first.
+// Returns an empty slice if the secret has no versions.
+func (c *Client) ListSecretVersions(ctx context.Context, userID, key string) ([]int, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+	if err := ValidateSecretKey(key); err != nil {
+		return nil, err
+	}
+
+	meta, err := c.readSecretMetadata(ctx, BuildCustomSecretPath(userID, key))
+	if err != nil {
+		if err == ErrSecretNotFound {
+			return []int{}, nil
+		}
+		return nil, fmt.Errorf("failed to read secret versions: %w", err)
+	}
+
+	versions := make([]int, 0, len(meta.Data.Versions))
+	for v := range meta.Data.Versions {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	return versions, nil
+}
+
+// RollbackSecret writes the value of an earlier version as a new version,
+// keeping the full history intact.
+// Returns ErrVersionNotFound if the target version doesn't exist.
+func (c *Client) RollbackSecret(ctx context.Context, userID, key string, toVersion int) error {
+	target, err := c.GetSecretVersion(ctx, userID, key, toVersion)
+	if err != nil {
+		return err
+	}
+
+	secretPath := BuildCustomSecretPath(userID, key)
+	if err := c.writeSecretValue(ctx, secretPath, target.Value); err != nil {
+		return fmt.Errorf("failed to roll back secret: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("key", key).
+		Int("to_version", toVersion).
+		Msg("rolled back secret")
+
+	return nil
+}
+
+// UpdateSecretMetadata replaces a secret's tags (unless nil) and updates its type and
+// validation time without creating a new version. CreatedBy and CreatedAt cannot be changed.
+// Returns ErrSecretNotFound if the secret doesn't exist.
+func (c *Client) UpdateSecretMetadata(ctx context.Context, userID, key string, metadata SecretMetadata) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if err := ValidateSecretKey(key); err != nil {
+		return err
+	}
+
+	secretPath := BuildCustomSecretPath(userID, key)
+	existing, err := c.readSecretMetadata(ctx, secretPath)
+	if err != nil {
+		if err == ErrSecretNotFound {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("failed to read secret metadata: %w", err)
+	}
+	if isCurrentVersionDeleted(existing) {
+		return ErrSecretNotFound
+	}
+
+	custom := make(map[string]string, len(existing.Data.CustomMetadata))
+	for k, v := range existing.Data.CustomMetadata {
+		custom[k] = v
+	}
+	applySecretMetadata(custom, metadata)
+
+	if err := c.writeCustomMetadata(ctx, secretPath, custom); err != nil {
+		return fmt.Errorf("failed to update secret metadata: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("key", key).
+		Msg("updated secret metadata")
+
+	return nil
+}
+
+// GetSecretMetadata retrieves only the metadata for a secret.
+// Returns ErrSecretNotFound if the secret doesn't exist or its current version is deleted.
+func (c *Client) GetSecretMetadata(ctx context.Context, userID, key string) (SecretMetadata, error) {
+	if userID == "" {
+		return SecretMetadata{}, fmt.Errorf("user ID is required")
+	}
+	if err := ValidateSecretKey(key); err != nil {
+		return SecretMetadata{}, err
+	}
+
+	meta, err := c.readSecretMetadata(ctx, BuildCustomSecretPath(userID, key))
+	if err != nil {
+		return SecretMetadata{}, err
+	}
+	if isCurrentVersionDeleted(meta) {
+		return SecretMetadata{}, ErrSecretNotFound
+	}
+
+	result := parseSecretMetadata(meta.Data.CustomMetadata)
+	result.Key = key
+	result.Version = meta.Data.CurrentVersion
+	return result, nil
+}
+
+// getSecretAtVersion reads a secret's value at a version (0 for current) combined with its metadata.
+func (c *Client) getSecretAtVersion(ctx context.Context, userID, key string, version int) (Secret, error) {
+	if userID == "" {
+		return Secret{}, fmt.Errorf("user ID is required")
+	}
+	if err := ValidateSecretKey(key); err != nil {
+		return Secret{}, err
+	}
+
+	secretPath := BuildCustomSecretPath(userID, key)
+	value, readVersion, err := c.readSecretValue(ctx, secretPath, version)
+	if err != nil {
+		return Secret{}, err
+	}
+	meta, err := c.readSecretMetadata(ctx, secretPath)
+	if err != nil {
+		return Secret{}, fmt.Errorf("failed to read secret metadata: %w", err)
+	}
+
+	metadata := parseSecretMetadata(meta.Data.CustomMetadata)
+	metadata.Key = key
+	metadata.Version = readVersion
+
+	return Secret{Key: key, Value: value, Metadata: metadata}, nil
+}
+
+// readSecretValue reads the value stored at a version (0 for current) and the version that was read.
+func (c *Client) readSecretValue(ctx context.Context, secretPath string, version int) (string, int, error) {
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+	if version > 0 {
+		kvPath = fmt.Sprintf("%s?version=%d", kvPath, version)
+	}
+
+	var response struct {
+		Data struct {
+			Data     map[string]interface{} `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := c.makeRequest(ctx, "GET", kvPath, nil, &response); err != nil {
+		if isNotFoundError(err) {
+			return "", 0, ErrSecretNotFound
+		}
+		return "", 0, fmt.Errorf("failed to read secret: %w", err)
+	}
+	// Deleted and destroyed versions come back without data
+	if response.Data.Data == nil {
+		return "", 0, ErrSecretNotFound
+	}
+
+	value, ok := response.Data.Data["value"].(string)
+	if !ok {
+		return "", 0, fmt.Errorf("value not found in secret data")
+	}
+	return value, response.Data.Metadata.Version, nil
+}
+
+// readSecretMetadata reads the KV v2 metadata for a secret path.
+func (c *Client) readSecretMetadata(ctx context.Context, secretPath string) (kvMetadataResponse, error) {
+	metadataPath := fmt.Sprintf("/v1/%s/metadata/%s", c.mountPath, secretPath)
+
+	var response kvMetadataResponse
+	if err := c.makeRequest(ctx, "GET", metadataPath, nil, &response); err != nil {
+		if isNotFoundError(err) {
+			return response, ErrSecretNotFound
+		}
+		return response, err
+	}
+	return response, nil
+}
+
+// writeSecretValue writes a new version of a secret's value.
+func (c *Client) writeSecretValue(ctx context.Context, secretPath, value string) error {
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+	requestBody := map[string]interface{}{
+		"data": map[string]interface{}{
+			"value": value,
+		},
+	}
+	return c.makeRequest(ctx, "POST", kvPath, requestBody, nil)
+}
+
+// writeCustomMetadata replaces a secret's custom metadata without creating a new version.
+func (c *Client) writeCustomMetadata(ctx context.Context, secretPath string, custom map[string]string) error {
+	metadataPath := fmt.Sprintf("/v1/%s/metadata/%s", c.mountPath, secretPath)
+	requestBody := map[string]interface{}{
+		"custom_metadata": custom,
+	}
+	return c.makeRequest(ctx, "POST", metadataPath, requestBody, nil)
+}
+
+// applySecretMetadata copies the mutable fields of metadata into custom metadata.
+// Tags are replaced as a whole unless nil, so an empty slice clears them; SecretType and
+// LastValidated only when set.
+func applySecretMetadata(custom map[string]string, metadata SecretMetadata) {
+	if metadata.Tags != nil {
+		custom[customMetaTags] = strings.Join(normalizeTags(metadata.Tags), tagSeparator)
+	}
+	if metadata.SecretType != "" {
+		custom[customMetaSecretType] = metadata.SecretType
+	} else if custom[customMetaSecretType] == "" {
+		custom[customMetaSecretType] = SecretTypeCustom
+	}
+	if metadata.LastValidated != nil {
+		custom[customMetaLastValidated] = metadata.LastValidated.UTC().Format(time.RFC3339)
+	}
+}
+
+// parseSecretMetadata converts KV v2 custom metadata into SecretMetadata.
+func parseSecretMetadata(custom map[string]string) SecretMetadata {
+	metadata := SecretMetadata{
+		CreatedBy:  custom[customMetaCreatedBy],
+		SecretType: custom[customMetaSecretType],
+		Tags:       []string{},
+	}
+	if t, err := time.Parse(time.RFC3339, custom[customMetaCreatedAt]); err == nil {
+		metadata.CreatedAt = t
+	}
+	if t, err := time.Parse(time.RFC3339, custom[customMetaLastValidated]); err == nil {
+		metadata.LastValidated = &t
+	}
+	if raw := custom[customMetaTags]; raw != "" {
+		metadata.Tags = strings.Split(raw, tagSeparator)
+	}
+	return metadata
+}
+
+// normalizeTags trims, lowercases and de-duplicates tags, dropping empty ones and the separator.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		t := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, tagSeparator, "")))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// isCurrentVersionDeleted reports whether the latest version of a secret is soft-deleted or destroyed.
+func isCurrentVersionDeleted(meta kvMetadataResponse) bool {
+	state, ok := meta.Data.Versions[strconv.Itoa(meta.Data.CurrentVersion)]
+	if !ok {
+		return false
+	}
+	return state.DeletionTime != "" || state.Destroyed
+}
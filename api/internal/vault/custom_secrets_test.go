@@ -0,0 +1,56 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMetadataServer serves custom as a secret's KV v2 metadata and records the custom metadata
+// written back.
+func newMetadataServer(t *testing.T, custom map[string]string) (*Client, *map[string]string) {
+	t.Helper()
+	var written map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"current_version": 1, "custom_metadata": custom},
+			})
+		case http.MethodPost:
+			var body struct {
+				CustomMetadata map[string]string `json:"custom_metadata"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			written = body.CustomMetadata
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{address: srv.URL, httpClient: srv.Client(), mountPath: "secret", circuitBreaker: NewCircuitBreaker()}, &written
+}
+
+func TestUpdateSecretMetadata_KeepsTagsWhenOmitted(t *testing.T) {
+	c, written := newMetadataServer(t, map[string]string{customMetaTags: "payments,prod", customMetaSecretType: SecretTypeCustom})
+
+	require.NoError(t, c.UpdateSecretMetadata(context.Background(), "user-1", "stripe-key", SecretMetadata{SecretType: "api_key"}))
+	assert.Equal(t, "payments,prod", (*written)[customMetaTags])
+	assert.Equal(t, "api_key", (*written)[customMetaSecretType])
+}
+
+func TestUpdateSecretMetadata_EmptyTagsClear(t *testing.T) {
+	c, written := newMetadataServer(t, map[string]string{customMetaTags: "payments,prod"})
+
+	require.NoError(t, c.UpdateSecretMetadata(context.Background(), "user-1", "stripe-key", SecretMetadata{Tags: []string{}}))
+	assert.Equal(t, "", (*written)[customMetaTags])
+}
@@ -0,0 +1,217 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDockerRegistry is the registry assumed when an image reference does not name one.
+const DefaultDockerRegistry = "docker.io"
+
+// dockerHubAliases are alternate hostnames for Docker Hub that are stored under DefaultDockerRegistry.
+var dockerHubAliases = map[string]bool{
+	"index.docker.io":         true,
+	"registry-1.docker.io":    true,
+	"registry.hub.docker.com": true,
+}
+
+// NormalizeRegistry converts a registry reference into the key used to store its credentials.
+// Schemes, trailing slashes and casing are stripped, and Docker Hub aliases collapse to docker.io.
+// Example: NormalizeRegistry("https://GHCR.io/") -> "ghcr.io"
+func NormalizeRegistry(registry string) string {
+	r := strings.ToLower(strings.TrimSpace(registry))
+	r = strings.TrimPrefix(r, "https://")
+	r = strings.TrimPrefix(r, "http://")
+	r = strings.TrimSuffix(r, "/")
+	// Docker Hub's auth endpoint is often given as index.docker.io/v1
+	r = strings.TrimSuffix(r, "/v1")
+	if dockerHubAliases[r] {
+		return DefaultDockerRegistry
+	}
+	return r
+}
+
+// validateRegistry returns the normalized registry, or an error wrapping ErrInvalidSecret if it
+// can't be used as a path component.
+func validateRegistry(registry string) (string, error) {
+	normalized := NormalizeRegistry(registry)
+	if normalized == "" {
+		return "", fmt.Errorf("%w: registry is required", ErrInvalidSecret)
+	}
+	if strings.Contains(normalized, "/") {
+		return "", fmt.Errorf("%w: registry %q must be a hostname", ErrInvalidSecret, registry)
+	}
+	return normalized, nil
+}
+
+// StoreDockerCredentials stores or updates Docker registry credentials in Vault.
+// Credentials are keyed by the normalized registry hostname, so a user can hold
+// credentials for several registries at once. Updating creates a new version.
+func (c *Client) StoreDockerCredentials(ctx context.Context, userID string, creds DockerCredentials) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	registry, err := validateRegistry(creds.Registry)
+	if err != nil {
+		return err
+	}
+	if creds.Username == "" || creds.Password == "" {
+		return fmt.Errorf("%w: username and password are required", ErrInvalidSecret)
+	}
+
+	secretPath := BuildDockerCredentialsPath(userID, registry)
+
+	secretData := map[string]interface{}{
+		"registry": registry,
+		"username": creds.Username,
+		"password": creds.Password,
+		"email":    creds.Email,
+		"metadata": map[string]interface{}{
+			"created_by":  userID,
+			"created_at":  time.Now().UTC().Format(time.RFC3339),
+			"secret_type": SecretTypeDocker,
+		},
+	}
+
+	// Vault KV v2 requires wrapping data in a "data" field
+	requestBody := map[string]interface{}{
+		"data": secretData,
+	}
+
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+	if err := c.makeRequest(ctx, "POST", kvPath, requestBody, nil); err != nil {
+		return fmt.Errorf("failed to store docker credentials: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("registry", registry).
+		Str("secret_path", secretPath).
+		Msg("stored docker credentials")
+
+	return nil
+}
+
+// GetDockerCredentials retrieves a user's credentials for a specific registry.
+// Returns ErrSecretNotFound if no credentials exist for the registry.
+func (c *Client) GetDockerCredentials(ctx context.Context, userID, registry string) (DockerCredentials, error) {
+	if userID == "" {
+		return DockerCredentials{}, fmt.Errorf("user ID is required")
+	}
+	normalized, err := validateRegistry(registry)
+	if err != nil {
+		return DockerCredentials{}, err
+	}
+
+	secretPath := BuildDockerCredentialsPath(userID, normalized)
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+
+	var response struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+
+	if err := c.makeRequest(ctx, "GET", kvPath, nil, &response); err != nil {
+		if isNotFoundError(err) {
+			return DockerCredentials{}, ErrSecretNotFound
+		}
+		return DockerCredentials{}, fmt.Errorf("failed to read docker credentials: %w", err)
+	}
+	if response.Data.Data == nil {
+		return DockerCredentials{}, ErrSecretNotFound
+	}
+
+	creds := DockerCredentials{Registry: normalized}
+	creds.Username, _ = response.Data.Data["username"].(string)
+	creds.Password, _ = response.Data.Data["password"].(string)
+	creds.Email, _ = response.Data.Data["email"].(string)
+	if creds.Username == "" || creds.Password == "" {
+		return DockerCredentials{}, fmt.Errorf("docker credentials for %s are incomplete", normalized)
+	}
+
+	log.Debug().
+		Str("user_id", userID).
+		Str("registry", normalized).
+		Msg("retrieved docker credentials from vault")
+
+	return creds, nil
+}
+
+// ListDockerRegistries returns the registries the user has stored credentials for, sorted by name.
+// Returns an empty slice if no registries are configured.
+func (c *Client) ListDockerRegistries(ctx context.Context, userID string) ([]string, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+
+	// KV v2 lists keys through the metadata endpoint
+	listPath := fmt.Sprintf("/v1/%s/metadata/%s", c.mountPath, BuildUserSecretPath(userID, PathDocker))
+
+	var response struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+
+	if err := c.makeRequest(ctx, "LIST", listPath, nil, &response); err != nil {
+		// Vault returns 404 when nothing has been stored under the prefix yet
+		if isNotFoundError(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list docker registries: %w", err)
+	}
+
+	registries := make([]string, 0, len(response.Data.Keys))
+	for _, key := range response.Data.Keys {
+		// Skip nested folders - registries are always leaf keys
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		registries = append(registries, key)
+	}
+	sort.Strings(registries)
+
+	return registries, nil
+}
+
+// DeleteDockerCredentials removes a user's credentials for a specific registry.
+// Unlike token deletion this removes every version, so the registry no longer
+// shows up in ListDockerRegistries.
+// Returns ErrSecretNotFound if no credentials exist for the registry.
+func (c *Client) DeleteDockerCredentials(ctx context.Context, userID, registry string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	normalized, err := validateRegistry(registry)
+	if err != nil {
+		return err
+	}
+
+	// Deleting KV v2 metadata succeeds for missing paths, so check existence first
+	if _, err := c.GetDockerCredentials(ctx, userID, normalized); err != nil {
+		return err
+	}
+
+	secretPath := BuildDockerCredentialsPath(userID, normalized)
+	metadataPath := fmt.Sprintf("/v1/%s/metadata/%s", c.mountPath, secretPath)
+	if err := c.makeRequest(ctx, "DELETE", metadataPath, nil, nil); err != nil {
+		if isNotFoundError(err) {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("failed to delete docker credentials: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("registry", normalized).
+		Str("secret_path", secretPath).
+		Msg("deleted docker credentials")
+
+	return nil
+}
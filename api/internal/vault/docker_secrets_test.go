@@ -0,0 +1,20 @@
+package vault
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRegistry_WrapsErrInvalidSecret(t *testing.T) {
+	registry, err := validateRegistry("https://GHCR.io/")
+	require.NoError(t, err)
+	assert.Equal(t, "ghcr.io", registry)
+
+	_, err = validateRegistry(" ")
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+
+	_, err = validateRegistry("ghcr.io/acme")
+	assert.ErrorIs(t, err, ErrInvalidSecret)
+}
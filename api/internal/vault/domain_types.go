@@ -5,6 +5,10 @@ import "time"
 // SecretMetadata contains metadata about a secret stored in Vault.
 // This metadata is used to track secret lifecycle, ownership, and classification.
 type SecretMetadata struct {
+	// Key is the secret identifier this metadata belongs to.
+	// Populated when metadata is returned on its own (e.g., by ListSecrets)
+	Key string `json:"key,omitempty"`
+
 	// CreatedBy is the user ID who created the secret
 	CreatedBy string `json:"created_by"`
 
@@ -0,0 +1,253 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxCASRetries bounds how often a read-modify-write of an environment's secrets is retried
+// after losing a check-and-set race to a concurrent writer.
+const maxCASRetries = 3
+
+// environmentSecretKeyPattern matches valid environment variable names.
+var environmentSecretKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateEnvironmentSecretKey checks that a key can be used as an environment variable name.
+func ValidateEnvironmentSecretKey(key string) error {
+	if !environmentSecretKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be a valid environment variable name", ErrInvalidSecret, key)
+	}
+	return nil
+}
+
+// All secrets for an environment live in a single KV v2 entry so bulk writes are atomic.
+// Concurrent updates are serialized with check-and-set on the entry's version.
+
+// StoreEnvironmentSecret stores or updates a single secret for an environment.
+func (c *Client) StoreEnvironmentSecret(ctx context.Context, userID, envID, key, value string) error {
+	return c.BulkStoreEnvironmentSecrets(ctx, userID, envID, map[string]string{key: value})
+}
+
+// GetEnvironmentSecret retrieves a single environment secret by key.
+// Returns ErrSecretNotFound if the secret doesn't exist.
+func (c *Client) GetEnvironmentSecret(ctx context.Context, userID, envID, key string) (string, error) {
+	secrets, err := c.GetAllEnvironmentSecrets(ctx, userID, envID)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// GetAllEnvironmentSecrets retrieves all secrets for an environment.
+// Returns an empty map if no secrets exist.
+func (c *Client) GetAllEnvironmentSecrets(ctx context.Context, userID, envID string) (map[string]string, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("user ID is required")
+	}
+	if envID == "" {
+		return nil, fmt.Errorf("environment ID is required")
+	}
+
+	secrets, _, err := c.readEnvironmentSecrets(ctx, BuildEnvironmentSecretPath(userID, envID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read environment secrets: %w", err)
+	}
+
+	log.Debug().
+		Str("user_id", userID).
+		Str("env_id", envID).
+		Int("secret_count", len(secrets)).
+		Msg("retrieved environment secrets from vault")
+
+	return secrets, nil
+}
+
+// DeleteEnvironmentSecret removes a single environment secret.
+// Returns ErrSecretNotFound if the secret doesn't exist.
+func (c *Client) DeleteEnvironmentSecret(ctx context.Context, userID, envID, key string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if envID == "" {
+		return fmt.Errorf("environment ID is required")
+	}
+
+	secretPath := BuildEnvironmentSecretPath(userID, envID)
+	err := c.updateEnvironmentSecrets(ctx, secretPath, userID, func(secrets map[string]string) error {
+		if _, ok := secrets[key]; !ok {
+			return ErrSecretNotFound
+		}
+		delete(secrets, key)
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrSecretNotFound) {
+			return ErrSecretNotFound
+		}
+		return fmt.Errorf("failed to delete environment secret: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("env_id", envID).
+		Str("key", key).
+		Msg("deleted environment secret")
+
+	return nil
+}
+
+// BulkStoreEnvironmentSecrets stores multiple environment secrets in a single write,
+// so either all of them are stored or none are.
+func (c *Client) BulkStoreEnvironmentSecrets(ctx context.Context, userID, envID string, secrets map[string]string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if envID == "" {
+		return fmt.Errorf("environment ID is required")
+	}
+	if len(secrets) == 0 {
+		return ErrInvalidSecret
+	}
+	for key, value := range secrets {
+		if err := ValidateEnvironmentSecretKey(key); err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("%w: value for %s is empty", ErrInvalidSecret, key)
+		}
+	}
+
+	secretPath := BuildEnvironmentSecretPath(userID, envID)
+	err := c.updateEnvironmentSecrets(ctx, secretPath, userID, func(current map[string]string) error {
+		for key, value := range secrets {
+			current[key] = value
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store environment secrets: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("env_id", envID).
+		Int("secret_count", len(secrets)).
+		Str("secret_path", secretPath).
+		Msg("stored environment secrets")
+
+	return nil
+}
+
+// DeleteAllEnvironmentSecrets permanently removes every secret for an environment.
+// It is used when the environment itself is deleted and succeeds if nothing was stored.
+func (c *Client) DeleteAllEnvironmentSecrets(ctx context.Context, userID, envID string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID is required")
+	}
+	if envID == "" {
+		return fmt.Errorf("environment ID is required")
+	}
+
+	secretPath := BuildEnvironmentSecretPath(userID, envID)
+	metadataPath := fmt.Sprintf("/v1/%s/metadata/%s", c.mountPath, secretPath)
+	if err := c.makeRequest(ctx, "DELETE", metadataPath, nil, nil); err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("failed to delete environment secrets: %w", err)
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("env_id", envID).
+		Msg("deleted all environment secrets")
+
+	return nil
+}
+
+// readEnvironmentSecrets returns the secrets stored at secretPath and the entry's current version.
+// A missing entry yields an empty map and version 0.
+func (c *Client) readEnvironmentSecrets(ctx context.Context, secretPath string) (map[string]string, int, error) {
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+
+	var response struct {
+		Data struct {
+			Data     map[string]interface{} `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+
+	if err := c.makeRequest(ctx, "GET", kvPath, nil, &response); err != nil {
+		if isNotFoundError(err) {
+			return map[string]string{}, 0, nil
+		}
+		return nil, 0, err
+	}
+
+	secrets := make(map[string]string)
+	if raw, ok := response.Data.Data["secrets"].(map[string]interface{}); ok {
+		for key, value := range raw {
+			if s, ok := value.(string); ok {
+				secrets[key] = s
+			}
+		}
+	}
+	return secrets, response.Data.Metadata.Version, nil
+}
+
+// updateEnvironmentSecrets applies mutate to the current secrets and writes the result back
+// using check-and-set, retrying when another writer updated the entry in between.
+func (c *Client) updateEnvironmentSecrets(ctx context.Context, secretPath, userID string, mutate func(map[string]string) error) error {
+	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
+
+	for attempt := 0; attempt < maxCASRetries; attempt++ {
+		secrets, version, err := c.readEnvironmentSecrets(ctx, secretPath)
+		if err != nil {
+			return err
+		}
+		if err := mutate(secrets); err != nil {
+			return err
+		}
+
+		requestBody := map[string]interface{}{
+			"options": map[string]interface{}{
+				"cas": version,
+			},
+			"data": map[string]interface{}{
+				"secrets": secrets,
+				"metadata": map[string]interface{}{
+					"updated_by":  userID,
+					"updated_at":  time.Now().UTC().Format(time.RFC3339),
+					"secret_type": SecretTypeEnvironment,
+				},
+			},
+		}
+
+		err = c.makeRequest(ctx, "POST", kvPath, requestBody, nil)
+		if err == nil {
+			return nil
+		}
+		if !isCASError(err) {
+			return err
+		}
+		log.Debug().
+			Str("secret_path", secretPath).
+			Int("attempt", attempt+1).
+			Msg("environment secrets changed concurrently; retrying")
+	}
+	return ErrSecretLocked
+}
+
+// isCASError checks if an error is a KV v2 check-and-set version mismatch
+func isCASError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "check-and-set")
+}
@@ -2,8 +2,11 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
 // storeTokenSecret is a generic internal helper for storing token-type secrets.
@@ -30,6 +33,8 @@ func (c *Client) storeTokenSecret(ctx context.Context, secretPath, secretType, t
 	// Format: /v1/{mount}/data/{path}
 	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
 	err := c.makeRequest(ctx, "POST", kvPath, requestBody, nil)
+	// Drop the cached value even on failure - the write may have landed
+	c.cache.invalidate(secretPath)
 	if err != nil {
 		return err
 	}
@@ -41,7 +46,32 @@ func (c *Client) storeTokenSecret(ctx context.Context, secretPath, secretType, t
 // This eliminates duplication across Railway, GitHub, and other provider token retrieval.
 // The public methods (GetRailwayToken, GetGitHubToken, etc.) wrap this with
 // provider-specific logging.
+// Tokens are read through the client's cache; while the circuit breaker is open a
+// recently expired cached token is returned instead of failing.
 func (c *Client) getTokenSecret(ctx context.Context, secretPath string) (string, error) {
+	if token, ok := c.cache.get(secretPath); ok {
+		return token, nil
+	}
+
+	token, err := c.readTokenSecret(ctx, secretPath)
+	if err != nil {
+		if errors.Is(err, ErrCircuitOpen) {
+			if stale, ok := c.cache.getStale(secretPath); ok {
+				log.Warn().
+					Str("secret_path", secretPath).
+					Msg("vault circuit open; serving cached token")
+				return stale, nil
+			}
+		}
+		return "", err
+	}
+
+	c.cache.set(secretPath, token)
+	return token, nil
+}
+
+// readTokenSecret reads a token-type secret directly from Vault, bypassing the cache.
+func (c *Client) readTokenSecret(ctx context.Context, secretPath string) (string, error) {
 	// Read from Vault using the KV v2 data endpoint
 	// Format: /v1/{mount}/data/{path}
 	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
@@ -85,6 +115,7 @@ func (c *Client) deleteTokenSecret(ctx context.Context, secretPath string) error
 	kvPath := fmt.Sprintf("/v1/%s/data/%s", c.mountPath, secretPath)
 
 	err := c.makeRequest(ctx, "DELETE", kvPath, nil, nil)
+	c.cache.invalidate(secretPath)
 	if err != nil {
 		// Check if it's a 404 - secret not found
 		if isNotFoundError(err) {
@@ -111,4 +111,6 @@ const (
 	DefaultSuccessThreshold = 2
 	// DefaultHealthCheckInterval is the interval for periodic health checks
 	DefaultHealthCheckInterval = 30 * time.Second
+	// DefaultCacheStaleTTL is how long past expiry a cached secret may still be served while Vault is unreachable
+	DefaultCacheStaleTTL = 1 * time.Hour
 )